@@ -0,0 +1,527 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+)
+
+// An Agent answers SNMPv1 and SNMPv2c GET, GETNEXT, and GETBULK
+// requests over UDP from the objects registered with Handle, and SET
+// requests for those registered with HandleSet.
+type Agent struct {
+	// Addr is the address to listen on as host or host:port.
+	// Port 161 is used if no port is given.
+	Addr string
+
+	// ReadCommunity is the community GET, GETNEXT, and GETBULK
+	// requests must use, and WriteCommunity the one SET requests
+	// must use. The write community can also read. Requests that use
+	// the wrong community are ignored, as most agents do. Any
+	// community is accepted if the field is empty. Both are ignored
+	// once subtrees are granted with Allow.
+	ReadCommunity  string
+	WriteCommunity string
+
+	// MaxMessageSize is the largest response the agent sends.
+	// GETBULK responses are truncated to fit. It defaults to 1472.
+	MaxMessageSize int
+
+	conn     net.PacketConn
+	closed   bool
+	handlers []agentHandler
+	rules    []agentRule
+	lock     sync.Mutex
+}
+
+// AccessMode is the access to a subtree granted by Agent.Allow.
+type AccessMode int
+
+const (
+	AccessReadOnly AccessMode = iota + 1
+	AccessReadWrite
+)
+
+// agentRule grants a community access to a subtree.
+type agentRule struct {
+	community string
+	subtree   ObjectIdentifier
+	access    AccessMode
+}
+
+// agentHandler is an object registered with an Agent.
+type agentHandler struct {
+	oid      ObjectIdentifier
+	getter   func() (DataType, error)
+	validate func(DataType) error
+	set      func(DataType) error
+}
+
+// Handle registers getter to provide the value of oid, replacing any
+// getter already registered for it. A getter that returns an error
+// fails the request with genErr.
+func (a *Agent) Handle(oid ObjectIdentifier, getter func() (DataType, error)) {
+	a.update(oid, func(h *agentHandler) {
+		h.getter = getter
+	})
+}
+
+// HandleSet makes oid writable. A SET request first calls the validate
+// function of each of its bindings, and only calls their set functions
+// if they all succeed, so that a rejected binding leaves every object
+// unchanged. Validate may return an ErrorStatus such as WrongType to
+// choose the error-status of the response, which is otherwise
+// wrongValue, or badValue for SNMPv1. An object without a getter
+// registered with Handle can be set but not read.
+func (a *Agent) HandleSet(oid ObjectIdentifier, validate, set func(DataType) error) {
+	a.update(oid, func(h *agentHandler) {
+		h.validate, h.set = validate, set
+	})
+}
+
+// Allow grants community access to the objects in subtree, replacing
+// any access already granted to it for the same subtree. Once Allow is
+// called, requests from communities without any subtree are ignored,
+// and to the others, objects outside their subtrees look missing: GET
+// gets noSuchObject, GETNEXT and GETBULK skip to the next object in
+// their subtrees, and SET fails with noAccess, as it does for objects
+// granted AccessReadOnly.
+func (a *Agent) Allow(community string, subtree ObjectIdentifier, access AccessMode) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	// Requests in progress hold on to the old slice
+	rules := make([]agentRule, 0, len(a.rules)+1)
+	for _, r := range a.rules {
+		if r.community != community || !r.subtree.Equal(subtree) {
+			rules = append(rules, r)
+		}
+	}
+
+	a.rules = append(rules, agentRule{community: community, subtree: subtree.Clone(), access: access})
+}
+
+// update applies fn to the handler of oid, adding it if needed.
+func (a *Agent) update(oid ObjectIdentifier, fn func(h *agentHandler)) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	i := sort.Search(len(a.handlers), func(i int) bool {
+		return a.handlers[i].oid.Compare(oid) >= 0
+	})
+
+	h := agentHandler{oid: oid.Clone()}
+	if i < len(a.handlers) && a.handlers[i].oid.Equal(oid) {
+		h = a.handlers[i]
+	}
+
+	fn(&h)
+
+	// Requests in progress hold on to the old slice
+	handlers := make([]agentHandler, 0, len(a.handlers)+1)
+	handlers = append(handlers, a.handlers[:i]...)
+	handlers = append(handlers, h)
+
+	if i < len(a.handlers) && a.handlers[i].oid.Equal(oid) {
+		i++
+	}
+
+	a.handlers = append(handlers, a.handlers[i:]...)
+}
+
+// ListenAndServe listens for requests and answers them. Datagrams that
+// can't be decoded are logged and skipped, and requests for another
+// community are ignored. It blocks until Close is called, after which
+// it returns nil.
+func (a *Agent) ListenAndServe() error {
+	conn, err := net.ListenPacket("udp", withDefaultPort(a.Addr, defaultPort))
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		conn.Close()
+		return nil
+	}
+
+	if a.conn != nil {
+		a.lock.Unlock()
+		conn.Close()
+		return errors.New("snmp: Agent is already listening")
+	}
+
+	a.conn = conn
+	a.lock.Unlock()
+
+	buf := make([]byte, 65535)
+
+	for {
+		n, src, err := conn.ReadFrom(buf)
+		if err != nil {
+			a.lock.Lock()
+			closed := a.closed
+			a.lock.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		m, _, err := decodeMessage(bytes.NewReader(buf[:n]))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		res := a.respond(m)
+		if res == nil {
+			continue
+		}
+
+		b, err := res.Encode()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		conn.WriteTo(b, src)
+	}
+}
+
+// LocalAddr returns the address the Agent is listening on,
+// or nil if it isn't listening.
+func (a *Agent) LocalAddr() net.Addr {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.conn == nil {
+		return nil
+	}
+
+	return a.conn.LocalAddr()
+}
+
+// Close stops the Agent.
+func (a *Agent) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.closed = true
+
+	if a.conn == nil {
+		return nil
+	}
+
+	return a.conn.Close()
+}
+
+// respond returns the response to request m,
+// or nil if it shouldn't be answered.
+func (a *Agent) respond(m *Message) *Message {
+	if m.Version == Version3 {
+		return nil
+	}
+
+	a.lock.Lock()
+	handlers, rules := a.handlers, a.rules
+	a.lock.Unlock()
+
+	if !a.authorized(m, rules) {
+		return nil
+	}
+
+	readable := allowed(rules, m.Community, AccessReadOnly)
+
+	var pdu PDU
+
+	switch req := m.PDU.(type) {
+	case GetRequest:
+		pdu = a.get(m.Version, visible(handlers, readable), req.PDU, false)
+	case GetNextRequest:
+		pdu = a.get(m.Version, visible(handlers, readable), req.PDU, true)
+	case GetBulkRequest:
+		pdu = a.getBulk(m, visible(handlers, readable), req)
+	case SetRequest:
+		pdu = a.set(m.Version, handlers, req.PDU, allowed(rules, m.Community, AccessReadWrite))
+	default:
+		return nil
+	}
+
+	return &Message{
+		Version:   m.Version,
+		Community: m.Community,
+		PDU:       GetResponse{PDU: pdu},
+	}
+}
+
+// authorized reports whether the community of m grants the
+// access its PDU needs, or with rules, any access.
+func (a *Agent) authorized(m *Message, rules []agentRule) bool {
+	if len(rules) > 0 {
+		for _, r := range rules {
+			if r.community == m.Community {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if _, ok := m.PDU.(SetRequest); ok {
+		return a.WriteCommunity == "" || m.Community == a.WriteCommunity
+	}
+
+	return a.ReadCommunity == "" || m.Community == a.ReadCommunity ||
+		(a.WriteCommunity != "" && m.Community == a.WriteCommunity)
+}
+
+// allowed returns whether rules grant community access to an OID,
+// which is always the case without rules.
+func allowed(rules []agentRule, community string, access AccessMode) func(ObjectIdentifier) bool {
+	return func(oid ObjectIdentifier) bool {
+		if len(rules) == 0 {
+			return true
+		}
+
+		for _, r := range rules {
+			if r.community == community && r.access >= access && oid.HasPrefix(r.subtree) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// visible returns the handlers of the OIDs allowed,
+// which are handlers itself if they all are.
+func visible(handlers []agentHandler, allowed func(ObjectIdentifier) bool) []agentHandler {
+	for i, h := range handlers {
+		if allowed(h.oid) {
+			continue
+		}
+
+		result := append([]agentHandler(nil), handlers[:i]...)
+		for _, h := range handlers[i+1:] {
+			if allowed(h.oid) {
+				result = append(result, h)
+			}
+		}
+
+		return result
+	}
+
+	return handlers
+}
+
+// get answers a GET, or a GETNEXT if next is set. SNMPv1 requests for
+// missing objects fail with noSuchName, while SNMPv2c requests get
+// noSuchObject or endOfMibView exceptions.
+func (a *Agent) get(version int, handlers []agentHandler, req PDU, next bool) PDU {
+	varbinds := make([]Varbind, 0, len(req.varbinds))
+
+	for i, v := range req.varbinds {
+		res, status := lookup(handlers, v.OID, next)
+		if status == NoError && version == Version1 && res.IsException() {
+			status = NoSuchName
+		}
+
+		if status != NoError {
+			return newPDU(req.requestID, int(status), i+1, req.varbinds)
+		}
+
+		varbinds = append(varbinds, res)
+	}
+
+	return newPDU(req.requestID, 0, 0, varbinds)
+}
+
+// getBulk answers a GETBULK, dropping repetitions from the end of the
+// response until it fits in a.MaxMessageSize.
+func (a *Agent) getBulk(m *Message, handlers []agentHandler, req GetBulkRequest) PDU {
+	nonRepeaters := req.NonRepeaters()
+	if nonRepeaters > len(req.varbinds) {
+		nonRepeaters = len(req.varbinds)
+	}
+
+	if nonRepeaters < 0 {
+		nonRepeaters = 0
+	}
+
+	var varbinds []Varbind
+
+	for i, v := range req.varbinds[:nonRepeaters] {
+		res, status := lookup(handlers, v.OID, true)
+		if status != NoError {
+			return newPDU(req.requestID, int(status), i+1, req.varbinds)
+		}
+
+		varbinds = append(varbinds, res)
+	}
+
+	repeaters := req.varbinds[nonRepeaters:]
+	last := make([]ObjectIdentifier, len(repeaters))
+	for i, v := range repeaters {
+		last[i] = v.OID
+	}
+
+	for r := 0; r < req.MaxRepetitions() && len(repeaters) > 0; r++ {
+		ended := true
+
+		for i := range repeaters {
+			res, status := lookup(handlers, last[i], true)
+			if status != NoError {
+				return newPDU(req.requestID, int(status), nonRepeaters+i+1, req.varbinds)
+			}
+
+			varbinds = append(varbinds, res)
+			last[i] = res.OID
+			ended = ended && res.value == EndOfMIBView
+		}
+
+		if ended {
+			break
+		}
+	}
+
+	maxSize := a.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxMessageSize
+	}
+
+	for {
+		pdu := newPDU(req.requestID, 0, 0, varbinds)
+
+		b, err := Message{Version: m.Version, Community: m.Community, PDU: GetResponse{PDU: pdu}}.Encode()
+		if err == nil && len(b) <= maxSize {
+			return pdu
+		}
+
+		// At least one repetition must fit
+		if len(varbinds) <= nonRepeaters+1 {
+			return newPDU(req.requestID, int(TooBig), 0, req.varbinds)
+		}
+
+		varbinds = varbinds[:len(varbinds)-1]
+	}
+}
+
+// set answers a SET in two phases: every binding is validated before
+// any is set. Objects that aren't writable fail with noAccess, those
+// that don't exist with noCreation, and those without a setter with
+// notWritable, or noSuchName and readOnly for SNMPv1. A setter that
+// fails fails the request with commitFailed.
+func (a *Agent) set(version int, handlers []agentHandler, req PDU, writable func(ObjectIdentifier) bool) PDU {
+	targets := make([]agentHandler, len(req.varbinds))
+
+	for i, v := range req.varbinds {
+		h, ok := find(handlers, v.OID)
+
+		status := NoError
+
+		switch {
+		case !writable(v.OID):
+			status = NoAccess
+		case !ok:
+			status = NoCreation
+		case h.set == nil:
+			status = NotWritable
+		case h.validate != nil:
+			if err := h.validate(v.value); err != nil {
+				status = WrongValue
+				errors.As(err, &status)
+			}
+		}
+
+		if status != NoError {
+			return newPDU(req.requestID, int(v1Status(version, status, ok)), i+1, req.varbinds)
+		}
+
+		targets[i] = h
+	}
+
+	for i, v := range req.varbinds {
+		if err := targets[i].set(v.value); err != nil {
+			return newPDU(req.requestID, int(v1Status(version, CommitFailed, true)), i+1, req.varbinds)
+		}
+	}
+
+	return newPDU(req.requestID, 0, 0, req.varbinds)
+}
+
+// v1Status maps a SET error-status to its SNMPv1 equivalent for SNMPv1
+// requests, as RFC 2576 describes, given whether the object exists.
+func v1Status(version int, status ErrorStatus, exists bool) ErrorStatus {
+	if version != Version1 || status <= GenErr {
+		return status
+	}
+
+	switch {
+	case status == NotWritable && exists:
+		return ReadOnly
+	case status == NotWritable, status == NoCreation, status == NoAccess,
+		status == AuthorizationError, status == InconsistentName:
+		return NoSuchName
+	case status == CommitFailed, status == UndoFailed, status == ResourceUnavailable:
+		return GenErr
+	}
+
+	return BadValue
+}
+
+// find returns the handler of oid.
+func find(handlers []agentHandler, oid ObjectIdentifier) (agentHandler, bool) {
+	i := sort.Search(len(handlers), func(i int) bool {
+		return handlers[i].oid.Compare(oid) >= 0
+	})
+
+	if i < len(handlers) && handlers[i].oid.Equal(oid) {
+		return handlers[i], true
+	}
+
+	return agentHandler{}, false
+}
+
+// lookup returns the binding for oid from handlers, which are sorted by
+// OID, or for the OID after it if next is set. It returns genErr if the
+// getter fails.
+func lookup(handlers []agentHandler, oid ObjectIdentifier, next bool) (Varbind, ErrorStatus) {
+	if !next {
+		h, ok := find(handlers, oid)
+		if !ok || h.getter == nil {
+			return NewVarbind(oid, NoSuchObject), NoError
+		}
+
+		return get(h)
+	}
+
+	i := sort.Search(len(handlers), func(i int) bool {
+		return handlers[i].oid.Compare(oid) > 0
+	})
+
+	// Write-only objects are skipped
+	for ; i < len(handlers); i++ {
+		if handlers[i].getter != nil {
+			return get(handlers[i])
+		}
+	}
+
+	return NewVarbind(oid, EndOfMIBView), NoError
+}
+
+// get returns the binding for h, or genErr if its getter fails.
+func get(h agentHandler) (Varbind, ErrorStatus) {
+	value, err := h.getter()
+	if err != nil || value == nil {
+		return Varbind{}, GenErr
+	}
+
+	return NewVarbind(h.oid, value), NoError
+}
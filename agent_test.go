@@ -0,0 +1,387 @@
+package snmp
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startAgent starts a and waits until it is listening.
+func startAgent(t *testing.T, a *Agent) string {
+	errs := make(chan error, 1)
+	go func() { errs <- a.ListenAndServe() }()
+
+	t.Cleanup(func() {
+		a.Close()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		if addr := a.LocalAddr(); addr != nil {
+			return addr.String()
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("Agent didn't start listening")
+	return ""
+}
+
+func TestAgent(t *testing.T) {
+	a := &Agent{Addr: "127.0.0.1:0"}
+
+	a.Handle(sysDescr, func() (DataType, error) { return String("test agent"), nil })
+	// Objects can be registered in any order
+	for _, i := range []uint32{3, 1, 2} {
+		name := String([]string{"lo", "eth0", "eth1"}[i-1])
+		a.Handle(ifDescr.Child(i), func() (DataType, error) { return name, nil })
+	}
+
+	sysLocation := MustParseOID(".1.3.6.1.2.1.1.6.0")
+	a.Handle(sysLocation, func() (DataType, error) { return nil, errors.New("unavailable") })
+
+	c := &Client{Addr: startAgent(t, a), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := c.Get(sysDescr, sysUpTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 2 || varbinds[0].Value() != String("test agent") || varbinds[1].Value() != NoSuchObject {
+		t.Errorf("unexpected varbinds %v", varbinds)
+	}
+
+	v, err := c.GetNext(ifDescr)
+	if err != nil || !v.OID.Equal(ifDescr.Child(1)) {
+		t.Errorf("expected the first ifDescr row, got %v = %v, %v", v.OID, v.Value(), err)
+	}
+
+	var names []string
+	walk := func(v Varbind) error {
+		name, _ := v.String()
+		names = append(names, name)
+		return nil
+	}
+
+	if err := c.Walk(ifDescr, walk); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 3 || names[0] != "lo" || names[1] != "eth0" || names[2] != "eth1" {
+		t.Errorf("expected [lo eth0 eth1], got %v", names)
+	}
+
+	names = nil
+	if err := c.BulkWalk(ifDescr, 2, walk); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 3 || names[2] != "eth1" {
+		t.Errorf("expected [lo eth0 eth1] from BulkWalk, got %v", names)
+	}
+
+	if _, err := c.GetNext(ifDescr.Child(3)); err != ErrEndOfMIBView {
+		t.Errorf("expected %v after the last object, got %v", ErrEndOfMIBView, err)
+	}
+
+	var snmpErr SNMPError
+	if _, err := c.Get(sysLocation); !errors.As(err, &snmpErr) || snmpErr.Status != GenErr {
+		t.Errorf("expected genErr from a failing getter, got %v", err)
+	}
+
+	c.Version = Version1
+	if _, err := c.Get(sysUpTime); !errors.Is(err, NoSuchName) {
+		t.Errorf("expected noSuchName from SNMPv1, got %v", err)
+	}
+
+}
+
+func TestAgentGetBulkTruncated(t *testing.T) {
+	a := &Agent{Addr: "127.0.0.1:0", MaxMessageSize: 200}
+
+	for i := uint32(1); i <= 20; i++ {
+		a.Handle(ifDescr.Child(i), func() (DataType, error) { return String("interface"), nil })
+	}
+
+	c := &Client{Addr: startAgent(t, a), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	var sizes []int
+	c.Trace = &Trace{OnReceive: func(packet []byte) { sizes = append(sizes, len(packet)) }}
+
+	count := 0
+	err := c.BulkWalk(ifDescr, 20, func(Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 20 {
+		t.Fatalf("expected 20 varbinds without error, got %d and %v", count, err)
+	}
+
+	if len(sizes) < 2 {
+		t.Errorf("expected the responses to be truncated, got %d", len(sizes))
+	}
+
+	for _, size := range sizes {
+		if size > 200 {
+			t.Errorf("expected responses of at most 200 bytes, got %d", size)
+		}
+	}
+}
+
+func TestAgentSet(t *testing.T) {
+	a := &Agent{Addr: "127.0.0.1:0"}
+
+	sysContact := MustParseOID(".1.3.6.1.2.1.1.4.0")
+	sysName := MustParseOID(".1.3.6.1.2.1.1.5.0")
+
+	var mu sync.Mutex
+	values := map[string]DataType{
+		sysContact.String(): String("admin"),
+		sysName.String():    String("router"),
+	}
+
+	value := func(oid ObjectIdentifier) DataType {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return values[oid.String()]
+	}
+
+	for _, oid := range []ObjectIdentifier{sysContact, sysName} {
+		key := oid.String()
+		oid := oid
+		a.Handle(oid, func() (DataType, error) { return value(oid), nil })
+		a.HandleSet(oid, func(v DataType) error {
+			s, ok := v.(String)
+			if !ok {
+				return WrongType
+			}
+
+			if len(s) > 8 {
+				return errors.New("too long")
+			}
+
+			return nil
+		}, func(v DataType) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			values[key] = v
+			return nil
+		})
+	}
+
+	a.Handle(sysDescr, func() (DataType, error) { return String("test agent"), nil })
+
+	c := &Client{Addr: startAgent(t, a), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	// The second binding fails validation, so neither is set
+	var snmpErr SNMPError
+	_, err := c.Set(NewVarbind(sysContact, String("ops")), NewVarbind(sysName, String("core-router-1")))
+	if !errors.As(err, &snmpErr) || snmpErr.Status != WrongValue || snmpErr.Index != 2 {
+		t.Fatalf("expected wrongValue for binding 2, got %v", err)
+	}
+
+	if value(sysContact) != String("admin") || value(sysName) != String("router") {
+		t.Errorf("expected no objects to be set, got %v and %v", value(sysContact), value(sysName))
+	}
+
+	if _, err := c.Set(NewVarbind(sysName, Int(1))); !errors.As(err, &snmpErr) || snmpErr.Status != WrongType {
+		t.Errorf("expected wrongType, got %v", err)
+	}
+
+	if _, err := c.Set(NewVarbind(sysContact, String("ops")), NewVarbind(sysName, String("core"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if value(sysContact) != String("ops") || value(sysName) != String("core") {
+		t.Errorf("expected both objects to be set, got %v and %v", value(sysContact), value(sysName))
+	}
+
+	for _, test := range []struct {
+		version  int
+		oid      ObjectIdentifier
+		expected ErrorStatus
+	}{
+		{Version2c, sysDescr, NotWritable},
+		{Version2c, sysUpTime, NoCreation},
+		{Version1, sysDescr, ReadOnly},
+		{Version1, sysUpTime, NoSuchName},
+		{Version1, sysName, BadValue},
+	} {
+		c.Version = test.version
+
+		v := NewVarbind(test.oid, String("x"))
+		if test.expected == BadValue {
+			v = NewVarbind(test.oid, String("too long for sysName"))
+		}
+
+		if _, err := c.Set(v); !errors.As(err, &snmpErr) || snmpErr.Status != test.expected {
+			t.Errorf("%v in version %d: expected %v, got %v", test.oid, test.version, test.expected, err)
+		}
+	}
+}
+
+func TestAgentCommunities(t *testing.T) {
+	a := &Agent{Addr: "127.0.0.1:0", ReadCommunity: "public", WriteCommunity: "private"}
+
+	a.Handle(sysDescr, func() (DataType, error) { return String("test agent"), nil })
+	a.HandleSet(sysDescr, nil, func(DataType) error { return nil })
+
+	c := &Client{Addr: startAgent(t, a), Version: Version2c, Timeout: 50 * time.Millisecond}
+
+	for _, test := range []struct {
+		community string
+		set       bool
+		answered  bool
+	}{
+		{"public", false, true},
+		{"private", false, true},
+		{"secret", false, false},
+		{"", false, false},
+		{"private", true, true},
+		{"public", true, false},
+	} {
+		c.Community = test.community
+
+		var err error
+		if test.set {
+			_, err = c.Set(NewVarbind(sysDescr, String("new")))
+		} else {
+			_, err = c.Get(sysDescr)
+		}
+
+		if test.answered && err != nil {
+			t.Errorf("%q, set %v: %v", test.community, test.set, err)
+		}
+
+		if !test.answered && err != ErrTimeout {
+			t.Errorf("%q, set %v: expected no response, got %v", test.community, test.set, err)
+		}
+	}
+}
+
+func TestAgentAllow(t *testing.T) {
+	a := &Agent{Addr: "127.0.0.1:0", ReadCommunity: "ignored"}
+
+	system := MustParseOID(".1.3.6.1.2.1.1")
+	ifTable := MustParseOID(".1.3.6.1.2.1.2.2")
+
+	var mu sync.Mutex
+	name := DataType(String("router"))
+
+	a.Handle(sysDescr, func() (DataType, error) { return String("test agent"), nil })
+	a.Handle(sysName, func() (DataType, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return name, nil
+	})
+
+	a.HandleSet(sysName, nil, func(v DataType) error {
+		mu.Lock()
+		defer mu.Unlock()
+		name = v
+		return nil
+	})
+
+	for i, s := range []String{"lo", "eth0", "eth1"} {
+		a.Handle(ifDescr.Child(uint32(i+1)), func() (DataType, error) { return s, nil })
+	}
+
+	a.Allow("public", ifTable, AccessReadOnly)
+	a.Allow("private", system, AccessReadOnly)
+	a.Allow("private", system, AccessReadWrite)
+	a.Allow("private", ifTable, AccessReadOnly)
+
+	addr := startAgent(t, a)
+	public := &Client{Addr: addr, Community: "public", Version: Version2c, Timeout: time.Second}
+	private := &Client{Addr: addr, Community: "private", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := public.Get(sysDescr, ifDescr.Child(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if varbinds[0].Value() != NoSuchObject || varbinds[1].Value() != String("lo") {
+		t.Errorf("expected only ifDescr.1 to be visible to public, got %v", varbinds)
+	}
+
+	// sysDescr and sysName are skipped
+	if v, err := public.GetNext(MustParseOID(".1.3.6.1.2.1")); err != nil || !v.OID.Equal(ifDescr.Child(1)) {
+		t.Errorf("expected GETNEXT to skip to ifDescr.1, got %v, %v", v, err)
+	}
+
+	for _, c := range []*Client{public, private} {
+		walked, err := c.WalkAll(MustParseOID(".1.3.6.1.2.1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var bulk []Varbind
+		err = c.BulkWalk(MustParseOID(".1.3.6.1.2.1"), 10, func(v Varbind) error {
+			bulk = append(bulk, v)
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 3
+		if c == private {
+			expected = 5
+		}
+
+		if len(walked) != expected || len(bulk) != expected {
+			t.Errorf("%s: expected %d objects, walked %v and bulk walked %v", c.Community, expected, walked, bulk)
+		}
+	}
+
+	var snmpErr SNMPError
+	if _, err := public.Set(NewVarbind(sysName, String("x"))); !errors.As(err, &snmpErr) || snmpErr.Status != NoAccess {
+		t.Errorf("expected noAccess for public, got %v", err)
+	}
+
+	if _, err := private.Set(NewVarbind(ifDescr.Child(1), String("x"))); !errors.As(err, &snmpErr) || snmpErr.Status != NoAccess {
+		t.Errorf("expected noAccess for a read-only subtree, got %v", err)
+	}
+
+	public.Version = Version1
+	if _, err := public.Set(NewVarbind(sysName, String("x"))); !errors.Is(err, NoSuchName) {
+		t.Errorf("expected noSuchName for SNMPv1, got %v", err)
+	}
+
+	if _, err := private.Set(NewVarbind(sysName, String("core"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := private.Get(sysName); err != nil || v[0].Value() != String("core") {
+		t.Errorf("expected sysName to be set, got %v, %v", v, err)
+	}
+
+	// Communities without subtrees, including ReadCommunity, are ignored
+	for _, community := range []string{"ignored", "secret"} {
+		c := &Client{Addr: addr, Community: community, Version: Version2c, Timeout: 50 * time.Millisecond}
+		if _, err := c.Get(sysDescr); err != ErrTimeout {
+			t.Errorf("%s: expected no response, got %v", community, err)
+		}
+	}
+}
+
+func TestAgentNegativeRequestID(t *testing.T) {
+	a := &Agent{}
+	a.Handle(sysDescr, func() (DataType, error) { return String("test agent"), nil })
+
+	for _, id := range []int32{-1, math.MinInt32} {
+		res := a.respond(&Message{Version: Version2c, Community: "public", PDU: NewGetRequest(id, sysDescr)})
+		if got := res.PDU.(GetResponse).RequestID(); got != int(id) {
+			t.Errorf("expected the request-id %d, got %d", id, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Bits returns the names of the bits set in value, the OCTET STRING
+// encoding of an SMIv2 BITS object, in order of position. Position 0 is
+// the most significant bit of the first octet. names maps positions to
+// names, as returned by MIB.BitNames, and bits without a name are
+// returned as their position in decimal.
+func Bits(value String, names map[int]string) []string {
+	var set []string
+
+	for i := 0; i < len(value)*8; i++ {
+		if value[i/8]&(0x80>>(i%8)) == 0 {
+			continue
+		}
+
+		name, ok := names[i]
+		if !ok {
+			name = strconv.Itoa(i)
+		}
+
+		set = append(set, name)
+	}
+
+	return set
+}
+
+// NewBits returns the OCTET STRING encoding of the BITS value with the
+// bits named by set. It has as many octets as the highest position in
+// names needs, and an error is returned if a name isn't in names.
+func NewBits(set []string, names map[int]string) (String, error) {
+	positions := make(map[string]int, len(names))
+	length := 0
+
+	for position, name := range names {
+		positions[name] = position
+
+		if position/8+1 > length {
+			length = position/8 + 1
+		}
+	}
+
+	b := make([]byte, length)
+
+	for _, name := range set {
+		position, ok := positions[name]
+		if !ok {
+			return "", fmt.Errorf("snmp: unknown bit %q", name)
+		}
+
+		b[position/8] |= 0x80 >> (position % 8)
+	}
+
+	return String(b), nil
+}
+
+// BitNames returns the positions and names of the bits of the object
+// of oid, if its SYNTAX is BITS.
+func (m *MIB) BitNames(oid ObjectIdentifier) (map[int]string, bool) {
+	node, _ := m.longestPrefix(oid)
+	if node == nil || m.baseSyntax(node.module, node.syntax.name) != "BITS" {
+		return nil, false
+	}
+
+	names := map[int]string{}
+	for _, enum := range m.enums(node) {
+		names[enum.value] = enum.name
+	}
+
+	return names, true
+}
@@ -0,0 +1,68 @@
+package snmp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBits(t *testing.T) {
+	names := map[int]string{0: "zero", 7: "seven", 8: "eight", 9: "nine", 10: "ten"}
+
+	value, err := NewBits([]string{"nine", "zero", "seven"}, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := String([]byte{0x81, 0x40}); value != expected {
+		t.Errorf("expected %x, got %x", expected, value)
+	}
+
+	if set := Bits(value, names); !reflect.DeepEqual(set, []string{"zero", "seven", "nine"}) {
+		t.Errorf("expected [zero seven nine], got %v", set)
+	}
+
+	// Bits without a name are returned as their position
+	if set := Bits(String([]byte{0x00, 0x40, 0x01}), names); !reflect.DeepEqual(set, []string{"nine", "23"}) {
+		t.Errorf("expected [nine 23], got %v", set)
+	}
+
+	if _, err := NewBits([]string{"eleven"}, names); err == nil {
+		t.Error("expected an error for an unknown bit")
+	}
+}
+
+func TestMIBBitNames(t *testing.T) {
+	m := testMIB(t)
+
+	err := m.Load(strings.NewReader(`
+	TEST-MIB DEFINITIONS ::= BEGIN
+	IMPORTS OBJECT-TYPE, enterprises FROM SNMPv2-SMI;
+
+	testCapabilities OBJECT-TYPE
+	    SYNTAX      BITS { zero(0), seven(7), nine(9) }
+	    MAX-ACCESS  read-only
+	    STATUS      current
+	    DESCRIPTION "A capabilities bitmap."
+	    ::= { enterprises 99999 1 }
+	END
+	`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, ok := m.BitNames(MustParseOID(".1.3.6.1.4.1.99999.1.0"))
+	if !ok || !reflect.DeepEqual(names, map[int]string{0: "zero", 7: "seven", 9: "nine"}) {
+		t.Fatalf("unexpected bit names %v (%v)", names, ok)
+	}
+
+	value, err := NewBits([]string{"zero", "seven", "nine"}, names)
+	if err != nil || value != String([]byte{0x81, 0x40}) {
+		t.Errorf("expected 8140, got %x (%v)", value, err)
+	}
+
+	if _, ok := m.BitNames(MustParseOID(".1.3.6.1.2.1.2.2.1.8.1")); ok {
+		t.Error("expected no bit names for the INTEGER ifOperStatus")
+	}
+}
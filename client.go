@@ -0,0 +1,1280 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTimeout is used when a Client has no Timeout set.
+const defaultTimeout = 5 * time.Second
+
+// defaultMaxPDUSize is used when a Client has no MaxPDUSize set.
+// It keeps requests within a single Ethernet frame.
+const defaultMaxPDUSize = 1400
+
+// pduHeaderSize is an upper bound on the size of the PDU
+// header, including its request-id and error fields.
+const pduHeaderSize = 32
+
+// defaultMaxMessageSize is used when a Client has no MaxMessageSize
+// set. It is the largest UDP payload in an Ethernet frame over IPv4.
+const defaultMaxMessageSize = 1472
+
+// defaultMaxWalkBindings is used when a Client has no MaxWalkBindings set.
+const defaultMaxWalkBindings = 100000
+
+// defaultPort is the port used when a Client address doesn't include one.
+const defaultPort = "161"
+
+var (
+	ErrTimeout = errors.New("snmp: timeout")
+
+	// ErrStopWalk can be returned by a walk function to stop
+	// the walk without an error.
+	ErrStopWalk = errors.New("snmp: stop walk")
+
+	// ErrEndOfMIBView is returned by GetNext when there is
+	// no OID after the requested one.
+	ErrEndOfMIBView = errors.New("snmp: end of MIB view")
+
+	ErrMessageTooLarge = errors.New("snmp: message too large")
+
+	ErrTooManyBindings = errors.New("snmp: too many variable bindings")
+
+	// ErrResponseTruncated is returned when a response datagram
+	// doesn't fit in the receive buffer sized by MaxMessageSize.
+	ErrResponseTruncated = errors.New("snmp: response truncated")
+
+	// ErrOIDNotIncreasing is returned by walks when an agent returns
+	// an OID that doesn't come after the previous one, other than the
+	// one requested, which would otherwise loop forever.
+	ErrOIDNotIncreasing = errors.New("snmp: OID not increasing")
+
+	// ErrOIDMismatch is returned by Get with StrictOIDs set when
+	// the agent responds with OIDs other than those requested.
+	ErrOIDMismatch = errors.New("snmp: response OID mismatch")
+
+	// ErrDuplicateOID is returned with RejectDuplicateOIDs set
+	// for a request that has an OID more than once.
+	ErrDuplicateOID = errors.New("snmp: duplicate OID in request")
+)
+
+// Client is an SNMP client for a single agent.
+type Client struct {
+	// Addr is the agent address as host or host:port, where an
+	// IPv6 host may be bracketed and have a zone, such as
+	// "[fe80::1%eth0]:161". Port is used if no port is given.
+	Addr string
+
+	// Port is the agent port used when an address doesn't include
+	// one, such as for agents that all listen on a custom port. 161
+	// is used if it is zero.
+	Port int
+
+	// Network is "udp", or "tcp" for SNMP over TCP as described in
+	// RFC 3430, which suits responses too large for a datagram.
+	// "udp" is used if it is empty.
+	Network string
+
+	// LocalAddr, if set, is the local address requests are sent
+	// from as host or host:port, such as the address of the interface
+	// agents expect them from. Any port is used if none is given.
+	LocalAddr string
+
+	// Community is used by SNMPv1 and SNMPv2c.
+	Community string
+	Version   int
+
+	// User holds the credentials used by SNMPv3.
+	User *USMUser
+
+	// Timeout is how long to wait for a response to each attempt.
+	Timeout time.Duration
+
+	// Retries is the number of times a request is resent after a
+	// timeout. A request can take up to Timeout × (Retries+1).
+	// Requests aren't resent over TCP.
+	Retries int
+
+	// MaxPDUSize bounds the size of each GetRequest sent by GetMany.
+	// 1400 bytes is used if it is zero.
+	MaxPDUSize int
+
+	// MaxMessageSize bounds the size of each encoded message sent.
+	// Larger messages fail with ErrMessageTooLarge before they are
+	// sent. 1472 bytes is used if it is zero, except over TCP,
+	// where messages are only limited if it is set. If it is set, it
+	// is also the size of the UDP receive buffer, and responses that
+	// don't fit fail with ErrResponseTruncated. Otherwise responses
+	// of up to 65535 bytes are received.
+	MaxMessageSize int
+
+	// EncodeOptions controls how requests are encoded, such as
+	// padding integers for agents that reject the minimal form.
+	EncodeOptions EncodeOptions
+
+	// Concurrency is the number of GetMany requests in flight.
+	// Requests are sent one at a time if it is zero.
+	Concurrency int
+
+	// RateLimit, if positive, is the most requests per second c sends,
+	// including retries, to spare agents on slow devices. Requests
+	// beyond it wait for their turn, or until their context is done.
+	RateLimit float64
+
+	// Interner, if set, interns the OIDs of the variable bindings
+	// returned by walks, which then must not be modified.
+	Interner *OIDInterner
+
+	// MIB, if set, is used by Set to check that each value
+	// matches the SYNTAX of its object before it is sent, and by
+	// GetByName and SetByName to resolve names.
+	MIB *MIB
+
+	// StrictOIDs makes Get and GetMany fail with ErrOIDMismatch if
+	// the OIDs of a response aren't those requested, as RFC 3416
+	// requires. It is off by default, since some agents that don't
+	// comply are still usable.
+	StrictOIDs bool
+
+	// RejectDuplicateOIDs makes Get, GetMany, and Prepare fail with
+	// ErrDuplicateOID, before anything is sent, if an OID is given
+	// more than once, which is legal but usually a mistake. Duplicates
+	// are sent by default.
+	RejectDuplicateOIDs bool
+
+	// MaxWalkBindings bounds the number of variable bindings
+	// collected by WalkAll or DumpAll. 100000 is used if it is zero.
+	MaxWalkBindings int
+
+	// MaxBindingsPerResponse, if set, bounds the number of variable
+	// bindings decoded from each response, to protect against agents
+	// that can't be trusted. A request whose response has more fails
+	// with an error wrapping ErrTooManyBindings. Encrypted SNMPv3
+	// responses and those of WalkRaw aren't decoded this way, and are
+	// only bounded by their size.
+	MaxBindingsPerResponse int
+
+	// Trace, if set, is called as messages are exchanged.
+	Trace *Trace
+
+	// Transport, if set, carries the messages c sends and their
+	// responses in place of a socket, such as to test without a
+	// network, and Addr, Network, LocalAddr, and MaxMessageSize's
+	// receive buffer are then ignored. Timeout and Retries still
+	// apply to each request.
+	Transport Transport
+
+	// engine is the SNMPv3 authoritative engine of the agent,
+	// once discovered.
+	mu     sync.Mutex
+	engine *engine
+
+	// nextSend is when the next request may be sent under RateLimit.
+	rateMu   sync.Mutex
+	nextSend time.Time
+}
+
+// Get returns the variable bindings for oids. Objects the agent doesn't
+// have are bound to an exception such as NoSuchObject, including when
+// an agent answers SNMPv2c or SNMPv3 with noSuchName as SNMPv1 does.
+func (c *Client) Get(oids ...ObjectIdentifier) ([]Varbind, error) {
+	return c.GetContext(context.Background(), oids...)
+}
+
+// GetContext is like Get but aborts when ctx is done.
+func (c *Client) GetContext(ctx context.Context, oids ...ObjectIdentifier) ([]Varbind, error) {
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return conn.GetContext(ctx, oids...)
+}
+
+// GetWithStats is like Get but also returns the statistics of the
+// request, such as to export as metrics. The Result holds the error
+// too, and its statistics are filled in as far as the request got.
+func (c *Client) GetWithStats(oids ...ObjectIdentifier) (Result, error) {
+	return c.GetWithStatsContext(context.Background(), oids...)
+}
+
+// GetWithStatsContext is like GetWithStats but aborts when ctx is done.
+func (c *Client) GetWithStatsContext(ctx context.Context, oids ...ObjectIdentifier) (Result, error) {
+	conn, err := c.Dial()
+	if err != nil {
+		return Result{Err: err}, err
+	}
+
+	defer conn.Close()
+
+	stats := &requestStats{}
+	conn.mx.stats = stats
+
+	varbinds, err := conn.GetContext(ctx, oids...)
+
+	return Result{
+		Varbinds:      varbinds,
+		Err:           err,
+		RoundTripTime: stats.roundTrip,
+		Attempts:      stats.attempts,
+		RequestSize:   stats.requestSize,
+		ResponseSize:  stats.responseSize,
+	}, err
+}
+
+// GetByName is like Get but takes the names of the objects, resolved
+// with c.MIB as ParseOIDWithMIB does, such as "sysDescr.0" or
+// "IF-MIB::ifDescr.2". Nothing is sent unless every name resolves.
+func (c *Client) GetByName(names ...string) ([]Varbind, error) {
+	return c.GetByNameContext(context.Background(), names...)
+}
+
+// GetByNameContext is like GetByName but aborts when ctx is done.
+func (c *Client) GetByNameContext(ctx context.Context, names ...string) ([]Varbind, error) {
+	if c.MIB == nil {
+		return nil, errors.New("snmp: GetByName requires a MIB")
+	}
+
+	oids := make([]ObjectIdentifier, len(names))
+
+	for i, name := range names {
+		oid, err := c.resolveName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		oids[i] = oid
+	}
+
+	return c.GetContext(ctx, oids...)
+}
+
+// GetPartial is like Get, but when the agent fails the request with
+// noSuchName, as SNMPv1 agents do for any OID they don't have, it sends
+// the request again without the OID at the error-index, until it
+// succeeds. It returns the bindings of the OIDs that remain and the
+// OIDs that failed, like the -Cf option of Net-SNMP's snmpget. SNMPv2c
+// and SNMPv3 agents report missing OIDs with exceptions in their
+// bindings instead.
+func (c *Client) GetPartial(oids ...ObjectIdentifier) ([]Varbind, []ObjectIdentifier, error) {
+	return c.GetPartialContext(context.Background(), oids...)
+}
+
+// GetPartialContext is like GetPartial but aborts when ctx is done.
+func (c *Client) GetPartialContext(ctx context.Context, oids ...ObjectIdentifier) ([]Varbind, []ObjectIdentifier, error) {
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer conn.Close()
+
+	remaining := append([]ObjectIdentifier(nil), oids...)
+
+	var failed []ObjectIdentifier
+
+	for len(remaining) > 0 {
+		varbinds, err := c.get(ctx, conn.mx, remaining)
+
+		// The error-index is resolved against the OIDs sent,
+		// as not every agent echoes them
+		var snmpErr SNMPError
+		if errors.As(err, &snmpErr) && snmpErr.Status == NoSuchName && snmpErr.RawIndex > 0 && snmpErr.RawIndex <= len(remaining) {
+			i := snmpErr.RawIndex - 1
+			failed = append(failed, remaining[i])
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			continue
+		}
+
+		if err != nil {
+			return nil, failed, err
+		}
+
+		return varbinds, failed, nil
+	}
+
+	return nil, failed, nil
+}
+
+// resolveName returns the OID of name with c.MIB.
+func (c *Client) resolveName(name string) (ObjectIdentifier, error) {
+	oid, err := ParseOIDWithMIB(name, c.MIB)
+	if err != nil {
+		return nil, fmt.Errorf("snmp: can't resolve %q: %w", name, err)
+	}
+
+	return oid, nil
+}
+
+func (c *Client) get(ctx context.Context, mx *mux, oids []ObjectIdentifier) ([]Varbind, error) {
+	if err := c.checkDuplicates(oids); err != nil {
+		return nil, err
+	}
+
+	varbinds, err := c.getOnce(ctx, mx, oids)
+
+	return c.retryNoSuchName(oids, varbinds, err, func(rest []ObjectIdentifier) ([]Varbind, error) {
+		return c.get(ctx, mx, rest)
+	})
+}
+
+// retryNoSuchName returns the result of a GetRequest for oids, varbinds
+// and err, with a noSuchName error of SNMPv2c and SNMPv3 normalized.
+// Some agents answer those requests for a missing object with
+// noSuchName, as SNMPv1 does, rather than with an exception. That
+// binding becomes noSuchObject, as the agent can't tell which exception
+// it is, and the rest are requested again with get, so that callers see
+// the same result for either.
+func (c *Client) retryNoSuchName(oids []ObjectIdentifier, varbinds []Varbind, err error, get func(rest []ObjectIdentifier) ([]Varbind, error)) ([]Varbind, error) {
+	var snmpErr SNMPError
+	if c.Version == Version1 || !errors.As(err, &snmpErr) || snmpErr.Status != NoSuchName ||
+		snmpErr.RawIndex < 1 || snmpErr.RawIndex > len(oids) {
+		return varbinds, err
+	}
+
+	i := snmpErr.RawIndex - 1
+	missing := NewVarbind(oids[i].Clone(), NoSuchObject)
+
+	rest := append(append([]ObjectIdentifier(nil), oids[:i]...), oids[i+1:]...)
+	if len(rest) == 0 {
+		return []Varbind{missing}, nil
+	}
+
+	varbinds, err = get(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(varbinds) != len(rest) {
+		return nil, errors.New("snmp: response has the wrong number of variable bindings")
+	}
+
+	result := make([]Varbind, 0, len(oids))
+	result = append(result, varbinds[:i]...)
+	result = append(result, missing)
+
+	return append(result, varbinds[i:]...), nil
+}
+
+// getOnce sends a single GetRequest for oids on mx.
+func (c *Client) getOnce(ctx context.Context, mx *mux, oids []ObjectIdentifier) ([]Varbind, error) {
+	res, err := c.request(ctx, mx, func(reqID int) DataType {
+		return newGetRequest(reqID, nullVarbinds(oids))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	varbinds := res.Varbinds()
+
+	if err := c.checkOIDs(oids, varbinds); err != nil {
+		return nil, err
+	}
+
+	return varbinds, nil
+}
+
+// checkDuplicates returns ErrDuplicateOID if c.RejectDuplicateOIDs is
+// set and oids has an OID more than once.
+func (c *Client) checkDuplicates(oids []ObjectIdentifier) error {
+	if !c.RejectDuplicateOIDs {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(oids))
+
+	for _, oid := range oids {
+		key := oid.Dotted()
+		if seen[key] {
+			return fmt.Errorf("%w: %v", ErrDuplicateOID, oid)
+		}
+
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// checkOIDs returns ErrOIDMismatch if c.StrictOIDs is set and the
+// OIDs of the response bindings varbinds aren't oids.
+func (c *Client) checkOIDs(oids []ObjectIdentifier, varbinds []Varbind) error {
+	if !c.StrictOIDs {
+		return nil
+	}
+
+	if len(varbinds) != len(oids) {
+		return fmt.Errorf("%w: requested %d bindings, got %d", ErrOIDMismatch, len(oids), len(varbinds))
+	}
+
+	for i, v := range varbinds {
+		if !v.OID.Equal(oids[i]) {
+			return fmt.Errorf("%w: requested %v, got %v", ErrOIDMismatch, oids[i], v.OID)
+		}
+	}
+
+	return nil
+}
+
+// GetNext returns the variable binding of the OID that follows oid,
+// such as the first row of a table given its entry OID. It returns
+// ErrEndOfMIBView if the agent has no OID after oid.
+func (c *Client) GetNext(oid ObjectIdentifier) (Varbind, error) {
+	return c.GetNextContext(context.Background(), oid)
+}
+
+// GetNextContext is like GetNext but aborts when ctx is done.
+func (c *Client) GetNextContext(ctx context.Context, oid ObjectIdentifier) (Varbind, error) {
+	conn, err := c.Dial()
+	if err != nil {
+		return Varbind{}, err
+	}
+
+	defer conn.Close()
+
+	return conn.GetNextContext(ctx, oid)
+}
+
+// Exists reports whether the agent has any object in the subtree
+// rooted at subtree, such as a row of a table given its entry OID,
+// with a single GetNext.
+func (c *Client) Exists(subtree ObjectIdentifier) (bool, error) {
+	return c.ExistsContext(context.Background(), subtree)
+}
+
+// ExistsContext is like Exists but aborts when ctx is done.
+func (c *Client) ExistsContext(ctx context.Context, subtree ObjectIdentifier) (bool, error) {
+	v, err := c.GetNextContext(ctx, subtree)
+	if err == ErrEndOfMIBView {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return !v.IsException() && v.OID.HasPrefix(subtree), nil
+}
+
+// SendInform sends an InformRequest for the notification trapOID to
+// c.Addr, on port 162 if it doesn't include a port, and waits for the
+// receiver to acknowledge it. The inform is resent on timeouts up to
+// c.Retries times, after which ErrTimeout is returned. An SNMPError
+// is returned if the acknowledgement has an error-status. Informs
+// are only supported with SNMPv2c.
+func (c *Client) SendInform(trapOID ObjectIdentifier, uptime TimeTicks, varbinds ...Varbind) error {
+	return c.SendInformContext(context.Background(), trapOID, uptime, varbinds...)
+}
+
+// SendInformContext is like SendInform but aborts when ctx is done.
+func (c *Client) SendInformContext(ctx context.Context, trapOID ObjectIdentifier, uptime TimeTicks, varbinds ...Varbind) error {
+	if c.Version != Version2c {
+		return errors.New("snmp: informs require SNMPv2c")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mx, err := c.dial(c.informAddress())
+	if err != nil {
+		return err
+	}
+
+	defer mx.close()
+
+	reqID := mx.reserve()
+	defer mx.release(reqID)
+
+	packet, err := c.EncodeOptions.Encode(Message{
+		Version:   c.Version,
+		Community: c.Community,
+		PDU:       NewInformRequest(reqID, uptime, trapOID, varbinds...),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	m, err := c.exchange(ctx, mx, reqID, packet, func(m *Message, _ []byte) bool {
+		res, ok := m.PDU.(GetResponse)
+		return ok && res.requestID == int(reqID)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = getResponse(m)
+	return err
+}
+
+// Set sets the value of each variable binding and returns the
+// variable bindings of the response. If c.MIB is set, a value that
+// doesn't match the SYNTAX of its object is reported with an error
+// wrapping ErrWrongSyntax without sending the request. If the agent
+// rejects a binding, the error is an SNMPError with the OID of that
+// binding, which errors.Is matches against its error-status, such as
+// NoSuchName, NotWritable, or WrongType.
+func (c *Client) Set(varbinds ...Varbind) ([]Varbind, error) {
+	return c.SetContext(context.Background(), varbinds...)
+}
+
+// SetContext is like Set but aborts when ctx is done.
+func (c *Client) SetContext(ctx context.Context, varbinds ...Varbind) ([]Varbind, error) {
+	if c.MIB != nil {
+		for _, v := range varbinds {
+			if err := c.MIB.CheckValue(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	res, err := c.request(ctx, conn.mx, func(reqID int) DataType {
+		return NewSetRequest(int32(reqID), varbinds...)
+	})
+
+	// Not every agent echoes the bindings of a failed SET,
+	// so the error-index is resolved against those sent
+	var snmpErr SNMPError
+	if errors.As(err, &snmpErr) && snmpErr.RawIndex > 0 && snmpErr.RawIndex <= len(varbinds) {
+		snmpErr.Index = snmpErr.RawIndex
+		snmpErr.OID = varbinds[snmpErr.Index-1].OID.Clone()
+		return nil, snmpErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Varbinds(), nil
+}
+
+// SetByName is like Set but takes the value of each object by its name,
+// resolved with c.MIB like GetByName. The bindings are sent in the order
+// of their OIDs, so that the error-index of a failed SET is the same for
+// the same values. Nothing is sent unless every name resolves.
+func (c *Client) SetByName(values map[string]DataType) ([]Varbind, error) {
+	return c.SetByNameContext(context.Background(), values)
+}
+
+// SetByNameContext is like SetByName but aborts when ctx is done.
+func (c *Client) SetByNameContext(ctx context.Context, values map[string]DataType) ([]Varbind, error) {
+	if c.MIB == nil {
+		return nil, errors.New("snmp: SetByName requires a MIB")
+	}
+
+	varbinds := make([]Varbind, 0, len(values))
+
+	for name, value := range values {
+		oid, err := c.resolveName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		varbinds = append(varbinds, NewVarbind(oid, value))
+	}
+
+	sort.Slice(varbinds, func(i, j int) bool {
+		return varbinds[i].OID.Compare(varbinds[j].OID) < 0
+	})
+
+	return c.SetContext(ctx, varbinds...)
+}
+
+// GetMany is like Get for any number of oids. It splits oids across
+// as many GetRequests as needed to stay within c.MaxPDUSize, halving
+// a request whenever the agent responds with tooBig or it exceeds
+// c.MaxMessageSize. The variable bindings are returned in the order
+// of oids. The requests share a single socket.
+func (c *Client) GetMany(oids []ObjectIdentifier) ([]Varbind, error) {
+	return c.GetManyContext(context.Background(), oids)
+}
+
+// GetManyContext is like GetMany but aborts when ctx is done.
+func (c *Client) GetManyContext(ctx context.Context, oids []ObjectIdentifier) ([]Varbind, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := c.checkDuplicates(oids); err != nil {
+		return nil, err
+	}
+
+	chunks, err := c.chunk(oids)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		results = make([]Varbind, len(oids))
+		sem     = make(chan struct{}, concurrency)
+		wg      sync.WaitGroup
+
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	for _, chunk := range chunks {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(start, end int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := c.getChunk(ctx, conn.mx, oids[start:end], results[start:end]); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(chunk[0], chunk[1])
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// chunk splits oids into [start, end) ranges whose
+// GetRequests fit within c.MaxPDUSize.
+func (c *Client) chunk(oids []ObjectIdentifier) ([][2]int, error) {
+	maxSize := c.MaxPDUSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxPDUSize
+	}
+
+	var chunks [][2]int
+
+	start, size := 0, pduHeaderSize
+	for i, oid := range oids {
+		b, err := NewVarbind(oid, Null).Encode()
+		if err != nil {
+			return nil, err
+		}
+
+		if i > start && size+len(b) > maxSize {
+			chunks = append(chunks, [2]int{start, i})
+			start, size = i, pduHeaderSize
+		}
+
+		size += len(b)
+	}
+
+	if start < len(oids) {
+		chunks = append(chunks, [2]int{start, len(oids)})
+	}
+
+	return chunks, nil
+}
+
+// getChunk gets oids into results, splitting the request in half
+// when the agent responds with tooBig or it is too large to send.
+func (c *Client) getChunk(ctx context.Context, mx *mux, oids []ObjectIdentifier, results []Varbind) error {
+	varbinds, err := c.get(ctx, mx, oids)
+	if (errors.Is(err, TooBig) || errors.Is(err, ErrMessageTooLarge)) && len(oids) > 1 {
+		half := len(oids) / 2
+
+		if err := c.getChunk(ctx, mx, oids[:half], results[:half]); err != nil {
+			return err
+		}
+
+		return c.getChunk(ctx, mx, oids[half:], results[half:])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(varbinds) != len(oids) {
+		return errors.New("snmp: response has the wrong number of variable bindings")
+	}
+
+	copy(results, varbinds)
+
+	return nil
+}
+
+// Walk calls fn for each variable binding in the subtree rooted at
+// root, in lexicographic order, using GETNEXT requests. The walk ends
+// when the agent returns an OID outside of root or endOfMibView, or
+// when fn returns an error. If fn returns ErrStopWalk, Walk returns nil.
+// An agent that answers with the OID requested, as some do instead of
+// endOfMibView after their last OID, ends the walk too, while one that
+// answers with an earlier OID fails it with ErrOIDNotIncreasing. The
+// root .1 walks the whole MIB.
+func (c *Client) Walk(root ObjectIdentifier, fn func(Varbind) error) error {
+	return c.WalkContext(context.Background(), root, fn)
+}
+
+// WalkContext is like Walk but aborts when ctx is done.
+func (c *Client) WalkContext(ctx context.Context, root ObjectIdentifier, fn func(Varbind) error) error {
+	conn, err := c.Dial()
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	return conn.WalkContext(ctx, root, fn)
+}
+
+func (c *Client) walk(ctx context.Context, mx *mux, root ObjectIdentifier, fn func(Varbind) error) error {
+	oid := walkStart(root)
+
+	for {
+		res, err := c.getNext(ctx, mx, oid)
+		if err != nil {
+			// SNMPv1 agents signal the end of the MIB with noSuchName
+			if errors.Is(err, NoSuchName) && c.Version == Version1 {
+				return nil
+			}
+
+			return err
+		}
+
+		if len(res.varbinds) == 0 {
+			return ErrDecodingType
+		}
+
+		v := res.varbinds[0]
+		if done, err := endOfWalk(root, oid, oid, v); done {
+			return err
+		}
+
+		c.intern(&v)
+
+		if err := fn(v); err != nil {
+			if err == ErrStopWalk {
+				return nil
+			}
+
+			return err
+		}
+
+		oid = v.OID
+	}
+}
+
+// WalkAll walks the subtree rooted at root like Walk and returns its
+// variable bindings sorted by OID. If the subtree has more than
+// c.MaxWalkBindings bindings, the walk stops and the bindings collected
+// so far are returned with ErrTooManyBindings.
+func (c *Client) WalkAll(root ObjectIdentifier) ([]Varbind, error) {
+	return c.WalkAllContext(context.Background(), root)
+}
+
+// WalkAllContext is like WalkAll but aborts when ctx is done.
+func (c *Client) WalkAllContext(ctx context.Context, root ObjectIdentifier) ([]Varbind, error) {
+	limit := c.MaxWalkBindings
+	if limit <= 0 {
+		limit = defaultMaxWalkBindings
+	}
+
+	var varbinds []Varbind
+
+	err := c.WalkContext(ctx, root, func(v Varbind) error {
+		if len(varbinds) == limit {
+			return fmt.Errorf("%w: more than %d under %v", ErrTooManyBindings, limit, root)
+		}
+
+		varbinds = append(varbinds, v)
+		return nil
+	})
+
+	sort.Slice(varbinds, func(i, j int) bool {
+		return varbinds[i].OID.Compare(varbinds[j].OID) < 0
+	})
+
+	return varbinds, err
+}
+
+// GetTable walks the columns of the conceptual table entryOID, such as
+// ifEntry, and returns its rows keyed by their index, i.e. the arcs
+// after the column OID in dotted form. Each row holds the variable
+// bindings present for it, in the order of columns. If columns is
+// empty, every column of the table is walked.
+func (c *Client) GetTable(entryOID ObjectIdentifier, columns []ObjectIdentifier) (map[string][]Varbind, error) {
+	return c.GetTableContext(context.Background(), entryOID, columns)
+}
+
+// GetTableContext is like GetTable but aborts when ctx is done.
+func (c *Client) GetTableContext(ctx context.Context, entryOID ObjectIdentifier, columns []ObjectIdentifier) (map[string][]Varbind, error) {
+	for _, column := range columns {
+		if len(column) != len(entryOID)+1 || !column.HasPrefix(entryOID) {
+			return nil, fmt.Errorf("snmp: %v is not a column of %v", column, entryOID)
+		}
+	}
+
+	roots := columns
+	if len(roots) == 0 {
+		roots = []ObjectIdentifier{entryOID}
+	}
+
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	rows := map[string][]Varbind{}
+
+	for _, root := range roots {
+		err := c.walk(ctx, conn.mx, root, func(v Varbind) error {
+			// The column is the arc after entryOID, and the index follows it
+			if len(v.OID) > len(entryOID)+1 {
+				index := v.OID[len(entryOID)+1:].Dotted()
+				rows[index] = append(rows[index], v)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+// BulkWalk is like Walk but uses GETBULK requests to retrieve up to
+// maxRepetitions variable bindings per request. It requires SNMPv2c.
+// When the agent responds with tooBig or the response is truncated,
+// the request is resent asking for half as many bindings, and after
+// each response that fits, a quarter more are asked for until
+// maxRepetitions is reached again. c.Trace can observe the number
+// asked for with OnBulkRepetitions.
+func (c *Client) BulkWalk(root ObjectIdentifier, maxRepetitions int, fn func(Varbind) error) error {
+	return c.BulkWalkContext(context.Background(), root, maxRepetitions, fn)
+}
+
+// BulkWalkContext is like BulkWalk but aborts when ctx is done.
+func (c *Client) BulkWalkContext(ctx context.Context, root ObjectIdentifier, maxRepetitions int, fn func(Varbind) error) error {
+	if c.Version == Version1 {
+		return errors.New("snmp: BulkWalk requires SNMPv2c")
+	}
+
+	conn, err := c.Dial()
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	oid := walkStart(root)
+	repetitions := maxRepetitions
+
+	for {
+		c.Trace.bulkRepetitions(repetitions)
+
+		res, err := c.request(ctx, conn.mx, func(reqID int) DataType {
+			return NewGetBulkRequest(int32(reqID), 0, repetitions, oid)
+		})
+
+		if (errors.Is(err, TooBig) || errors.Is(err, ErrResponseTruncated)) && repetitions > 1 {
+			repetitions /= 2
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if repetitions < maxRepetitions {
+			repetitions += (repetitions + 3) / 4
+
+			if repetitions > maxRepetitions {
+				repetitions = maxRepetitions
+			}
+		}
+
+		varbinds := res.varbinds
+
+		// An agent without room for a single repetition may return no
+		// bindings at all, so step past oid with a GetNext instead.
+		if len(varbinds) == 0 {
+			next, err := c.getNext(ctx, conn.mx, oid)
+			if err != nil {
+				return err
+			}
+
+			if len(next.varbinds) == 0 {
+				return ErrDecodingType
+			}
+
+			varbinds = next.varbinds[:1]
+		}
+
+		// Agents truncate responses that would exceed their maximum
+		// message size to fewer than maxRepetitions bindings. Unless
+		// the last one ends the walk, continue from it.
+		requested := oid
+
+		for _, v := range varbinds {
+			if done, err := endOfWalk(root, requested, oid, v); done {
+				return err
+			}
+
+			c.intern(&v)
+
+			if err := fn(v); err != nil {
+				if err == ErrStopWalk {
+					return nil
+				}
+
+				return err
+			}
+
+			oid = v.OID
+		}
+	}
+}
+
+// walkStart returns the OID a walk of root starts after. A root of a
+// single arc, such as .1, can't be encoded, so the walk starts after its
+// first child, .1.0, which isn't an object instance, instead.
+func walkStart(root ObjectIdentifier) ObjectIdentifier {
+	if len(root) == 1 {
+		return root.Child(0)
+	}
+
+	return root
+}
+
+// endOfWalk reports whether v ends a walk of root that last returned
+// prev, in response to a request for the OID after requested, and the
+// error to end the walk with if any. Some agents answer a request for
+// the last OID of their MIB with that OID rather than endOfMibView,
+// which ends the walk like endOfMibView does.
+func endOfWalk(root, requested, prev ObjectIdentifier, v Varbind) (bool, error) {
+	if v.IsException() || !v.OID.HasPrefix(root) || v.OID.Equal(requested) {
+		return true, nil
+	}
+
+	if v.OID.Compare(prev) <= 0 {
+		return true, fmt.Errorf("%w: agent returned %v after %v", ErrOIDNotIncreasing, v.OID, prev)
+	}
+
+	return false, nil
+}
+
+// intern interns the OID of v if c has an Interner.
+func (c *Client) intern(v *Varbind) {
+	if c.Interner != nil {
+		v.OID = c.Interner.Intern(v.OID)
+	}
+}
+
+// address returns the agent address with a port.
+func (c *Client) address() string {
+	return c.agentAddress(c.Addr)
+}
+
+// agentAddress returns the agent address addr with c.Port, or 161,
+// if it doesn't include a port.
+func (c *Client) agentAddress(addr string) string {
+	if c.Port != 0 {
+		return withDefaultPort(addr, strconv.Itoa(c.Port))
+	}
+
+	return withDefaultPort(addr, defaultPort)
+}
+
+// checkPort returns an error if the port of addr, as host:port, is
+// a number outside of 1 to 65535. Named ports are resolved on dialing.
+func checkPort(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if n, err := strconv.Atoi(port); err == nil && (n < 1 || n > 65535) {
+		return fmt.Errorf("snmp: invalid port %d in address %q", n, addr)
+	}
+
+	return nil
+}
+
+// informAddress returns the address informs are sent to,
+// with port 162 if c.Addr doesn't include a port.
+func (c *Client) informAddress() string {
+	return withDefaultPort(c.Addr, defaultTrapPort)
+}
+
+// withDefaultPort returns addr with port if it doesn't include one.
+// IPv6 addresses may be bracketed and have a zone, such as
+// "[fe80::1%eth0]:161", "[fe80::1%eth0]", or "fe80::1%eth0".
+func withDefaultPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		addr = addr[1 : len(addr)-1]
+	}
+
+	return net.JoinHostPort(addr, port)
+}
+
+// maxMessageSize returns the largest message c sends,
+// or 0 if there is no limit.
+func (c *Client) maxMessageSize() int {
+	if c.MaxMessageSize > 0 {
+		return c.MaxMessageSize
+	}
+
+	if c.network() == "tcp" {
+		return 0
+	}
+
+	return defaultMaxMessageSize
+}
+
+// decodeOptions returns the options responses to c are decoded with.
+func (c *Client) decodeOptions() DecodeOptions {
+	return DecodeOptions{MaxVarbinds: c.MaxBindingsPerResponse}
+}
+
+func (c *Client) network() string {
+	if c.Network == "" {
+		return "udp"
+	}
+
+	return c.Network
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+
+	return defaultTimeout
+}
+
+// getNext sends a GetNextRequest for oid on mx.
+func (c *Client) getNext(ctx context.Context, mx *mux, oid ObjectIdentifier) (GetResponse, error) {
+	return c.request(ctx, mx, func(reqID int) DataType {
+		return newGetNextRequest(reqID, nullVarbinds([]ObjectIdentifier{oid}))
+	})
+}
+
+// request sends the PDU built by pdu for a request ID reserved on mx
+// and waits for the matching GetResponse, resending the PDU on timeouts
+// up to c.Retries times. It returns ctx.Err() if ctx is done first.
+func (c *Client) request(ctx context.Context, mx *mux, pdu func(requestID int) DataType) (GetResponse, error) {
+	id := mx.reserve()
+	defer mx.release(id)
+
+	requestID := int(id)
+
+	if err := c.EncodeOptions.validate(); err != nil {
+		return GetResponse{}, err
+	}
+
+	req := c.EncodeOptions.pdu(pdu(requestID))
+
+	if c.Version == Version3 {
+		return c.requestV3(ctx, mx, req, requestID)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return GetResponse{}, err
+	}
+
+	packet, err := Message{
+		Version:   c.Version,
+		Community: c.Community,
+		PDU:       req,
+	}.Encode()
+
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	m, err := c.exchange(ctx, mx, id, packet, func(m *Message, _ []byte) bool {
+		res, ok := m.PDU.(GetResponse)
+		return ok && res.requestID == requestID
+	})
+
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	return getResponse(m)
+}
+
+// getResponse returns the GetResponse carried by m, with an
+// SNMPError if its error-status is not NoError.
+func getResponse(m *Message) (GetResponse, error) {
+	res, ok := m.PDU.(GetResponse)
+	if !ok {
+		return GetResponse{}, ErrDecodingType
+	}
+
+	return res, res.Err()
+}
+
+// exchange sends packet on mx and waits for a response to request id
+// accepted by match, resending packet on timeouts up to c.Retries
+// times. It returns ctx.Err() if ctx is done first.
+func (c *Client) exchange(ctx context.Context, mx *mux, id int32, packet []byte, match func(m *Message, packet []byte) bool) (*Message, error) {
+	res := c.send(ctx, mx, id, packet, match)
+	if res.err != nil {
+		c.Trace.error(res.err)
+	}
+
+	return res.m, res.err
+}
+
+// send is exchange without tracing its error. The response holds the
+// packet rather than a Message for requests reserved with reserveRaw.
+func (c *Client) send(ctx context.Context, mx *mux, id int32, packet []byte, match func(m *Message, packet []byte) bool) response {
+	if maxSize := c.maxMessageSize(); maxSize > 0 && len(packet) > maxSize {
+		return response{err: fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrMessageTooLarge, len(packet), maxSize)}
+	}
+
+	responses := mx.expect(id, match)
+
+	// TCP delivers the request or fails, so resending it can't help
+	retries := c.Retries
+	if mx.stream {
+		retries = 0
+	}
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err := c.throttle(ctx); err != nil {
+			return response{err: err}
+		}
+
+		if attempt > 0 {
+			c.Trace.retry(attempt)
+		}
+
+		c.Trace.send(packet)
+
+		if s := mx.stats; s != nil {
+			s.attempts++
+			s.requestSize = len(packet)
+		}
+
+		sent := time.Now()
+
+		if err := mx.write(ctx, packet, c.timeout()); err != nil {
+			return response{err: err}
+		}
+
+		res := c.receive(ctx, mx, responses)
+		if res.err == ErrTimeout {
+			continue
+		}
+
+		if s := mx.stats; s != nil && res.err == nil {
+			s.roundTrip = time.Since(sent)
+			s.responseSize = res.size
+		}
+
+		return res
+	}
+
+	return response{err: ErrTimeout}
+}
+
+// throttle waits until c may send a request under c.RateLimit, or
+// returns ctx.Err() if ctx is done first. Requests are spaced evenly,
+// in the order they call throttle.
+func (c *Client) throttle(ctx context.Context) error {
+	if c.RateLimit <= 0 {
+		return nil
+	}
+
+	interval := time.Duration(float64(time.Second) / c.RateLimit)
+
+	c.rateMu.Lock()
+	at := c.nextSend
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+
+	c.nextSend = at.Add(interval)
+	c.rateMu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the turn back unless a later request took the next one
+		c.rateMu.Lock()
+		if c.nextSend.Equal(at.Add(interval)) {
+			c.nextSend = at
+		}
+		c.rateMu.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// receive waits for a response on responses. Since every attempt of
+// a request is identical, a late response to an earlier attempt is
+// accepted.
+func (c *Client) receive(ctx context.Context, mx *mux, responses <-chan response) response {
+	timer := time.NewTimer(c.timeout())
+	defer timer.Stop()
+
+	select {
+	case res := <-responses:
+		return res
+	case <-timer.C:
+		return response{err: ErrTimeout}
+	case <-ctx.Done():
+		return response{err: ctx.Err()}
+	case <-mx.done:
+		return response{err: mx.err}
+	}
+}
@@ -0,0 +1,2140 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubAgent is a minimal SNMP agent serving a fixed set of
+// variable bindings for client tests.
+type stubAgent struct {
+	conn     net.PacketConn
+	varbinds []Varbind
+
+	mu sync.Mutex
+
+	// handler, if set, replaces the default request handling.
+	// Each returned Message is sent in order; returning none
+	// drops the request.
+	handler func(m *Message) []*Message
+
+	// authProtocol and authKey, if set, are used to verify
+	// authenticated SNMPv3 requests and authenticate responses.
+	authProtocol AuthProtocol
+	authKey      []byte
+
+	// privProtocol and privKey, if set, are used to decrypt
+	// encrypted SNMPv3 requests and encrypt responses.
+	privProtocol PrivProtocol
+	privKey      []byte
+
+	// sources holds the addresses requests were received from.
+	sources map[string]bool
+}
+
+func newStubAgent(t testing.TB, varbinds ...Varbind) *stubAgent {
+	return newStubAgentAt(t, "127.0.0.1:0", varbinds...)
+}
+
+// newStubAgentAt is like newStubAgent but listens on addr.
+func newStubAgentAt(t testing.TB, addr string, varbinds ...Varbind) *stubAgent {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted := append([]Varbind(nil), varbinds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].OID.Compare(sorted[j].OID) < 0 })
+
+	a := &stubAgent{conn: conn, varbinds: sorted, sources: map[string]bool{}}
+	t.Cleanup(func() { conn.Close() })
+
+	go a.serve()
+
+	return a
+}
+
+// handle replaces the default request handling with handler.
+func (a *stubAgent) handle(handler func(m *Message) []*Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.handler = handler
+}
+
+// authenticate makes the agent verify and authenticate
+// SNMPv3 messages with protocol and key.
+func (a *stubAgent) authenticate(protocol AuthProtocol, key []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.authProtocol = protocol
+	a.authKey = key
+}
+
+// privacy makes the agent decrypt and encrypt
+// SNMPv3 messages with protocol and key.
+func (a *stubAgent) privacy(protocol PrivProtocol, key []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.privProtocol = protocol
+	a.privKey = key
+}
+
+// sourceCount returns the number of addresses
+// requests were received from.
+func (a *stubAgent) sourceCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.sources)
+}
+
+func (a *stubAgent) addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *stubAgent) serve() {
+	buf := make([]byte, 65535)
+
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		m, _, err := decodeMessage(bytes.NewReader(buf[:n]))
+		if err != nil {
+			continue
+		}
+
+		a.mu.Lock()
+		a.sources[addr.String()] = true
+		handler := a.handler
+		authProtocol, authKey := a.authProtocol, a.authKey
+		privProtocol, privKey := a.privProtocol, a.privKey
+		a.mu.Unlock()
+
+		if m.Flags&FlagAuth != 0 && authProtocol.verifyPacket(authKey, buf[:n]) != nil {
+			continue
+		}
+
+		if m.Flags&FlagPriv != 0 && m.decrypt(privProtocol, privKey) != nil {
+			continue
+		}
+
+		responses := []*Message{a.respond(m)}
+		if handler != nil {
+			responses = handler(m)
+		}
+
+		for _, res := range responses {
+			if res == nil {
+				continue
+			}
+
+			if res.Flags&FlagPriv != 0 && res.PDU != nil {
+				if err := res.encrypt(privProtocol, privKey); err != nil {
+					continue
+				}
+			}
+
+			b, err := res.encodeAuthenticated(authProtocol, authKey)
+			if err != nil {
+				continue
+			}
+
+			a.conn.WriteTo(b, addr)
+		}
+	}
+}
+
+// respond answers GET, GETNEXT, and GETBULK requests from the
+// agent's variable bindings, and echoes SET requests.
+func (a *stubAgent) respond(m *Message) *Message {
+	var (
+		reqID    int
+		varbinds []Varbind
+	)
+
+	switch req := m.PDU.(type) {
+	case GetRequest:
+		reqID = req.requestID
+		for _, v := range req.varbinds {
+			varbinds = append(varbinds, a.get(v.OID))
+		}
+
+	case GetNextRequest:
+		reqID = req.requestID
+		for _, v := range req.varbinds {
+			varbinds = append(varbinds, a.next(v.OID))
+		}
+
+	case GetBulkRequest:
+		reqID = req.requestID
+		for i, v := range req.varbinds {
+			if i < req.NonRepeaters() {
+				varbinds = append(varbinds, a.next(v.OID))
+			}
+		}
+
+		repeaters := req.varbinds[minInt(req.NonRepeaters(), len(req.varbinds)):]
+		last := make([]ObjectIdentifier, len(repeaters))
+		for i, v := range repeaters {
+			last[i] = v.OID
+		}
+
+		for r := 0; r < req.MaxRepetitions(); r++ {
+			for i := range repeaters {
+				next := a.next(last[i])
+				varbinds = append(varbinds, next)
+				last[i] = next.OID
+			}
+		}
+
+	case SetRequest:
+		reqID = req.requestID
+		varbinds = req.varbinds
+
+	default:
+		return nil
+	}
+
+	res := *m
+	res.PDU = GetResponse{PDU: newPDU(reqID, 0, 0, varbinds)}
+	res.Flags &^= FlagReportable
+
+	return &res
+}
+
+func (a *stubAgent) get(oid ObjectIdentifier) Varbind {
+	for _, v := range a.varbinds {
+		if v.OID.Equal(oid) {
+			return v
+		}
+	}
+
+	return NewVarbind(oid, NoSuchInstance)
+}
+
+func (a *stubAgent) next(oid ObjectIdentifier) Varbind {
+	for _, v := range a.varbinds {
+		if v.OID.Compare(oid) > 0 {
+			return v
+		}
+	}
+
+	return NewVarbind(oid, EndOfMIBView)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+var (
+	sysDescr  = MustParseOID(".1.3.6.1.2.1.1.1.0")
+	sysUpTime = MustParseOID(".1.3.6.1.2.1.1.3.0")
+	sysName   = MustParseOID(".1.3.6.1.2.1.1.5.0")
+)
+
+func TestClientGet(t *testing.T) {
+	agent := newStubAgent(t,
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(sysUpTime, TimeTicks(12345)),
+	)
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+	}
+
+	varbinds, err := c.Get(sysDescr, sysUpTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 2 {
+		t.Fatalf("expected 2 varbinds, got %d", len(varbinds))
+	}
+
+	if varbinds[0].Value() != String("test agent") || varbinds[1].Value() != TimeTicks(12345) {
+		t.Errorf("unexpected values %v and %v", varbinds[0].Value(), varbinds[1].Value())
+	}
+}
+
+func TestClientStrictOIDs(t *testing.T) {
+	agent := newStubAgent(t)
+
+	// Answer with an extra .0 on the second OID
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetRequest)
+
+		varbinds := []Varbind{
+			NewVarbind(req.varbinds[0].OID, String("test agent")),
+			NewVarbind(req.varbinds[1].OID.Child(0), TimeTicks(12345)),
+		}
+
+		res := *m
+		res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, varbinds)}
+
+		return []*Message{&res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	if _, err := c.Get(sysDescr, sysUpTime); err != nil {
+		t.Fatalf("expected the mismatch to be accepted by default, got %v", err)
+	}
+
+	c.StrictOIDs = true
+
+	_, err := c.Get(sysDescr, sysUpTime)
+	if !errors.Is(err, ErrOIDMismatch) {
+		t.Fatalf("expected ErrOIDMismatch, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), sysUpTime.String()) || !strings.Contains(err.Error(), sysUpTime.Child(0).String()) {
+		t.Errorf("expected %q to name the requested and returned OIDs", err)
+	}
+}
+
+func TestClientRejectDuplicateOIDs(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")), NewVarbind(sysUpTime, TimeTicks(12345)))
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := c.Get(sysDescr, sysUpTime, sysDescr)
+	if err != nil || len(varbinds) != 3 {
+		t.Fatalf("expected the duplicate to be sent by default, got %v and %v", varbinds, err)
+	}
+
+	c.RejectDuplicateOIDs = true
+	atomic.StoreInt32(&requests, 0)
+
+	_, err = c.Get(sysDescr, sysUpTime, sysDescr)
+	if !errors.Is(err, ErrDuplicateOID) || !strings.Contains(err.Error(), sysDescr.String()) {
+		t.Errorf("expected ErrDuplicateOID naming sysDescr, got %v", err)
+	}
+
+	if _, err := c.GetMany([]ObjectIdentifier{sysUpTime, sysDescr, sysUpTime}); !errors.Is(err, ErrDuplicateOID) {
+		t.Errorf("expected ErrDuplicateOID from GetMany, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("expected nothing to be sent, got %d requests", n)
+	}
+
+	if _, err := c.Get(sysDescr, sysUpTime); err != nil {
+		t.Errorf("expected distinct OIDs to succeed, got %v", err)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second, RateLimit: 20}
+
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.Get(sysDescr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first request is sent at once, and the others 50ms apart
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected 5 requests to take at least 200ms, took %v", elapsed)
+	}
+
+	c.RateLimit = 1
+
+	if _, err := c.Get(sysDescr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+
+	if _, err := c.GetContext(ctx, sysDescr); err != context.DeadlineExceeded {
+		t.Errorf("expected the waiting request to be canceled, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected cancellation to unblock the request, took %v", elapsed)
+	}
+}
+
+func TestClientGetWithStats(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	// Drop the first attempt
+	var attempts int32
+	agent.handle(func(m *Message) []*Message {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return nil
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	var sent, received int
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   50 * time.Millisecond,
+		Retries:   1,
+		Trace: &Trace{
+			OnSend:    func(b []byte) { sent = len(b) },
+			OnReceive: func(b []byte) { received = len(b) },
+		},
+	}
+
+	res, err := c.GetWithStats(sysDescr)
+	if err != nil || res.Err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Varbinds) != 1 || res.Varbinds[0].Value() != String("test agent") {
+		t.Errorf("unexpected varbinds %v", res.Varbinds)
+	}
+
+	if res.Attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", res.Attempts)
+	}
+
+	if res.RoundTripTime <= 0 || res.RoundTripTime >= 50*time.Millisecond {
+		t.Errorf("expected the round trip of the second attempt, got %v", res.RoundTripTime)
+	}
+
+	if res.RequestSize != sent || res.ResponseSize != received {
+		t.Errorf("expected sizes %d and %d, got %d and %d", sent, received, res.RequestSize, res.ResponseSize)
+	}
+
+	agent.handle(func(*Message) []*Message { return nil })
+
+	res, err = c.GetWithStats(sysDescr)
+	if err != ErrTimeout || res.Err != ErrTimeout || res.Attempts != 2 || res.ResponseSize != 0 {
+		t.Errorf("expected a timeout after 2 attempts, got %+v", res)
+	}
+}
+
+func TestClientGetByName(t *testing.T) {
+	agent := newStubAgent(t,
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(ifDescr.Child(2), String("eth1")),
+	)
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MIB:       testMIB(t),
+	}
+
+	varbinds, err := c.GetByName("sysDescr.0", "IF-MIB::ifDescr.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 2 || !varbinds[0].OID.Equal(sysDescr) || !varbinds[1].OID.Equal(ifDescr.Child(2)) {
+		t.Fatalf("unexpected varbinds %v", varbinds)
+	}
+
+	if varbinds[0].Value() != String("test agent") || varbinds[1].Value() != String("eth1") {
+		t.Errorf("unexpected values %v and %v", varbinds[0].Value(), varbinds[1].Value())
+	}
+
+	_, err = c.GetByName("sysDescr.0", "ifDescrx.2")
+	if !errors.Is(err, ErrUnknownSymbol) || !strings.Contains(err.Error(), "ifDescrx") {
+		t.Errorf("expected an unknown symbol error naming ifDescrx, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+
+	c.MIB = nil
+	if _, err := c.GetByName("sysDescr.0"); err == nil {
+		t.Error("expected an error without a MIB")
+	}
+}
+
+func TestClientMaxBindingsPerResponse(t *testing.T) {
+	agent := newStubAgent(t)
+
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetRequest)
+
+		varbinds := make([]Varbind, 1000)
+		for i := range varbinds {
+			varbinds[i] = NewVarbind(sysDescr.Child(uint32(i)), Int(i))
+		}
+
+		return []*Message{{Version: m.Version, Community: m.Community, PDU: GetResponse{PDU: newPDU(req.requestID, 0, 0, varbinds)}}}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: 5 * time.Second, MaxBindingsPerResponse: 10}
+
+	start := time.Now()
+
+	_, err := c.Get(sysDescr)
+	if !errors.Is(err, ErrTooManyBindings) {
+		t.Fatalf("expected ErrTooManyBindings, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= c.Timeout {
+		t.Errorf("expected the request to fail without waiting for the timeout, took %v", elapsed)
+	}
+}
+
+func TestClientGetNoSuchNameV2c(t *testing.T) {
+	get := func(errorStatus bool) ([]Varbind, int32, error) {
+		agent := newStubAgent(t,
+			NewVarbind(sysDescr, String("test agent")),
+			NewVarbind(sysName, String("router")),
+		)
+
+		var requests int32
+		agent.handle(func(m *Message) []*Message {
+			atomic.AddInt32(&requests, 1)
+
+			res := agent.respond(m)
+			req := m.PDU.(GetRequest)
+			varbinds := res.PDU.(GetResponse).varbinds
+
+			for i, v := range varbinds {
+				if !v.IsException() {
+					continue
+				}
+
+				// Fail the request as SNMPv1 does, or mark the binding
+				if errorStatus {
+					res.PDU = GetResponse{PDU: newPDU(req.requestID, int(NoSuchName), i+1, req.varbinds)}
+					break
+				}
+
+				varbinds[i] = NewVarbind(v.OID, NoSuchObject)
+				res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, varbinds)}
+			}
+
+			return []*Message{res}
+		})
+
+		c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+		varbinds, err := c.Get(sysDescr, sysUpTime, sysName)
+		return varbinds, atomic.LoadInt32(&requests), err
+	}
+
+	exceptions, n, err := get(false)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 request without error, got %d and %v", n, err)
+	}
+
+	normalized, n, err := get(true)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 requests without error, got %d and %v", n, err)
+	}
+
+	if len(exceptions) != 3 || !reflect.DeepEqual(exceptions, normalized) {
+		t.Errorf("expected the same varbinds, got %v and %v", exceptions, normalized)
+	}
+
+	if v := normalized[1]; !v.OID.Equal(sysUpTime) || v.Value() != NoSuchObject {
+		t.Errorf("expected sysUpTime to be noSuchObject, got %v", v)
+	}
+}
+
+func TestClientGetPartial(t *testing.T) {
+	agent := newStubAgent(t,
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(sysName, String("router")),
+	)
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+
+		// SNMPv1 fails the whole request for a missing OID
+		res := agent.respond(m)
+		req := m.PDU.(GetRequest)
+		for i, v := range res.PDU.(GetResponse).varbinds {
+			if v.IsException() {
+				res.PDU = GetResponse{PDU: newPDU(req.requestID, int(NoSuchName), i+1, req.varbinds)}
+				break
+			}
+		}
+
+		return []*Message{res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version1, Timeout: time.Second}
+
+	varbinds, failed, err := c.GetPartial(sysDescr, sysUpTime, sysName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 2 || !varbinds[0].OID.Equal(sysDescr) || !varbinds[1].OID.Equal(sysName) {
+		t.Fatalf("unexpected varbinds %v", varbinds)
+	}
+
+	if varbinds[0].Value() != String("test agent") || varbinds[1].Value() != String("router") {
+		t.Errorf("unexpected values %v and %v", varbinds[0].Value(), varbinds[1].Value())
+	}
+
+	if len(failed) != 1 || !failed[0].Equal(sysUpTime) {
+		t.Errorf("expected sysUpTime to fail, got %v", failed)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+
+	varbinds, failed, err = c.GetPartial(sysUpTime)
+	if err != nil || len(varbinds) != 0 || len(failed) != 1 {
+		t.Errorf("expected only sysUpTime to fail, got %v, %v, and %v", varbinds, failed, err)
+	}
+}
+
+func TestClientRequestIDAliases(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	agent.handle(func(m *Message) []*Message {
+		id := int64(m.PDU.(GetRequest).requestID)
+
+		// Responses whose IDs agree with the request in their
+		// low 32 bits, or are its negation, are for other requests
+		var responses []*Message
+		for i, alias := range []int64{id + 1<<32, id - 1<<32, -id} {
+			res := agent.respond(m)
+			res.PDU = GetResponse{PDU: newPDU(int(alias), 0, 0, []Varbind{NewVarbind(sysDescr, String(fmt.Sprint("alias ", i)))})}
+			responses = append(responses, res)
+		}
+
+		return append(responses, agent.respond(m))
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := c.Get(sysDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if varbinds[0].Value() != String("test agent") {
+		t.Errorf("expected the response to the request, got %v", varbinds[0])
+	}
+
+	for _, id := range []int64{1 << 32, -1<<31 - 1} {
+		m := &Message{Version: Version2c, PDU: GetResponse{PDU: newPDU(int(id), 0, 0, nil)}}
+		if _, ok := responseID(m); ok {
+			t.Errorf("expected no request for the request-id %d", id)
+		}
+	}
+
+	m := &Message{Version: Version2c, PDU: GetResponse{PDU: newPDU(math.MinInt32, 0, 0, nil)}}
+	if id, ok := responseID(m); !ok || id != math.MinInt32 {
+		t.Errorf("expected the request-id %d, got %d", math.MinInt32, id)
+	}
+}
+
+func TestClientTrace(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	// Drop the first attempt
+	var attempts int32
+	agent.handle(func(m *Message) []*Message {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return nil
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	var (
+		mu       sync.Mutex
+		sent     [][]byte
+		received [][]byte
+		retries  []int
+		errs     []error
+	)
+
+	record := func(b []byte, to *[][]byte) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		*to = append(*to, append([]byte(nil), b...))
+	}
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   100 * time.Millisecond,
+		Retries:   1,
+		Trace: &Trace{
+			OnSend:    func(b []byte) { record(b, &sent) },
+			OnReceive: func(b []byte) { record(b, &received) },
+			OnRetry: func(attempt int) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				retries = append(retries, attempt)
+			},
+			OnError: func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				errs = append(errs, err)
+			},
+		},
+	}
+
+	varbinds, err := c.Get(sysDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+
+	if len(sent) != 2 || !bytes.Equal(sent[0], sent[1]) {
+		t.Fatalf("expected the same packet to be sent twice, got %x", sent)
+	}
+
+	req, err := Unmarshal(sent[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, ok := req.PDU.(GetRequest); !ok || !get.varbinds[0].OID.Equal(sysDescr) {
+		t.Errorf("unexpected request %+v", req.PDU)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 datagram received, got %d", len(received))
+	}
+
+	res, err := Unmarshal(received[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := res.PDU.(GetResponse).varbinds; len(v) != 1 || v[0].Value() != varbinds[0].Value() {
+		t.Errorf("unexpected response %v", v)
+	}
+
+	if len(retries) != 1 || retries[0] != 1 {
+		t.Errorf("expected one retry, got %v", retries)
+	}
+
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	mu.Unlock()
+
+	c.Retries = 0
+	atomic.StoreInt32(&attempts, 0)
+
+	_, err = c.Get(sysDescr)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err != ErrTimeout || len(errs) != 1 || errs[0] != ErrTimeout {
+		t.Errorf("expected ErrTimeout to be traced, got %v and %v", err, errs)
+	}
+}
+
+func TestClientGetError(t *testing.T) {
+	agent := newStubAgent(t)
+
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetRequest)
+
+		res := *m
+		res.PDU = GetResponse{PDU: newPDU(req.requestID, int(NoSuchName), 2, req.varbinds)}
+
+		return []*Message{&res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version1, Timeout: time.Second}
+
+	_, err := c.Get(sysDescr, sysName)
+
+	snmpErr, ok := err.(SNMPError)
+	if !ok {
+		t.Fatalf("expected an SNMPError, got %v", err)
+	}
+
+	if snmpErr.Status != NoSuchName || snmpErr.Index != 2 || !snmpErr.OID.Equal(sysName) {
+		t.Errorf("unexpected SNMPError %+v", snmpErr)
+	}
+}
+
+func TestClientSetWithMIB(t *testing.T) {
+	agent := newStubAgent(t)
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "private",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MIB:       testMIB(t),
+	}
+
+	varbinds, err := c.Set(NewVarbind(sysName, String("router")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 1 || varbinds[0].Value() != String("router") {
+		t.Errorf("unexpected varbinds %v", varbinds)
+	}
+
+	if _, err := c.Set(NewVarbind(sysName, Int(1))); !errors.Is(err, ErrWrongSyntax) {
+		t.Errorf("expected ErrWrongSyntax, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestClientSetByName(t *testing.T) {
+	agent := newStubAgent(t)
+
+	var sent []ObjectIdentifier
+	agent.handle(func(m *Message) []*Message {
+		agent.mu.Lock()
+		for _, v := range m.PDU.(SetRequest).varbinds {
+			sent = append(sent, v.OID)
+		}
+		agent.mu.Unlock()
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "private",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MIB:       testMIB(t),
+	}
+
+	varbinds, err := c.SetByName(map[string]DataType{
+		"SNMPv2-MIB::sysLocation.0": String("rack 4"),
+		"ifAdminStatus.2":           Int(2),
+		"sysName.0":                 String("router"),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	sysLocation := MustParseOID(".1.3.6.1.2.1.1.6.0")
+	ifAdminStatus2 := MustParseOID(".1.3.6.1.2.1.2.2.1.7.2")
+
+	// Sorted by OID
+	if len(sent) != 3 || !sent[0].Equal(sysName) || !sent[1].Equal(sysLocation) || !sent[2].Equal(ifAdminStatus2) {
+		t.Fatalf("unexpected OIDs sent %v", sent)
+	}
+
+	if len(varbinds) != 3 || varbinds[0].Value() != String("router") || varbinds[1].Value() != String("rack 4") || varbinds[2].Value() != Int(2) {
+		t.Errorf("unexpected varbinds %v", varbinds)
+	}
+
+	_, err = c.SetByName(map[string]DataType{"sysName.0": String("router"), "sysNamex.0": String("x")})
+	if !errors.Is(err, ErrUnknownSymbol) || !strings.Contains(err.Error(), "sysNamex") {
+		t.Errorf("expected an unknown symbol error naming sysNamex, got %v", err)
+	}
+
+	if len(sent) != 3 {
+		t.Errorf("expected no request for an unresolved name, got %v", sent)
+	}
+
+	c.MIB = nil
+	if _, err := c.SetByName(map[string]DataType{"sysName.0": String("router")}); err == nil {
+		t.Error("expected an error without a MIB")
+	}
+}
+
+func TestClientSetError(t *testing.T) {
+	varbinds := []Varbind{
+		NewVarbind(sysName, String("router")),
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(ifDescr.Child(2), String("eth1")),
+	}
+
+	for name, echo := range map[string]bool{"echoed": true, "empty": false} {
+		t.Run(name, func(t *testing.T) {
+			agent := newStubAgent(t)
+			agent.handle(func(m *Message) []*Message {
+				req := m.PDU.(SetRequest)
+
+				var res []Varbind
+				if echo {
+					res = req.varbinds
+				}
+
+				return []*Message{{
+					Version:   m.Version,
+					Community: m.Community,
+					PDU:       GetResponse{PDU: newPDU(req.requestID, int(NotWritable), 3, res)},
+				}}
+			})
+
+			c := &Client{Addr: agent.addr(), Community: "private", Version: Version2c, Timeout: time.Second}
+
+			_, err := c.Set(varbinds...)
+
+			var snmpErr SNMPError
+			if !errors.As(err, &snmpErr) || !errors.Is(err, NotWritable) {
+				t.Fatalf("expected a notWritable SNMPError, got %v", err)
+			}
+
+			if snmpErr.Index != 3 || !snmpErr.OID.Equal(ifDescr.Child(2)) {
+				t.Errorf("expected the error for varbind 3 (%v), got %v", ifDescr.Child(2), err)
+			}
+
+			if !strings.Contains(err.Error(), ifDescr.Child(2).String()) {
+				t.Errorf("expected %q to name %v", err, ifDescr.Child(2))
+			}
+		})
+	}
+}
+
+func TestClientSetIntegerWidth(t *testing.T) {
+	agent := newStubAgent(t)
+
+	var (
+		mu   sync.Mutex
+		sent []byte
+	)
+
+	c := &Client{
+		Addr:          agent.addr(),
+		Community:     "private",
+		Version:       Version2c,
+		Timeout:       time.Second,
+		EncodeOptions: EncodeOptions{IntegerWidth: 4},
+		Trace: &Trace{OnSend: func(b []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			sent = append([]byte(nil), b...)
+		}},
+	}
+
+	varbinds, err := c.Set(NewVarbind(sysName, Int(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 1 || varbinds[0].Value() != Int(5) {
+		t.Errorf("unexpected varbinds %v", varbinds)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !bytes.HasSuffix(sent, []byte{0x02, 0x04, 0x00, 0x00, 0x00, 0x05}) {
+		t.Errorf("expected a 4-byte INTEGER in %x", sent)
+	}
+}
+
+func TestClientGetMany(t *testing.T) {
+	var (
+		oids     []ObjectIdentifier
+		varbinds []Varbind
+	)
+
+	for i := uint32(1); i <= 200; i++ {
+		oid := ifDescr.Child(i)
+		oids = append(oids, oid)
+		varbinds = append(varbinds, NewVarbind(oid, Int(i)))
+	}
+
+	// Request the OIDs out of order
+	for i := range oids {
+		j := (i * 7) % len(oids)
+		oids[i], oids[j] = oids[j], oids[i]
+	}
+
+	agent := newStubAgent(t, varbinds...)
+
+	var requests, tooBig int32
+
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+
+		req := m.PDU.(GetRequest)
+		if len(req.varbinds) > 30 {
+			atomic.AddInt32(&tooBig, 1)
+
+			res := *m
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, int(TooBig), 0, req.varbinds)}
+
+			return []*Message{&res}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	for _, concurrency := range []int{0, 4} {
+		atomic.StoreInt32(&requests, 0)
+		atomic.StoreInt32(&tooBig, 0)
+
+		c := &Client{
+			Addr:        agent.addr(),
+			Community:   "public",
+			Version:     Version2c,
+			Timeout:     time.Second,
+			MaxPDUSize:  1000,
+			Concurrency: concurrency,
+		}
+
+		res, err := c.GetMany(oids)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != len(oids) {
+			t.Fatalf("expected %d varbinds, got %d", len(oids), len(res))
+		}
+
+		for i, v := range res {
+			if !v.OID.Equal(oids[i]) || v.Value() != Int(oids[i][len(oids[i])-1]) {
+				t.Fatalf("expected %v at %d, got %v", oids[i], i, v)
+			}
+		}
+
+		// 1000 bytes hold up to 60 bindings, so the first three
+		// of four requests are too big and split in half
+		if n := atomic.LoadInt32(&tooBig); n != 3 {
+			t.Errorf("expected 3 tooBig responses, got %d", n)
+		}
+
+		if n := atomic.LoadInt32(&requests); n != 10 {
+			t.Errorf("expected 10 requests, got %d", n)
+		}
+	}
+}
+
+func TestClientMessageTooLarge(t *testing.T) {
+	agent := newStubAgent(t)
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	oids := make([]ObjectIdentifier, 100)
+	for i := range oids {
+		oids[i] = ifDescr.Child(uint32(i + 1))
+	}
+
+	_, err := c.Get(oids...)
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("expected ErrMessageTooLarge, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "exceeds the limit of 1472") {
+		t.Errorf("expected the error to name the limit, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("expected no requests to be sent, got %d", n)
+	}
+
+	// GetMany splits requests that are too large to send
+	c.MaxPDUSize = 4000
+	varbinds, err := c.GetMany(oids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != len(oids) {
+		t.Errorf("expected %d varbinds, got %d", len(oids), len(varbinds))
+	}
+}
+
+func TestClientSharedSocket(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// Hold the first request and answer both in reverse order
+	// once the second arrives
+	var held *Message
+	agent.handle(func(m *Message) []*Message {
+		if held == nil {
+			held = m
+			return nil
+		}
+
+		first := held
+		held = nil
+
+		return []*Message{agent.respond(m), agent.respond(first)}
+	})
+
+	c := &Client{
+		Addr:        agent.addr(),
+		Community:   "public",
+		Version:     Version2c,
+		Timeout:     time.Second,
+		MaxPDUSize:  50,
+		Concurrency: 2,
+	}
+
+	oids := []ObjectIdentifier{ifDescr.Child(1), ifDescr.Child(2)}
+
+	varbinds, err := c.GetMany(oids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 2 || varbinds[0].Value() != String("lo") || varbinds[1].Value() != String("eth0") {
+		t.Errorf("responses were routed to the wrong requests: %v", varbinds)
+	}
+
+	if n := agent.sourceCount(); n != 1 {
+		t.Errorf("expected the requests to share a socket, got %d sources", n)
+	}
+}
+
+func TestClientLocalAddr(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	c := &Client{
+		Addr:      agent.addr(),
+		LocalAddr: "127.0.0.2",
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+	}
+
+	if _, err := c.Get(sysDescr); err != nil {
+		t.Fatal(err)
+	}
+
+	agent.mu.Lock()
+	for src := range agent.sources {
+		if host, _, _ := net.SplitHostPort(src); host != "127.0.0.2" {
+			t.Errorf("expected requests from 127.0.0.2, got %s", src)
+		}
+	}
+	agent.mu.Unlock()
+
+	for _, local := range []string{"127.0.0.1:99999", "192.0.2.1"} {
+		c.LocalAddr = local
+
+		_, err := c.Dial()
+		if err == nil || !strings.Contains(err.Error(), local) {
+			t.Errorf("expected an error naming %q, got %v", local, err)
+		}
+	}
+}
+
+func TestMuxReserve(t *testing.T) {
+	mx, err := dialMux("udp", "", "127.0.0.1:9", 0, DecodeOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer mx.close()
+
+	ids := map[int32]bool{}
+	for i := 0; i < 1000; i++ {
+		id := mx.reserve()
+		if id <= 0 || ids[id] {
+			t.Fatalf("reserved an invalid or duplicate request ID %d", id)
+		}
+
+		ids[id] = true
+	}
+}
+
+func TestClientIgnoresMismatchedResponses(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	agent.handle(func(m *Message) []*Message {
+		res := agent.respond(m)
+
+		// Send a response for some other request first
+		stale := *res
+		pdu := stale.PDU.(GetResponse)
+		stale.PDU = GetResponse{PDU: newPDU(pdu.requestID+1, 0, 0, []Varbind{NewVarbind(sysDescr, String("stale"))})}
+
+		return []*Message{&stale, res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := c.Get(sysDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if varbinds[0].Value() != String("test agent") {
+		t.Errorf("expected %q, got %v", "test agent", varbinds[0].Value())
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	agent := newStubAgent(t)
+	agent.handle(func(m *Message) []*Message { return nil })
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: 50 * time.Millisecond}
+
+	if _, err := c.Get(sysDescr); err != ErrTimeout {
+		t.Errorf("expected %v, got %v", ErrTimeout, err)
+	}
+}
+
+func TestClientDefaultPort(t *testing.T) {
+	c := &Client{Addr: "192.0.2.1"}
+	if addr := c.address(); addr != "192.0.2.1:161" {
+		t.Errorf("expected 192.0.2.1:161, got %s", addr)
+	}
+
+	c = &Client{Addr: "192.0.2.1:1161"}
+	if addr := c.address(); addr != "192.0.2.1:1161" {
+		t.Errorf("expected 192.0.2.1:1161, got %s", addr)
+	}
+
+	for addr, expected := range map[string]string{
+		"2001:db8::1":          "[2001:db8::1]:161",
+		"[2001:db8::1]":        "[2001:db8::1]:161",
+		"[2001:db8::1]:1161":   "[2001:db8::1]:1161",
+		"fe80::1%eth0":         "[fe80::1%eth0]:161",
+		"[fe80::1%eth0]:1161":  "[fe80::1%eth0]:1161",
+		"agent.example.com":    "agent.example.com:161",
+		"agent.example.com:16": "agent.example.com:16",
+	} {
+		c := &Client{Addr: addr}
+		if got := c.address(); got != expected {
+			t.Errorf("%s: expected %s, got %s", addr, expected, got)
+		}
+	}
+}
+
+func TestClientPort(t *testing.T) {
+	first := newStubAgent(t, NewVarbind(sysName, String("first")))
+	second := newStubAgent(t, NewVarbind(sysName, String("second")))
+
+	_, port, _ := net.SplitHostPort(first.addr())
+	n, _ := strconv.Atoi(port)
+
+	// The port is used for an address without one
+	c := &Client{Addr: "127.0.0.1", Port: n, Community: "public", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := c.Get(sysName)
+	if err != nil || varbinds[0].Value() != String("first") {
+		t.Fatalf("expected the first agent, got %v and %v", varbinds, err)
+	}
+
+	// An explicit port wins
+	conn, err := c.DialAddr(second.addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	varbinds, err = conn.Get(sysName)
+	if err != nil || varbinds[0].Value() != String("second") {
+		t.Errorf("expected the second agent, got %v and %v", varbinds, err)
+	}
+
+	if c.Addr != "127.0.0.1" {
+		t.Errorf("expected DialAddr to leave the Client unchanged, got %s", c.Addr)
+	}
+
+	for _, bad := range []*Client{
+		{Addr: "127.0.0.1", Port: 70000},
+		{Addr: "127.0.0.1", Port: -1},
+		{Addr: "127.0.0.1:0"},
+		{Addr: "127.0.0.1:65536", Port: 161},
+	} {
+		if _, err := bad.Dial(); err == nil || !strings.Contains(err.Error(), "invalid port") {
+			t.Errorf("%s with port %d: expected an invalid port error, got %v", bad.Addr, bad.Port, err)
+		}
+	}
+}
+
+// serveTCP answers requests to a over TCP connections to l,
+// counting the connections accepted.
+func serveTCP(l net.Listener, a *Agent, accepted *int32) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		atomic.AddInt32(accepted, 1)
+
+		go func() {
+			defer conn.Close()
+
+			for {
+				m, _, err := ReadMessageFrom(conn)
+				if err != nil {
+					return
+				}
+
+				if res := a.respond(m); res != nil {
+					res.WriteTo(conn)
+				}
+			}
+		}()
+	}
+}
+
+func TestClientTCP(t *testing.T) {
+	a := &Agent{MaxMessageSize: 1 << 20}
+	for i := uint32(1); i <= 500; i++ {
+		a.Handle(ifDescr.Child(i), func() (DataType, error) { return String(strings.Repeat("x", 100)), nil })
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	var accepted int32
+	go serveTCP(l, a, &accepted)
+
+	var mu sync.Mutex
+	var sizes []int
+
+	c := &Client{
+		Addr:      l.Addr().String(),
+		Network:   "tcp",
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		Trace: &Trace{OnReceive: func(b []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			sizes = append(sizes, len(b))
+		}},
+	}
+
+	count := 0
+	err = c.BulkWalk(ifDescr, 500, func(Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 500 {
+		t.Fatalf("expected 500 varbinds without error, got %d and %v", count, err)
+	}
+
+	if n := atomic.LoadInt32(&accepted); n != 1 {
+		t.Errorf("expected 1 connection, got %d", n)
+	}
+
+	mu.Lock()
+	if len(sizes) != 2 || sizes[0] < 50000 {
+		t.Errorf("expected a response of over 50000 bytes and then the end of the table, got %v", sizes)
+	}
+	mu.Unlock()
+}
+
+func TestClientTCPNoRetries(t *testing.T) {
+	// Accept connections but never respond
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+		}
+	}()
+
+	var sends int32
+	c := &Client{
+		Addr:      l.Addr().String(),
+		Network:   "tcp",
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   50 * time.Millisecond,
+		Retries:   3,
+		Trace:     &Trace{OnSend: func([]byte) { atomic.AddInt32(&sends, 1) }},
+	}
+
+	if _, err := c.Get(sysDescr); err != ErrTimeout {
+		t.Errorf("expected %v, got %v", ErrTimeout, err)
+	}
+
+	if n := atomic.LoadInt32(&sends); n != 1 {
+		t.Errorf("expected 1 send without retries, got %d", n)
+	}
+}
+
+func TestClientIPv6(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback is unavailable:", err)
+	}
+
+	conn.Close()
+
+	agent := newStubAgentAt(t, "[::1]:0", NewVarbind(sysDescr, String("test agent")))
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	varbinds, err := c.Get(sysDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 1 || varbinds[0].Value() != String("test agent") {
+		t.Errorf("unexpected varbinds %v", varbinds)
+	}
+}
+
+var ifDescr = MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+// ifTableAgent returns a stub agent serving a small interface table.
+func ifTableAgent(t *testing.T) *stubAgent {
+	return newStubAgent(t,
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(ifDescr.Child(3), String("eth1")),
+		NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.3.1"), Int(24)),
+		NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.3.2"), Int(6)),
+	)
+}
+
+func TestClientGetNext(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	v, err := c.GetNext(ifDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !v.OID.Equal(ifDescr.Child(1)) || v.Value() != String("lo") {
+		t.Errorf("expected the first ifDescr row, got %v = %v", v.OID, v.Value())
+	}
+
+	if _, err := c.GetNext(MustParseOID(".1.3.6.1.2.1.2.2.1.3.2")); err != ErrEndOfMIBView {
+		t.Errorf("expected ErrEndOfMIBView, got %v", err)
+	}
+}
+
+func TestClientExists(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	for oid, expected := range map[string]bool{
+		".1.3.6.1.2.1.2.2.1.2": true,
+		// sysORTable sorts before the populated ifTable
+		".1.3.6.1.2.1.1.9": false,
+		// ifMtu sorts after every object of the agent
+		".1.3.6.1.2.1.2.2.1.4": false,
+	} {
+		exists, err := c.Exists(MustParseOID(oid))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists != expected {
+			t.Errorf("expected Exists(%s) to be %t", oid, expected)
+		}
+	}
+}
+
+func TestClientSendInform(t *testing.T) {
+	agent := newStubAgent(t)
+
+	var attempts, status int32
+
+	agent.handle(func(m *Message) []*Message {
+		req, ok := m.PDU.(InformRequest)
+		if !ok {
+			t.Errorf("expected an InformRequest, got %T", m.PDU)
+			return nil
+		}
+
+		// Acknowledge every second attempt
+		if atomic.AddInt32(&attempts, 1)%2 == 1 {
+			return nil
+		}
+
+		res := *m
+		res.PDU = GetResponse{PDU: newPDU(req.requestID, int(atomic.LoadInt32(&status)), 0, req.varbinds)}
+
+		return []*Message{&res}
+	})
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   100 * time.Millisecond,
+		Retries:   1,
+	}
+
+	linkDown := MustParseOID(".1.3.6.1.6.3.1.1.5.3")
+
+	if err := c.SendInform(linkDown, 100, NewVarbind(ifDescr.Child(1), String("lo"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+
+	atomic.StoreInt32(&status, int32(GenErr))
+	if err := c.SendInform(linkDown, 200); !errors.Is(err, GenErr) {
+		t.Errorf("expected genErr, got %v", err)
+	}
+
+	c.Retries = 0
+	if err := c.SendInform(linkDown, 300); err != ErrTimeout {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestClientGetTable(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	ifEntry := MustParseOID(".1.3.6.1.2.1.2.2.1")
+	ifType := ifEntry.Child(3)
+
+	for _, columns := range [][]ObjectIdentifier{{ifDescr, ifType}, nil} {
+		rows, err := c.GetTable(ifEntry, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rows) != 3 {
+			t.Fatalf("expected 3 rows, got %d", len(rows))
+		}
+
+		if row := rows["2"]; len(row) != 2 || row[0].Value() != String("eth0") || row[1].Value() != Int(6) {
+			t.Errorf("unexpected row 2 %v", row)
+		}
+
+		// ifType is missing from row 3
+		if row := rows["3"]; len(row) != 1 || !row[0].OID.Equal(ifDescr.Child(3)) {
+			t.Errorf("unexpected row 3 %v", row)
+		}
+	}
+
+	if _, err := c.GetTable(ifEntry, []ObjectIdentifier{sysDescr}); err == nil {
+		t.Error("expected an error for a column outside of the table")
+	}
+}
+
+func TestClientWalk(t *testing.T) {
+	agent := ifTableAgent(t)
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	var names []string
+	err := c.Walk(ifDescr, func(v Varbind) error {
+		name, err := v.GetStringValue()
+		names = append(names, name)
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 3 || names[0] != "lo" || names[1] != "eth0" || names[2] != "eth1" {
+		t.Errorf("expected [lo eth0 eth1], got %v", names)
+	}
+
+	// Walking past the last OID ends at endOfMibView
+	count := 0
+	err = c.Walk(MustParseOID(".1.3.6.1.2.1.2.2.1.3"), func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 2 {
+		t.Errorf("expected 2 varbinds without error, got %d and %v", count, err)
+	}
+
+	// ErrStopWalk stops early without an error
+	count = 0
+	err = c.Walk(ifDescr, func(v Varbind) error {
+		count++
+		return ErrStopWalk
+	})
+
+	if err != nil || count != 1 {
+		t.Errorf("expected 1 varbind without error, got %d and %v", count, err)
+	}
+}
+
+func TestClientWalkEcho(t *testing.T) {
+	agent := ifTableAgent(t)
+	last := MustParseOID(".1.3.6.1.2.1.2.2.1.3.2")
+
+	// Echo the last OID back instead of signalling endOfMibView
+	agent.handle(func(m *Message) []*Message {
+		var req PDU
+		switch pdu := m.PDU.(type) {
+		case GetNextRequest:
+			req = pdu.PDU
+		case GetBulkRequest:
+			req = pdu.PDU
+		}
+
+		if req.varbinds[0].OID.Equal(last) {
+			res := *m
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, []Varbind{agent.get(last)})}
+
+			return []*Message{&res}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+	root := MustParseOID(".1.3.6.1")
+
+	count := 0
+	err := c.Walk(root, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 6 {
+		t.Errorf("expected 6 varbinds without error, got %d and %v", count, err)
+	}
+
+	count = 0
+	err = c.BulkWalk(root, 4, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 6 {
+		t.Errorf("expected 6 varbinds from BulkWalk without error, got %d and %v", count, err)
+	}
+
+	count = 0
+	err = c.WalkRaw(root, func(oid, value []byte) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 6 {
+		t.Errorf("expected 6 varbinds from WalkRaw without error, got %d and %v", count, err)
+	}
+
+	// Echoing the root ends the walk before it starts
+	count = 0
+	err = c.Walk(last, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 0 {
+		t.Errorf("expected no varbinds without error, got %d and %v", count, err)
+	}
+}
+
+func TestClientWalkAll(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	ifEntry := MustParseOID(".1.3.6.1.2.1.2.2.1")
+
+	varbinds, err := c.WalkAll(ifEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 5 {
+		t.Fatalf("expected 5 varbinds, got %d", len(varbinds))
+	}
+
+	for i := 1; i < len(varbinds); i++ {
+		if varbinds[i-1].OID.Compare(varbinds[i].OID) >= 0 {
+			t.Errorf("varbinds are not sorted: %v before %v", varbinds[i-1].OID, varbinds[i].OID)
+		}
+	}
+
+	c.MaxWalkBindings = 2
+
+	varbinds, err = c.WalkAll(ifEntry)
+	if !errors.Is(err, ErrTooManyBindings) {
+		t.Errorf("expected ErrTooManyBindings, got %v", err)
+	}
+
+	if len(varbinds) != 2 || !varbinds[0].OID.Equal(ifDescr.Child(1)) {
+		t.Errorf("expected the first 2 varbinds, got %v", varbinds)
+	}
+}
+
+func TestClientBulkWalk(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		atomic.AddInt32(&requests, 1)
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	var names []string
+	err := c.BulkWalk(ifDescr, 2, func(v Varbind) error {
+		name, err := v.GetStringValue()
+		names = append(names, name)
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 3 || names[0] != "lo" || names[1] != "eth0" || names[2] != "eth1" {
+		t.Errorf("expected [lo eth0 eth1], got %v", names)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+
+	c.Version = Version1
+	if err := c.BulkWalk(ifDescr, 2, func(Varbind) error { return nil }); err == nil {
+		t.Error("expected an error using BulkWalk with SNMPv1")
+	}
+}
+
+func TestClientBulkWalkShortResponses(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// Return a single repetition no matter how many were requested
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetBulkRequest)
+		res := *m
+		res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, []Varbind{agent.next(req.varbinds[0].OID)})}
+
+		return []*Message{&res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	count := 0
+	err := c.BulkWalk(ifDescr, 10, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 3 {
+		t.Errorf("expected 3 varbinds without error, got %d and %v", count, err)
+	}
+}
+
+func TestClientBulkWalkTruncatedResponse(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// Truncate the first response to one repetition, as an agent
+	// does when the rest would exceed its maximum message size
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		res := agent.respond(m)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			pdu := res.PDU.(GetResponse)
+			res.PDU = GetResponse{PDU: newPDU(pdu.requestID, 0, 0, pdu.varbinds[:1])}
+		}
+
+		return []*Message{res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	var names []string
+	err := c.BulkWalk(ifDescr, 10, func(v Varbind) error {
+		name, _ := v.String()
+		names = append(names, name)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 3 || names[0] != "lo" || names[1] != "eth0" || names[2] != "eth1" {
+		t.Errorf("expected [lo eth0 eth1], got %v", names)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestClientBulkWalkEmptyResponse(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// Answer GetBulk requests with no bindings at all
+	var bulkRequests int32
+	agent.handle(func(m *Message) []*Message {
+		res := agent.respond(m)
+		if req, ok := m.PDU.(GetBulkRequest); ok {
+			atomic.AddInt32(&bulkRequests, 1)
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, nil)}
+		}
+
+		return []*Message{res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	count := 0
+	err := c.BulkWalk(ifDescr, 10, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 3 {
+		t.Errorf("expected 3 varbinds without error, got %d and %v", count, err)
+	}
+
+	if n := atomic.LoadInt32(&bulkRequests); n != 4 {
+		t.Errorf("expected 4 GetBulk requests, got %d", n)
+	}
+}
+
+func TestClientBulkWalkBacksOff(t *testing.T) {
+	rows := make([]Varbind, 40)
+	for i := range rows {
+		rows[i] = NewVarbind(ifDescr.Child(uint32(i+1)), String(fmt.Sprintf("eth%d", i)))
+	}
+
+	agent := newStubAgent(t, rows...)
+
+	// Fail requests for more than 3 repetitions with tooBig
+	agent.handle(func(m *Message) []*Message {
+		if req, ok := m.PDU.(GetBulkRequest); ok && req.MaxRepetitions() > 3 {
+			res := *m
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, int(TooBig), 0, req.varbinds)}
+
+			return []*Message{&res}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	var repetitions []int
+
+	c := &Client{
+		Addr:      agent.addr(),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		Trace:     &Trace{OnBulkRepetitions: func(n int) { repetitions = append(repetitions, n) }},
+	}
+
+	count := 0
+	err := c.BulkWalk(ifDescr, 16, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != len(rows) {
+		t.Fatalf("expected %d varbinds without error, got %d and %v", len(rows), count, err)
+	}
+
+	if len(repetitions) < 5 || fmt.Sprint(repetitions[:5]) != "[16 8 4 2 3]" {
+		t.Errorf("expected to back off from 16 to 2 and ramp up to 3, got %v", repetitions)
+	}
+
+	// A single repetition that is too big fails the walk
+	c.Trace = nil
+
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetBulkRequest)
+		res := *m
+		res.PDU = GetResponse{PDU: newPDU(req.requestID, int(TooBig), 0, req.varbinds)}
+
+		return []*Message{&res}
+	})
+
+	if err := c.BulkWalk(ifDescr, 16, func(Varbind) error { return nil }); !errors.Is(err, TooBig) {
+		t.Errorf("expected tooBig, got %v", err)
+	}
+}
+
+func TestClientWalkNotIncreasing(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// Answer a GetNext of the second row with the first one
+	agent.handle(func(m *Message) []*Message {
+		if req, ok := m.PDU.(GetNextRequest); ok && req.varbinds[0].OID.Equal(ifDescr.Child(2)) {
+			res := *m
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, []Varbind{agent.get(ifDescr.Child(1))})}
+
+			return []*Message{&res}
+		}
+
+		if req, ok := m.PDU.(GetBulkRequest); ok {
+			res := *m
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, 0, 0, []Varbind{
+				agent.get(ifDescr.Child(1)),
+				agent.get(ifDescr.Child(2)),
+				agent.get(ifDescr.Child(2)),
+			})}
+
+			return []*Message{&res}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	count := 0
+	err := c.Walk(ifDescr, func(Varbind) error {
+		count++
+		return nil
+	})
+
+	if !errors.Is(err, ErrOIDNotIncreasing) || count != 2 {
+		t.Fatalf("expected ErrOIDNotIncreasing after 2 varbinds, got %v after %d", err, count)
+	}
+
+	if expected := fmt.Sprintf("snmp: OID not increasing: agent returned %v after %v", ifDescr.Child(1), ifDescr.Child(2)); err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	count = 0
+	err = c.BulkWalk(ifDescr, 3, func(Varbind) error {
+		count++
+		return nil
+	})
+
+	if !errors.Is(err, ErrOIDNotIncreasing) || count != 2 {
+		t.Errorf("expected ErrOIDNotIncreasing from BulkWalk after 2 varbinds, got %v after %d", err, count)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		// Drop every other request
+		if atomic.AddInt32(&requests, 1)%2 == 1 {
+			return nil
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: 50 * time.Millisecond}
+
+	if _, err := c.Get(sysDescr); err != ErrTimeout {
+		t.Errorf("expected %v without retries, got %v", ErrTimeout, err)
+	}
+
+	c.Retries = 1
+
+	varbinds, err := c.Get(sysDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if varbinds[0].Value() != String("test agent") {
+		t.Errorf("expected %q, got %v", "test agent", varbinds[0].Value())
+	}
+
+	count := 0
+	err = c.Walk(ifDescr, func(v Varbind) error {
+		count++
+		return nil
+	})
+
+	if err != nil || count != 3 {
+		t.Errorf("expected 3 varbinds without error, got %d and %v", count, err)
+	}
+}
+
+func TestClientContextCancel(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests int32
+	agent.handle(func(m *Message) []*Message {
+		// Answer the first request, then stop responding
+		if atomic.AddInt32(&requests, 1) > 1 {
+			return nil
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: 10 * time.Second}
+
+	start := time.Now()
+	err := c.WalkContext(ctx, ifDescr, func(v Varbind) error {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		return nil
+	})
+
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the walk to stop promptly, took %v", elapsed)
+	}
+
+	if _, err := c.GetContext(ctx, sysDescr); err != context.Canceled {
+		t.Errorf("expected %v from a cancelled context, got %v", context.Canceled, err)
+	}
+}
+
+func TestClientContextDeadline(t *testing.T) {
+	agent := ifTableAgent(t)
+	agent.handle(func(m *Message) []*Message { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: 10 * time.Second, Retries: 3}
+
+	if _, err := c.GetContext(ctx, sysDescr); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestClientResponseTruncated(t *testing.T) {
+	agent := newStubAgent(t,
+		NewVarbind(sysDescr, String(strings.Repeat("x", 1000))),
+		NewVarbind(sysUpTime, TimeTicks(12345)),
+	)
+
+	c := &Client{
+		Addr:           agent.addr(),
+		Community:      "public",
+		Version:        Version2c,
+		Timeout:        5 * time.Second,
+		MaxMessageSize: 512,
+	}
+
+	start := time.Now()
+
+	if _, err := c.Get(sysDescr); !errors.Is(err, ErrResponseTruncated) {
+		t.Fatalf("expected ErrResponseTruncated, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the truncation to be reported without waiting for a timeout, took %v", elapsed)
+	}
+
+	// Responses that fit are still received
+	if varbinds, err := c.Get(sysUpTime); err != nil || varbinds[0].Value() != TimeTicks(12345) {
+		t.Errorf("expected sysUpTime, got %v, %v", varbinds, err)
+	}
+
+	c.MaxMessageSize = 0
+
+	if varbinds, err := c.Get(sysDescr); err != nil || len(varbinds[0].Value().(String)) != 1000 {
+		t.Errorf("expected the whole sysDescr with the default buffer, got %v", err)
+	}
+}
+
+func TestPeekID(t *testing.T) {
+	for _, m := range []Message{
+		{Version: Version2c, Community: "public", PDU: GetResponse{PDU: newPDU(1234, 0, 0, []Varbind{NewVarbind(sysDescr, String("test agent"))})}},
+		{Version: Version1, Community: "public", PDU: GetResponse{PDU: newPDU(1234, 0, 0, []Varbind{NewVarbind(sysDescr, String("test agent"))})}},
+	} {
+		b, err := m.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id, ok := peekID(b[:len(b)-5]); !ok || id != 1234 {
+			t.Errorf("version %d: expected request ID 1234, got %d, %v", m.Version, id, ok)
+		}
+
+		if _, ok := peekID(b[:10]); ok {
+			t.Errorf("version %d: expected no request ID before it", m.Version)
+		}
+	}
+}
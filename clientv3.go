@@ -0,0 +1,306 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// USMUser holds the SNMPv3 user-based security model credentials
+// of a Client.
+type USMUser struct {
+	Name           string
+	AuthProtocol   AuthProtocol
+	AuthPassphrase string
+
+	// Privacy requires authentication.
+	PrivProtocol   PrivProtocol
+	PrivPassphrase string
+}
+
+// engine is an SNMPv3 authoritative engine discovered by a Client.
+type engine struct {
+	id    []byte
+	boots int32
+	time  int32
+
+	// at is when the engine reported time. It comes from time.Now,
+	// so now advances by the monotonic clock, unaffected by changes
+	// to the wall clock.
+	at time.Time
+
+	authKey []byte
+	privKey []byte
+}
+
+// now returns the estimated current time of the engine.
+func (e *engine) now() int32 {
+	return e.time + int32(time.Since(e.at)/time.Second)
+}
+
+// DiscoverEngine discovers the authoritative engine ID, boots, and
+// time of the agent with an unauthenticated request, as described in
+// RFC 3414 section 4. Later SNMPv3 requests use the discovered engine.
+func (c *Client) DiscoverEngine(ctx context.Context) (engineID []byte, boots, time int32, err error) {
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	defer conn.Close()
+
+	return c.discoverEngine(ctx, conn.mx)
+}
+
+func (c *Client) discoverEngine(ctx context.Context, mx *mux) (engineID []byte, boots, time int32, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	id := mx.reserve()
+	defer mx.release(id)
+
+	msgID := int(id)
+
+	packet, err := Message{
+		Version:   Version3,
+		MessageID: msgID,
+		Flags:     FlagReportable,
+		PDU:       newGetRequest(msgID, nil),
+	}.Encode()
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	m, err := c.exchange(ctx, mx, id, packet, func(m *Message, _ []byte) bool {
+		_, ok := m.PDU.(Report)
+		return ok && m.Version == Version3 && m.MessageID == msgID
+	})
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if err := reportError(m.PDU.(Report)); err != ErrUnknownEngineID {
+		if err == nil {
+			err = errors.New("snmp: unexpected discovery Report")
+		}
+
+		return nil, 0, 0, err
+	}
+
+	security := m.Security
+	if len(security.AuthoritativeEngineID) == 0 {
+		return nil, 0, 0, errors.New("snmp: agent reported an empty engine ID")
+	}
+
+	e, err := c.newEngine(security)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	c.mu.Lock()
+	c.engine = e
+	c.mu.Unlock()
+
+	return e.id, e.boots, e.time, nil
+}
+
+// newEngine returns the engine described by security, with the
+// keys of c.User localized to it.
+func (c *Client) newEngine(security USMSecurityParameters) (*engine, error) {
+	e := &engine{
+		id:    security.AuthoritativeEngineID,
+		boots: int32(security.AuthoritativeEngineBoots),
+		time:  int32(security.AuthoritativeEngineTime),
+		at:    time.Now(),
+	}
+
+	if c.User != nil && c.User.AuthProtocol != NoAuth {
+		key, err := LocalizeKey(c.User.AuthProtocol, c.User.AuthPassphrase, e.id)
+		if err != nil {
+			return nil, err
+		}
+
+		e.authKey = key
+	}
+
+	if c.User != nil && c.User.PrivProtocol != NoPriv {
+		key, err := LocalizePrivKey(c.User.AuthProtocol, c.User.PrivProtocol, c.User.PrivPassphrase, e.id)
+		if err != nil {
+			return nil, err
+		}
+
+		e.privKey = key
+	}
+
+	return e, nil
+}
+
+// discoveredEngine returns the engine of the agent,
+// discovering it first if needed.
+func (c *Client) discoveredEngine(ctx context.Context, mx *mux) (*engine, error) {
+	c.mu.Lock()
+	e := c.engine
+	c.mu.Unlock()
+
+	if e != nil {
+		return e, nil
+	}
+
+	if _, _, _, err := c.discoverEngine(ctx, mx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.engine, nil
+}
+
+// requestV3 is like request for SNMPv3. It resynchronizes with the
+// engine and retries once if the agent reports an unknown engine ID
+// or a message outside of its time window.
+func (c *Client) requestV3(ctx context.Context, mx *mux, pdu DataType, requestID int) (GetResponse, error) {
+	if c.User == nil {
+		return GetResponse{}, errors.New("snmp: SNMPv3 requires a User")
+	}
+
+	if c.User.PrivProtocol != NoPriv && c.User.AuthProtocol == NoAuth {
+		return GetResponse{}, errors.New("snmp: privacy requires authentication")
+	}
+
+	e, err := c.discoveredEngine(ctx, mx)
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	m, err := c.sendV3(ctx, mx, e, pdu, requestID)
+
+	switch {
+	case err == ErrUnknownEngineID:
+		c.mu.Lock()
+		c.engine = nil
+		c.mu.Unlock()
+
+		if e, err = c.discoveredEngine(ctx, mx); err != nil {
+			return GetResponse{}, err
+		}
+
+		m, err = c.sendV3(ctx, mx, e, pdu, requestID)
+
+	case err == ErrNotInTimeWindow && m.Flags&FlagAuth != 0:
+		synced := *e
+		synced.boots = int32(m.Security.AuthoritativeEngineBoots)
+		synced.time = int32(m.Security.AuthoritativeEngineTime)
+		synced.at = time.Now()
+
+		c.mu.Lock()
+		c.engine = &synced
+		c.mu.Unlock()
+
+		m, err = c.sendV3(ctx, mx, &synced, pdu, requestID)
+	}
+
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	return getResponse(m)
+}
+
+// sendV3 sends pdu to engine e in an SNMPv3 message on mx and waits
+// for the matching GetResponse or Report. If the agent returns a Report,
+// the error it reports is returned along with the Message.
+func (c *Client) sendV3(ctx context.Context, mx *mux, e *engine, pdu DataType, requestID int) (*Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	auth, priv := c.User.AuthProtocol, c.User.PrivProtocol
+
+	m := Message{
+		Version:   Version3,
+		MessageID: requestID,
+		Flags:     FlagReportable,
+		Security: USMSecurityParameters{
+			AuthoritativeEngineID:    e.id,
+			AuthoritativeEngineBoots: int(e.boots),
+			AuthoritativeEngineTime:  int(e.now()),
+			UserName:                 c.User.Name,
+		},
+		ContextEngineID: e.id,
+		PDU:             pdu,
+	}
+
+	if auth != NoAuth {
+		m.Flags |= FlagAuth
+	}
+
+	if priv != NoPriv {
+		if err := m.encrypt(priv, e.privKey); err != nil {
+			return nil, err
+		}
+	}
+
+	packet, err := m.encodeAuthenticated(auth, e.authKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.exchange(ctx, mx, int32(requestID), packet, func(res *Message, packet []byte) bool {
+		if res.Version != Version3 || res.MessageID != requestID {
+			return false
+		}
+
+		// Encrypted messages are always authenticated
+		if res.Flags&FlagPriv != 0 {
+			if priv == NoPriv || auth.verifyPacket(e.authKey, packet) != nil {
+				return false
+			}
+
+			if res.decrypt(priv, e.privKey) != nil {
+				return false
+			}
+		}
+
+		switch p := res.PDU.(type) {
+		case GetResponse:
+			// Responses are authenticated and encrypted like their requests
+			if p.requestID != requestID {
+				return false
+			}
+
+			if priv != NoPriv && res.Flags&FlagPriv == 0 {
+				return false
+			}
+
+			if auth != NoAuth {
+				return res.Flags&FlagAuth != 0 && auth.verifyPacket(e.authKey, packet) == nil
+			}
+
+			return true
+
+		case Report:
+			// Some Reports, such as unknown user names, can't be authenticated
+			if res.Flags&FlagAuth != 0 {
+				return auth != NoAuth && auth.verifyPacket(e.authKey, packet) == nil
+			}
+
+			return true
+		}
+
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if report, ok := res.PDU.(Report); ok {
+		return res, reportError(report)
+	}
+
+	return res, nil
+}
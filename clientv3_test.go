@@ -0,0 +1,291 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testEngineID = []byte{0x80, 0, 0x1f, 0x88, 0x80, 0xde, 0xad, 0xbe, 0xef}
+
+// usmReport returns an SNMPv3 Report answering m with the usmStats
+// counter sub-identifier and the given engine boots and time.
+func usmReport(m *Message, counter uint32, boots, engineTime int) *Message {
+	var reqID int
+	if req, ok := m.PDU.(GetRequest); ok {
+		reqID = req.requestID
+	}
+
+	return &Message{
+		Version:   Version3,
+		MessageID: m.MessageID,
+		Flags:     m.Flags & FlagAuth,
+		Security: USMSecurityParameters{
+			AuthoritativeEngineID:    testEngineID,
+			AuthoritativeEngineBoots: boots,
+			AuthoritativeEngineTime:  engineTime,
+			UserName:                 m.Security.UserName,
+		},
+		ContextEngineID: testEngineID,
+		PDU: Report{
+			Int(reqID), Int(0), Int(0),
+			Sequence{NewVarbind(usmStats.Append(counter, 0), Counter(1))},
+		},
+	}
+}
+
+// v3Agent returns a stub agent that answers engine discovery and
+// serves authenticated requests for user with protocol and passphrase.
+func v3Agent(t *testing.T, protocol AuthProtocol, passphrase string) *stubAgent {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("v3 agent")))
+
+	key, err := LocalizeKey(protocol, passphrase, testEngineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent.authenticate(protocol, key)
+	agent.handle(func(m *Message) []*Message {
+		if len(m.Security.AuthoritativeEngineID) == 0 {
+			return []*Message{usmReport(m, 4, 5, 1000)}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	return agent
+}
+
+func TestClientDiscoverEngine(t *testing.T) {
+	agent := v3Agent(t, SHA, "authpassphrase")
+
+	c := &Client{Addr: agent.addr(), Version: Version3}
+
+	engineID, boots, engineTime, err := c.DiscoverEngine(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(engineID, testEngineID) || boots != 5 || engineTime != 1000 {
+		t.Errorf("unexpected engine %x, boots %d, time %d", engineID, boots, engineTime)
+	}
+}
+
+func TestClientV3Get(t *testing.T) {
+	for _, protocol := range []AuthProtocol{MD5, SHA} {
+		agent := v3Agent(t, protocol, "authpassphrase")
+
+		c := &Client{
+			Addr:    agent.addr(),
+			Version: Version3,
+			User:    &USMUser{Name: "operator", AuthProtocol: protocol, AuthPassphrase: "authpassphrase"},
+		}
+
+		varbinds, err := c.Get(sysDescr)
+		if err != nil {
+			t.Fatalf("%v: %v", protocol, err)
+		}
+
+		if len(varbinds) != 1 || varbinds[0].Value() != String("v3 agent") {
+			t.Errorf("%v: unexpected varbinds %v", protocol, varbinds)
+		}
+	}
+}
+
+func TestClientV3WrongPassphrase(t *testing.T) {
+	agent := v3Agent(t, SHA, "authpassphrase")
+
+	c := &Client{
+		Addr:    agent.addr(),
+		Version: Version3,
+		Timeout: 50 * time.Millisecond,
+		User:    &USMUser{Name: "operator", AuthProtocol: SHA, AuthPassphrase: "wrongpassphrase"},
+	}
+
+	if _, err := c.Get(sysDescr); err != ErrTimeout {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestClientV3TimeWindow(t *testing.T) {
+	agent := v3Agent(t, SHA, "authpassphrase")
+
+	// Like RFC 3414 discovery, report no boots or time at first
+	agent.handle(func(m *Message) []*Message {
+		switch {
+		case len(m.Security.AuthoritativeEngineID) == 0:
+			return []*Message{usmReport(m, 4, 0, 0)}
+		case m.Security.AuthoritativeEngineBoots != 5:
+			return []*Message{usmReport(m, 2, 5, 1000)}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{
+		Addr:    agent.addr(),
+		Version: Version3,
+		User:    &USMUser{Name: "operator", AuthProtocol: SHA, AuthPassphrase: "authpassphrase"},
+	}
+
+	if _, err := c.Get(sysDescr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReportError(t *testing.T) {
+	b, err := usmReport(&Message{}, 3, 0, 0).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _, err := decodeMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reportError(m.PDU.(Report)); err != ErrUnknownUserName {
+		t.Errorf("expected ErrUnknownUserName, got %v", err)
+	}
+}
+
+func TestReportErr(t *testing.T) {
+	report := func(oid ObjectIdentifier) Report {
+		b, err := Report{
+			Int(1), Int(0), Int(0),
+			Sequence{NewVarbind(oid, Counter(7))},
+		}.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return decoded.(Report)
+	}
+
+	for _, test := range []struct {
+		oid ObjectIdentifier
+		err error
+	}{
+		{usmStats.Append(1, 0), ErrUnsupportedSecurityLevel},
+		{usmStats.Append(2, 0), ErrNotInTimeWindow},
+		{usmStats.Append(3, 0), ErrUnknownUserName},
+		{usmStats.Append(4, 0), ErrUnknownEngineID},
+		{usmStats.Append(5, 0), ErrWrongDigest},
+		{usmStats.Append(6, 0), ErrDecryption},
+		{MustParseOID(".1.3.6.1.6.3.11.2.1.1.0"), ErrUnknownSecurityModel},
+		{MustParseOID(".1.3.6.1.6.3.11.2.1.3.0"), ErrUnknownPDUHandler},
+		{MustParseOID(".1.3.6.1.6.3.12.1.5.0"), ErrUnknownContext},
+	} {
+		if err := report(test.oid).Err(); err != test.err {
+			t.Errorf("%v: expected %v, got %v", test.oid, test.err, err)
+		}
+	}
+
+	unknown := usmStats.Append(99, 0)
+	if err := report(unknown).Err(); err == nil || !strings.Contains(err.Error(), unknown.String()) {
+		t.Errorf("expected an error naming the unknown counter, got %v", err)
+	}
+}
+
+func TestClientV3TimeWindowResync(t *testing.T) {
+	agent := v3Agent(t, SHA, "authpassphrase")
+
+	var (
+		mu       sync.Mutex
+		rejected int
+		times    []int
+	)
+
+	// The agent rejects requests until they carry its boots, and
+	// records the engine time of each accepted request
+	agent.handle(func(m *Message) []*Message {
+		if len(m.Security.AuthoritativeEngineID) == 0 {
+			return []*Message{usmReport(m, 4, 0, 0)}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if m.Security.AuthoritativeEngineBoots != 5 {
+			rejected++
+			return []*Message{usmReport(m, 2, 5, 1000)}
+		}
+
+		times = append(times, m.Security.AuthoritativeEngineTime)
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{
+		Addr:    agent.addr(),
+		Version: Version3,
+		Timeout: time.Second,
+		User:    &USMUser{Name: "operator", AuthProtocol: SHA, AuthPassphrase: "authpassphrase"},
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(sysDescr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Only the first request is rejected, and the synchronized
+	// time is kept and advanced for the later ones
+	if rejected != 1 {
+		t.Errorf("expected 1 rejected request, got %d", rejected)
+	}
+
+	if len(times) != 3 || times[0] < 1000 || times[1] < times[0] || times[2] < times[1] {
+		t.Errorf("expected engine times from 1000 on, got %v", times)
+	}
+}
+
+func TestClientV3TimeWindowRetriesOnce(t *testing.T) {
+	agent := v3Agent(t, SHA, "authpassphrase")
+
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+
+	agent.handle(func(m *Message) []*Message {
+		if len(m.Security.AuthoritativeEngineID) == 0 {
+			return []*Message{usmReport(m, 4, 0, 0)}
+		}
+
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		return []*Message{usmReport(m, 2, 5, 1000)}
+	})
+
+	c := &Client{
+		Addr:    agent.addr(),
+		Version: Version3,
+		Timeout: time.Second,
+		User:    &USMUser{Name: "operator", AuthProtocol: SHA, AuthPassphrase: "authpassphrase"},
+	}
+
+	if _, err := c.Get(sysDescr); err != ErrNotInTimeWindow {
+		t.Errorf("expected ErrNotInTimeWindow, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if requests != 2 {
+		t.Errorf("expected the request to be retried once, got %d requests", requests)
+	}
+}
@@ -0,0 +1,157 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Conn is a connection to the agent of a Client that reuses a single
+// socket for its requests, avoiding the cost of opening one for each.
+// Requests may be made concurrently. The Client must not be modified
+// while a Conn is in use.
+type Conn struct {
+	client *Client
+	mx     *mux
+}
+
+// Dial opens a Conn to the agent of c. It must be closed
+// once it is no longer used.
+func (c *Client) Dial() (*Conn, error) {
+	addr := c.address()
+	if c.Transport == nil {
+		if err := checkPort(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	mx, err := c.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{client: c, mx: mx}, nil
+}
+
+// DialAddr is like Dial but opens a Conn to the agent at addr, with
+// c.Port if it doesn't include a port, rather than to c.Addr. It lets
+// one Client poll agents on different ports, each with its own SNMPv3
+// engine and RateLimit.
+func (c *Client) DialAddr(addr string) (*Conn, error) {
+	other := c.clone()
+	other.Addr = addr
+
+	return other.Dial()
+}
+
+// dial returns a mux for the messages c sends to addr,
+// or to c.Transport if it is set.
+func (c *Client) dial(addr string) (*mux, error) {
+	if c.Transport != nil {
+		return newTransportMux(c.Transport, c.decodeOptions(), c.Trace), nil
+	}
+
+	return dialMux(c.network(), c.LocalAddr, addr, c.MaxMessageSize, c.decodeOptions(), c.Trace)
+}
+
+// Close closes the socket of conn. Requests in flight fail.
+func (conn *Conn) Close() error {
+	return conn.mx.close()
+}
+
+// Get is like Client.Get.
+func (conn *Conn) Get(oids ...ObjectIdentifier) ([]Varbind, error) {
+	return conn.GetContext(context.Background(), oids...)
+}
+
+// GetContext is like Get but aborts when ctx is done.
+func (conn *Conn) GetContext(ctx context.Context, oids ...ObjectIdentifier) ([]Varbind, error) {
+	return conn.client.get(ctx, conn.mx, oids)
+}
+
+// GetNext is like Client.GetNext.
+func (conn *Conn) GetNext(oid ObjectIdentifier) (Varbind, error) {
+	return conn.GetNextContext(context.Background(), oid)
+}
+
+// GetNextContext is like GetNext but aborts when ctx is done.
+func (conn *Conn) GetNextContext(ctx context.Context, oid ObjectIdentifier) (Varbind, error) {
+	c := conn.client
+
+	res, err := c.getNext(ctx, conn.mx, oid)
+	if err != nil {
+		// SNMPv1 agents signal the end of the MIB with noSuchName
+		if errors.Is(err, NoSuchName) && c.Version == Version1 {
+			return Varbind{}, ErrEndOfMIBView
+		}
+
+		return Varbind{}, err
+	}
+
+	if len(res.varbinds) != 1 {
+		return Varbind{}, ErrDecodingType
+	}
+
+	v := res.varbinds[0]
+	if v.Value() == EndOfMIBView {
+		return Varbind{}, ErrEndOfMIBView
+	}
+
+	return v, nil
+}
+
+// Walk is like Client.Walk.
+func (conn *Conn) Walk(root ObjectIdentifier, fn func(Varbind) error) error {
+	return conn.WalkContext(context.Background(), root, fn)
+}
+
+// WalkContext is like Walk but aborts when ctx is done.
+func (conn *Conn) WalkContext(ctx context.Context, root ObjectIdentifier, fn func(Varbind) error) error {
+	return conn.client.walk(ctx, conn.mx, root, fn)
+}
+
+// WalkMany walks the subtrees rooted at roots concurrently over conn,
+// calling fn with each variable binding and the root of its subtree.
+// Calls to fn are serialized, and each subtree is walked in order. If
+// fn returns ErrStopWalk, only the walk of that root stops. Any other
+// error stops every walk and is returned.
+func (conn *Conn) WalkMany(roots []ObjectIdentifier, fn func(root ObjectIdentifier, v Varbind) error) error {
+	return conn.WalkManyContext(context.Background(), roots, fn)
+}
+
+// WalkManyContext is like WalkMany but aborts every walk when ctx is done.
+func (conn *Conn) WalkManyContext(ctx context.Context, roots []ObjectIdentifier, fn func(root ObjectIdentifier, v Varbind) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	errs := make(chan error, len(roots))
+
+	for _, root := range roots {
+		root := root
+
+		go func() {
+			errs <- conn.WalkContext(ctx, root, func(v Varbind) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				// Another walk may have failed while this one waited
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				return fn(root, v)
+			})
+		}()
+	}
+
+	var first error
+	for range roots {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,132 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConnReuse(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	conn, err := c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []String{"lo", "eth0", "eth1"}
+
+	for i := 0; i < 300; i++ {
+		index := uint32(i%len(names) + 1)
+
+		varbinds, err := conn.Get(ifDescr.Child(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(varbinds) != 1 || varbinds[0].Value() != names[index-1] {
+			t.Fatalf("request %d: unexpected varbinds %v", i, varbinds)
+		}
+	}
+
+	v, err := conn.GetNext(ifDescr)
+	if err != nil || v.Value() != String("lo") {
+		t.Errorf("unexpected GetNext result %v, %v", v, err)
+	}
+
+	walked := 0
+	if err := conn.Walk(ifDescr, func(Varbind) error { walked++; return nil }); err != nil || walked != 3 {
+		t.Errorf("expected to walk 3 varbinds, got %d (%v)", walked, err)
+	}
+
+	if n := agent.sourceCount(); n != 1 {
+		t.Errorf("expected every request to use one socket, got %d sources", n)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Get(sysDescr); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
+
+func TestConnWalkMany(t *testing.T) {
+	ifType := MustParseOID(".1.3.6.1.2.1.2.2.1.3")
+	system := MustParseOID(".1.3.6.1.2.1.1")
+	roots := []ObjectIdentifier{ifDescr, ifType, system}
+
+	agent := ifTableAgent(t)
+
+	// Hold the first request of each walk until all of them have
+	// arrived, which only happens if the walks run concurrently
+	var held []*Message
+	agent.handle(func(m *Message) []*Message {
+		req, ok := m.PDU.(GetNextRequest)
+		if !ok {
+			return nil
+		}
+
+		for _, root := range roots {
+			if req.varbinds[0].OID.Equal(root) {
+				held = append(held, agent.respond(m))
+				if len(held) < len(roots) {
+					return nil
+				}
+
+				return held
+			}
+		}
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	conn, err := c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	walked := map[string][]string{}
+	err = conn.WalkMany(roots, func(root ObjectIdentifier, v Varbind) error {
+		if !v.OID.HasPrefix(root) {
+			t.Errorf("%v attributed to %v", v.OID, root)
+		}
+
+		walked[root.String()] = append(walked[root.String()], v.OID.String())
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for root, expected := range map[string]int{ifDescr.String(): 3, ifType.String(): 2, system.String(): 1} {
+		if len(walked[root]) != expected {
+			t.Errorf("%s: expected %d varbinds, got %v", root, expected, walked[root])
+		}
+	}
+
+	// The deadline of ctx is shared by every walk
+	agent.handle(func(*Message) []*Message { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = conn.WalkManyContext(ctx, roots, func(ObjectIdentifier, Varbind) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the walks to stop at the deadline, took %v", elapsed)
+	}
+}
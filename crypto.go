@@ -6,13 +6,15 @@ import (
 	"crypto/cipher"
 	"crypto/sha1"
 	"encoding/binary"
+	"hash"
 )
 
 const oneMegabyte = 1024 * 1024
 
-// passphraseToKey generates a SHA1 hashed key using the given passphrase.
-func passphraseToKey(passphrase, engineId []byte) []byte {
-	h := sha1.New()
+// passphraseToKey generates a key localized to engineId from the given
+// passphrase using the algorithm in RFC 3414 section A.2.
+func passphraseToKey(newHash func() hash.Hash, passphrase, engineId []byte) []byte {
+	h := newHash()
 
 	passphraseLength := len(passphrase)
 
@@ -1,28 +1,32 @@
 package snmp
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 )
 
 var (
-	ErrDecodingType = errors.New("snmp: error decoding type")
-	ErrUnknownType  = errors.New("snmp: unknown type")
+	ErrDecodingType  = errors.New("snmp: error decoding type")
+	ErrUnknownType   = errors.New("snmp: unknown type")
+	ErrTruncated     = errors.New("snmp: truncated data")
+	ErrInvalidLength = errors.New("snmp: invalid length")
 )
 
-// decode decodes an SNMP DataType from r.
-// It returns the SNMP data type, the number of bytes read, and an error.
-func decode(r io.Reader) (DataType, int, error) {
+// decodeHeader decodes a type and length header from r.
+// It returns the type, the length, the number of bytes read, and an error.
+func decodeHeader(r io.Reader) (byte, int, int, error) {
 	bytesRead := 0
 
 	typeLength := []byte{0, 0}
-	n, err := r.Read(typeLength)
+	n, err := io.ReadFull(r, typeLength)
 
 	bytesRead += n
 
 	if err != nil {
-		return nil, bytesRead, err
+		return 0, 0, bytesRead, err
 	}
 
 	t := typeLength[0]
@@ -31,21 +35,170 @@ func decode(r io.Reader) (DataType, int, error) {
 	// Decode the length
 	if length > 0x7F {
 		lengthNumBytes := 0x80 ^ byte(length)
+
+		// SNMP doesn't allow the indefinite form, and
+		// no message needs more than four length bytes.
+		if lengthNumBytes == 0 {
+			return 0, 0, bytesRead, fmt.Errorf("%w: indefinite form", ErrInvalidLength)
+		}
+
+		if lengthNumBytes > 4 {
+			return 0, 0, bytesRead, fmt.Errorf("%w: %d length bytes", ErrInvalidLength, lengthNumBytes)
+		}
+
 		length = 0
 		for lengthNumBytes > 0 {
 			length = length << 8
 			var b [1]byte
-			n, err := r.Read(b[:])
+			n, err := io.ReadFull(r, b[:])
 
 			bytesRead += n
 
 			if err != nil {
-				return nil, bytesRead, err
+				return 0, 0, bytesRead, err
 			}
 
 			length |= int(b[0])
 			lengthNumBytes--
 		}
+
+		// Four length bytes overflow an int on 32-bit platforms
+		if length < 0 {
+			return 0, 0, bytesRead, fmt.Errorf("%w: overflows int", ErrInvalidLength)
+		}
+	}
+
+	return t, length, bytesRead, nil
+}
+
+// readContent reads the length bytes of content of a value from r.
+// Rather than trusting length to allocate a buffer, it allocates no
+// more than r holds, so a crafted length can't exhaust memory.
+// It returns the content, the number of bytes read, and an error.
+func readContent(length int, r io.Reader) ([]byte, int, error) {
+	if length < 0 {
+		return nil, 0, ErrInvalidLength
+	}
+
+	// Readers that know how many bytes are left, like the
+	// bytes.Reader of a received packet, can be checked up front
+	if sized, ok := r.(interface{ Len() int }); ok {
+		if left := sized.Len(); length > left {
+			b := make([]byte, left)
+			n, _ := io.ReadFull(r, b)
+			return b[:n], n, io.ErrUnexpectedEOF
+		}
+
+		b := make([]byte, length)
+		n, err := io.ReadFull(r, b)
+		return b[:n], n, err
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := io.CopyN(buf, r, int64(length))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return buf.Bytes(), int(n), err
+}
+
+// errOverrun returns the error for the elements of a constructed value
+// that take up more than its length.
+func errOverrun(length, read int) error {
+	return fmt.Errorf("%w: %d bytes of elements overrun a length of %d", ErrInvalidLength, read, length)
+}
+
+// DecodeTLVHeader decodes a BER tag and length header from r, in
+// either the short or the long form.
+// It returns the tag, the length, the number of bytes read, and an error.
+func DecodeTLVHeader(r io.Reader) (tag byte, length, bytesRead int, err error) {
+	return decodeHeader(r)
+}
+
+// WalkTLV calls fn with the tag and content of each TLV in data, such
+// as the content of a SEQUENCE, in order, without interpreting them.
+// Constructed values can be walked in turn by passing their content to
+// WalkTLV. The content passed to fn shares data's backing array. It
+// stops at the first error from fn and returns it, and returns an error
+// wrapping ErrTruncated if a TLV runs past the end of data.
+func WalkTLV(data []byte, fn func(tag byte, content []byte) error) error {
+	for offset := 0; offset < len(data); {
+		tag, content, n, err := nextTLV(data[offset:])
+		if err != nil {
+			return fmt.Errorf("%w at offset %d", err, offset)
+		}
+
+		if err := fn(tag, content); err != nil {
+			return err
+		}
+
+		offset += n
+	}
+
+	return nil
+}
+
+// nextTLV returns the tag and content of the TLV data begins with, the
+// number of bytes it takes up, and an error wrapping ErrTruncated if it
+// runs past the end of data.
+func nextTLV(data []byte) (byte, []byte, int, error) {
+	tag, length, n, err := splitHeader(data)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	if length > len(data)-n {
+		return 0, nil, 0, fmt.Errorf("%w: expected %d bytes, %d available", ErrTruncated, length, len(data)-n)
+	}
+
+	return tag, data[n : n+length], n + length, nil
+}
+
+// splitHeader parses the tag and length header data begins with like
+// decodeHeader, but without a Reader to allocate. It returns the tag,
+// the length, the size of the header, and an error.
+func splitHeader(data []byte) (byte, int, int, error) {
+	if len(data) < 2 {
+		return 0, 0, 0, fmt.Errorf("%w: incomplete header", ErrTruncated)
+	}
+
+	tag, length, n := data[0], int(data[1]), 2
+
+	if length > 0x7f {
+		lengthNumBytes := length & 0x7f
+
+		switch {
+		case lengthNumBytes == 0:
+			return 0, 0, 0, fmt.Errorf("%w: indefinite form", ErrInvalidLength)
+		case lengthNumBytes > 4:
+			return 0, 0, 0, fmt.Errorf("%w: %d length bytes", ErrInvalidLength, lengthNumBytes)
+		case len(data) < n+lengthNumBytes:
+			return 0, 0, 0, fmt.Errorf("%w: incomplete header", ErrTruncated)
+		}
+
+		length = 0
+		for _, b := range data[n : n+lengthNumBytes] {
+			length = length<<8 | int(b)
+		}
+
+		n += lengthNumBytes
+
+		// Four length bytes overflow an int on 32-bit platforms
+		if length < 0 {
+			return 0, 0, 0, fmt.Errorf("%w: overflows int", ErrInvalidLength)
+		}
+	}
+
+	return tag, length, n, nil
+}
+
+// decode decodes an SNMP DataType from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decode(r io.Reader) (DataType, int, error) {
+	t, length, bytesRead, err := decodeHeader(r)
+	if err != nil {
+		return nil, bytesRead, err
 	}
 
 	// Decode types
@@ -54,40 +207,74 @@ func decode(r io.Reader) (DataType, int, error) {
 		seq, n, err := decodeSequence(length, r)
 		return seq, bytesRead + n, err
 
-	case TypeInteger, TypeCounter, TypeCounter64, TypeGauge, TypeTimeTicks:
+	case TypeInteger:
 		i, n, err := decodeInteger(length, r)
 		return i, bytesRead + n, err
 
-	case TypeString:
-		str := make([]byte, length)
-		n, _ := r.Read(str)
-		bytesRead += n
+	case TypeCounter:
+		u, n, err := decodeUnsigned(length, r, 32)
+		return Counter(u), bytesRead + n, err
 
-		if err != nil {
-			return nil, bytesRead, err
-		}
+	case TypeCounter64:
+		u, n, err := decodeUnsigned(length, r, 64)
+		return Counter64(u), bytesRead + n, err
 
-		return String(str), bytesRead, nil
+	case TypeGauge:
+		u, n, err := decodeUnsigned(length, r, 32)
+		return Gauge(u), bytesRead + n, err
 
-	case TypeIpAddress:
-		ip := make(IpAddress, length)
-		n, _ := r.Read(ip)
-		bytesRead += n
+	case TypeTimeTicks:
+		u, n, err := decodeUnsigned(length, r, 32)
+		return TimeTicks(u), bytesRead + n, err
 
-		if err != nil {
-			return nil, bytesRead, err
-		}
+	case TypeString:
+		str, n, err := decodeString(length, r)
+		return str, bytesRead + n, err
 
-		return ip, bytesRead, nil
+	case TypeIpAddress:
+		ip, n, err := decodeIpAddress(length, r)
+		return ip, bytesRead + n, err
+
+	case TypeOpaque:
+		o, n, err := decodeOpaque(length, r)
+		return o, bytesRead + n, err
 
 	case TypeOID:
 		oid, n, err := decodeOID(length, r)
 		return oid, bytesRead + n, err
 
+	case TypeGetRequest:
+		getRequest, n, err := decodeGetRequest(length, r)
+		return getRequest, n + bytesRead, err
+
+	case TypeGetNextRequest:
+		getNextRequest, n, err := decodeGetNextRequest(length, r)
+		return getNextRequest, n + bytesRead, err
+
 	case TypeGetResponse:
 		getResponse, n, err := decodeGetResponse(length, r)
 		return getResponse, n + bytesRead, err
 
+	case TypeSetRequest:
+		setRequest, n, err := decodeSetRequest(length, r)
+		return setRequest, n + bytesRead, err
+
+	case TypeTrap:
+		trap, n, err := decodeTrapV1(length, r)
+		return trap, n + bytesRead, err
+
+	case TypeGetBulkRequest:
+		getBulkRequest, n, err := decodeGetBulkRequest(length, r)
+		return getBulkRequest, n + bytesRead, err
+
+	case TypeInformRequest:
+		inform, n, err := decodeInformRequest(length, r)
+		return inform, n + bytesRead, err
+
+	case TypeTrapV2:
+		trap, n, err := decodeTrapV2(length, r)
+		return trap, n + bytesRead, err
+
 	case TypeReport:
 		res := Report{}
 		seqBytes := 0
@@ -105,12 +292,32 @@ func decode(r io.Reader) (DataType, int, error) {
 			}
 		}
 
+		if seqBytes > length {
+			return nil, bytesRead, errOverrun(length, seqBytes)
+		}
+
 		return res, bytesRead, nil
 
-	case TypeNull, TypeNoSuchObject, TypeNoSuchInstance, TypeEndOfMIBView:
-		return tag(t), bytesRead, nil
+	case TypeNull:
+		null, err := decodeNull(length)
+		return null, bytesRead, err
+
+	case TypeNoSuchObject, TypeNoSuchInstance, TypeEndOfMIBView:
+		exception, err := decodeException(t, length)
+		return exception, bytesRead, err
 
 	default:
+		// Values, such as those of vendor-specific application
+		// types, are primitive, unlike PDUs
+		if t&0x20 == 0 {
+			content, n, err := readContent(length, r)
+			if err != nil {
+				return nil, bytesRead + n, err
+			}
+
+			return UnknownValue{Tag: t, Bytes: content}, bytesRead + n, nil
+		}
+
 		log.Printf("0x%X", t)
 		return nil, bytesRead, ErrUnknownType
 	}
@@ -0,0 +1,171 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var errBadDisplayHint = errors.New("snmp: invalid DISPLAY-HINT")
+
+// maxHintPlaces is the most implied decimal places of an INTEGER
+// DISPLAY-HINT, the number of digits of the largest int64.
+const maxHintPlaces = 19
+
+// octetHintSpec is one octet-format specification of an
+// OCTET STRING DISPLAY-HINT, such as "1x:" or "*1d.".
+type octetHintSpec struct {
+	repeat     bool
+	length     int
+	format     byte
+	separator  byte
+	terminator byte
+}
+
+// parseOctetHint parses an OCTET STRING DISPLAY-HINT
+// as described in RFC 2579 section 3.1.
+func parseOctetHint(hint string) ([]octetHintSpec, error) {
+	var specs []octetHintSpec
+
+	for i := 0; i < len(hint); {
+		var spec octetHintSpec
+
+		if hint[i] == '*' {
+			spec.repeat = true
+			i++
+		}
+
+		j := i
+		for j < len(hint) && isDigit(hint[j]) {
+			j++
+		}
+
+		length, err := strconv.Atoi(hint[i:j])
+		if err != nil || length == 0 || j == len(hint) || !strings.ContainsRune("xdoat", rune(hint[j])) {
+			return nil, errBadDisplayHint
+		}
+
+		spec.length, spec.format = length, hint[j]
+		i = j + 1
+
+		if i < len(hint) && !isDigit(hint[i]) && hint[i] != '*' {
+			spec.separator = hint[i]
+			i++
+
+			if spec.repeat && i < len(hint) && !isDigit(hint[i]) && hint[i] != '*' {
+				spec.terminator = hint[i]
+				i++
+			}
+		}
+
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return nil, errBadDisplayHint
+	}
+
+	return specs, nil
+}
+
+// formatOctetHint formats b using an OCTET STRING DISPLAY-HINT.
+// The last specification is reused until b is exhausted.
+func formatOctetHint(hint string, b []byte) (string, error) {
+	specs, err := parseOctetHint(hint)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &strings.Builder{}
+
+	for i := 0; len(b) > 0; i++ {
+		spec := specs[len(specs)-1]
+		if i < len(specs) {
+			spec = specs[i]
+		}
+
+		count := 1
+		if spec.repeat {
+			count, b = int(b[0]), b[1:]
+		}
+
+		for k := 0; k < count && len(b) > 0; k++ {
+			n := spec.length
+			if n > len(b) {
+				n = len(b)
+			}
+
+			formatOctets(buf, spec.format, b[:n])
+			b = b[n:]
+
+			if len(b) == 0 {
+				break
+			}
+
+			if k == count-1 && spec.terminator != 0 {
+				buf.WriteByte(spec.terminator)
+			} else if spec.separator != 0 {
+				buf.WriteByte(spec.separator)
+			}
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// formatOctets writes b in the format of an octet-format specification.
+// Numeric formats treat b as an unsigned integer in network byte order.
+func formatOctets(buf *strings.Builder, format byte, b []byte) {
+	if format == 'a' || format == 't' {
+		buf.Write(b)
+		return
+	}
+
+	switch format {
+	case 'x':
+		fmt.Fprintf(buf, "%x", b)
+	case 'd':
+		buf.WriteString(new(big.Int).SetBytes(b).Text(10))
+	case 'o':
+		buf.WriteString(new(big.Int).SetBytes(b).Text(8))
+	}
+}
+
+// formatIntegerHint formats i using an INTEGER DISPLAY-HINT:
+// "x", "o", "b", "d", or "d-n" for n implied decimal places.
+func formatIntegerHint(hint string, i int64) (string, error) {
+	switch {
+	case hint == "x":
+		return strconv.FormatInt(i, 16), nil
+	case hint == "o":
+		return strconv.FormatInt(i, 8), nil
+	case hint == "b":
+		return strconv.FormatInt(i, 2), nil
+	case hint == "d":
+		return strconv.FormatInt(i, 10), nil
+	case !strings.HasPrefix(hint, "d-"):
+		return "", errBadDisplayHint
+	}
+
+	places, err := strconv.Atoi(hint[2:])
+	if err != nil || places < 0 || places > maxHintPlaces {
+		return "", errBadDisplayHint
+	}
+
+	sign, digits := "", strconv.FormatInt(i, 10)
+	if i < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	if places == 0 {
+		return sign + digits, nil
+	}
+
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:], nil
+}
@@ -0,0 +1,220 @@
+package snmp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Dump writes a human-readable tree of m to w: its version and
+// community or SNMPv3 header, the type of its PDU, the PDU fields, and
+// each variable binding as formatted by DumpVarbind. If mib is not nil,
+// OIDs are shown by name.
+func Dump(w io.Writer, m *Message, mib *MIB) error {
+	bw := bufio.NewWriter(w)
+
+	switch m.Version {
+	case Version1:
+		fmt.Fprintln(bw, "SNMPv1 message")
+	case Version2c:
+		fmt.Fprintln(bw, "SNMPv2c message")
+	case Version3:
+		fmt.Fprintln(bw, "SNMPv3 message")
+	default:
+		fmt.Fprintf(bw, "SNMP message of version %d\n", m.Version)
+	}
+
+	if m.Version != Version3 {
+		fmt.Fprintf(bw, "  community: %s\n", String(m.Community))
+	} else {
+		fmt.Fprintf(bw, "  msgID: %d\n", m.MessageID)
+		fmt.Fprintf(bw, "  msgMaxSize: %d\n", m.MaxSize)
+		fmt.Fprintf(bw, "  msgFlags: %s\n", dumpFlags(m.Flags))
+		fmt.Fprintf(bw, "  engine: %x boots %d time %d\n", m.Security.AuthoritativeEngineID,
+			m.Security.AuthoritativeEngineBoots, m.Security.AuthoritativeEngineTime)
+		fmt.Fprintf(bw, "  user: %s\n", String(m.Security.UserName))
+		fmt.Fprintf(bw, "  context: %x %s\n", m.ContextEngineID, String(m.ContextName))
+	}
+
+	if m.PDU == nil && m.EncryptedPDU != nil {
+		fmt.Fprintf(bw, "  encrypted PDU of %d bytes\n", len(m.EncryptedPDU))
+	} else {
+		dumpPDU(bw, "  ", m.PDU, mib)
+	}
+
+	return bw.Flush()
+}
+
+// DumpPDU writes a human-readable tree of pdu, one of the PDU types
+// such as GetResponse, to w, as Dump does for the PDU of a Message.
+func DumpPDU(w io.Writer, pdu DataType, mib *MIB) error {
+	bw := bufio.NewWriter(w)
+	dumpPDU(bw, "", pdu, mib)
+
+	return bw.Flush()
+}
+
+// DumpVarbind formats v as "OID = TYPE: value", such as
+// "SNMPv2-MIB::sysUpTime.0 = Timeticks: 12345". Exceptions are formatted
+// as "OID = noSuchObject". If mib is not nil, OIDs are shown by name and
+// values are formatted with MIB.Render.
+func DumpVarbind(v Varbind, mib *MIB) string {
+	name := dumpOID(v.OID, mib)
+
+	if v.IsException() || v.value == Null {
+		return fmt.Sprintf("%s = %v", name, v.value)
+	}
+
+	var value string
+
+	switch val := v.value.(type) {
+	case ObjectIdentifier:
+		value = dumpOID(val, mib)
+	case Opaque:
+		value = fmt.Sprintf("%x", []byte(val))
+	default:
+		if mib != nil {
+			value = mib.Render(v)
+		} else {
+			value = fmt.Sprint(val)
+		}
+	}
+
+	return fmt.Sprintf("%s = %s: %s", name, typeName(v.value), value)
+}
+
+// dumpPDU writes the tree of pdu to w, indenting each line.
+func dumpPDU(w io.Writer, indent string, pdu DataType, mib *MIB) {
+	var (
+		name string
+		p    PDU
+	)
+
+	switch pdu := pdu.(type) {
+	case GetRequest:
+		name, p = "GetRequest", pdu.PDU
+	case GetNextRequest:
+		name, p = "GetNextRequest", pdu.PDU
+	case GetResponse:
+		name, p = "GetResponse", pdu.PDU
+	case SetRequest:
+		name, p = "SetRequest", pdu.PDU
+	case InformRequest:
+		name, p = "InformRequest", pdu.PDU
+	case TrapV2:
+		name, p = "SNMPv2-Trap", pdu.PDU
+	case GetBulkRequest:
+		fmt.Fprintf(w, "%sGetBulkRequest\n", indent)
+		fmt.Fprintf(w, "%s  request-id: %d\n", indent, pdu.requestID)
+		fmt.Fprintf(w, "%s  non-repeaters: %d\n", indent, pdu.NonRepeaters())
+		fmt.Fprintf(w, "%s  max-repetitions: %d\n", indent, pdu.MaxRepetitions())
+		dumpVarbinds(w, indent, pdu.varbinds, mib)
+		return
+	case TrapV1:
+		fmt.Fprintf(w, "%sTrap\n", indent)
+		fmt.Fprintf(w, "%s  enterprise: %s\n", indent, dumpOID(pdu.Enterprise, mib))
+		fmt.Fprintf(w, "%s  agent-addr: %v\n", indent, pdu.AgentAddr)
+		fmt.Fprintf(w, "%s  generic-trap: %d\n", indent, pdu.GenericTrap)
+		fmt.Fprintf(w, "%s  specific-trap: %d\n", indent, pdu.SpecificTrap)
+		fmt.Fprintf(w, "%s  time-stamp: %d\n", indent, pdu.Timestamp)
+		dumpVarbinds(w, indent, pdu.Varbinds, mib)
+		return
+	case Report:
+		var err error
+		if p, err = pdu.pdu(); err != nil {
+			fmt.Fprintf(w, "%sReport: %v\n", indent, err)
+			return
+		}
+
+		name = "Report"
+	case nil:
+		fmt.Fprintf(w, "%sno PDU\n", indent)
+		return
+	default:
+		fmt.Fprintf(w, "%s%T\n", indent, pdu)
+		return
+	}
+
+	fmt.Fprintf(w, "%s%s\n", indent, name)
+	fmt.Fprintf(w, "%s  request-id: %d\n", indent, p.requestID)
+	fmt.Fprintf(w, "%s  error-status: %s (%d)\n", indent, p.ErrorStatus().String(), p.err)
+	fmt.Fprintf(w, "%s  error-index: %d\n", indent, p.errIndex)
+	dumpVarbinds(w, indent, p.varbinds, mib)
+}
+
+// dumpVarbinds writes the variable bindings of a PDU to w.
+func dumpVarbinds(w io.Writer, indent string, varbinds []Varbind, mib *MIB) {
+	fmt.Fprintf(w, "%s  variable-bindings: %d\n", indent, len(varbinds))
+
+	for _, v := range varbinds {
+		fmt.Fprintf(w, "%s    %s\n", indent, DumpVarbind(v, mib))
+	}
+}
+
+// dumpOID returns the name of oid if mib isn't nil, or its numeric form.
+func dumpOID(oid ObjectIdentifier, mib *MIB) string {
+	if mib == nil {
+		return oid.String()
+	}
+
+	return mib.Name(oid)
+}
+
+// dumpFlags returns the names of the msgFlags set in flags.
+func dumpFlags(flags byte) string {
+	s := ""
+
+	for _, flag := range []struct {
+		bit  byte
+		name string
+	}{
+		{FlagAuth, "auth"},
+		{FlagPriv, "priv"},
+		{FlagReportable, "reportable"},
+	} {
+		if flags&flag.bit == 0 {
+			continue
+		}
+
+		if s != "" {
+			s += "|"
+		}
+
+		s += flag.name
+	}
+
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
+
+// typeName returns the SMI name of the type of value, as net-snmp
+// prints it.
+func typeName(value DataType) string {
+	switch value.(type) {
+	case Int:
+		return "INTEGER"
+	case String:
+		return "STRING"
+	case ObjectIdentifier:
+		return "OID"
+	case IpAddress:
+		return "IpAddress"
+	case Counter:
+		return "Counter32"
+	case Gauge:
+		return "Gauge32"
+	case TimeTicks:
+		return "Timeticks"
+	case Opaque:
+		return "Opaque"
+	case Counter64:
+		return "Counter64"
+	case UnknownValue:
+		return fmt.Sprintf("Unknown(0x%02X)", value.(UnknownValue).Tag)
+	}
+
+	return fmt.Sprintf("%T", value)
+}
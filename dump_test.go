@@ -0,0 +1,100 @@
+package snmp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	sysObjectID := MustParseOID(".1.3.6.1.2.1.1.2.0")
+	ifOperStatus := MustParseOID(".1.3.6.1.2.1.2.2.1.8.2")
+	ifInOctets := MustParseOID(".1.3.6.1.2.1.2.2.1.10.2")
+
+	b, err := Message{
+		Version:   Version2c,
+		Community: "public",
+		PDU: GetResponse{PDU: newPDU(1234, 0, 0, []Varbind{
+			NewVarbind(sysDescr, String("test agent")),
+			NewVarbind(sysObjectID, MustParseOID(".1.3.6.1.4.1.9.1.1")),
+			NewVarbind(sysUpTime, TimeTicks(12345)),
+			NewVarbind(ifOperStatus, Int(1)),
+			NewVarbind(ifInOctets, Counter(5678)),
+			NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.10.3"), NoSuchInstance),
+		})},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &strings.Builder{}
+	if err := Dump(buf, m, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `SNMPv2c message
+  community: public
+  GetResponse
+    request-id: 1234
+    error-status: noError (0)
+    error-index: 0
+    variable-bindings: 6
+      .1.3.6.1.2.1.1.1.0 = STRING: test agent
+      .1.3.6.1.2.1.1.2.0 = OID: .1.3.6.1.4.1.9.1.1
+      .1.3.6.1.2.1.1.3.0 = Timeticks: 12345
+      .1.3.6.1.2.1.2.2.1.8.2 = INTEGER: 1
+      .1.3.6.1.2.1.2.2.1.10.2 = Counter32: 5678
+      .1.3.6.1.2.1.2.2.1.10.3 = noSuchInstance
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := Dump(buf, m, testMIB(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `SNMPv2c message
+  community: public
+  GetResponse
+    request-id: 1234
+    error-status: noError (0)
+    error-index: 0
+    variable-bindings: 6
+      SNMPv2-MIB::sysDescr.0 = STRING: test agent
+      SNMPv2-MIB::sysObjectID.0 = OID: SNMPv2-SMI::enterprises.9.1.1
+      SNMPv2-MIB::sysUpTime.0 = Timeticks: 12345
+      IF-MIB::ifOperStatus.2 = INTEGER: up(1)
+      IF-MIB::ifInOctets.2 = Counter32: 5678
+      IF-MIB::ifInOctets.3 = noSuchInstance
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestDumpPDU(t *testing.T) {
+	buf := &strings.Builder{}
+
+	err := DumpPDU(buf, NewGetBulkRequest(7, 1, 10, sysUpTime, ifDescr), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `GetBulkRequest
+  request-id: 7
+  non-repeaters: 1
+  max-repetitions: 10
+  variable-bindings: 2
+    .1.3.6.1.2.1.1.3.0 = NULL
+    .1.3.6.1.2.1.2.2.1.2 = NULL
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
@@ -1,5 +1,12 @@
 package snmp
 
+// EncodeTLVHeader encodes a BER tag and length header for custom
+// types. Lengths below 128 use the short form; longer lengths use the
+// long form, a 0x80|n byte followed by the length in n bytes.
+func EncodeTLVHeader(tag byte, length int) []byte {
+	return encodeHeaderSequence(tag, length)
+}
+
 // encodeHeaderSequence encodes an SNMP data type
 // header sequence. The first byte is the field type and
 // the remaining bytes are a length-encoded length.
@@ -0,0 +1,126 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestTLVHeader(t *testing.T) {
+	cases := []struct {
+		length   int
+		expected []byte
+	}{
+		{0, []byte{0x04, 0x00}},
+		{127, []byte{0x04, 0x7f}},
+		{128, []byte{0x04, 0x81, 0x80}},
+		{255, []byte{0x04, 0x81, 0xff}},
+		{256, []byte{0x04, 0x82, 0x01, 0x00}},
+		{65536, []byte{0x04, 0x83, 0x01, 0x00, 0x00}},
+	}
+
+	for _, c := range cases {
+		b := EncodeTLVHeader(0x04, c.length)
+		if !bytes.Equal(b, c.expected) {
+			t.Errorf("%d: expected %x, got %x", c.length, c.expected, b)
+		}
+
+		tag, length, n, err := DecodeTLVHeader(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%d: %v", c.length, err)
+			continue
+		}
+
+		if tag != 0x04 || length != c.length || n != len(b) {
+			t.Errorf("%d: decoded tag %#x, length %d, %d bytes", c.length, tag, length, n)
+		}
+	}
+
+	for _, b := range [][]byte{
+		{0x04},
+		{0x04, 0x82, 0x01},
+		{0x04, 0x80},
+		{0x04, 0x85, 0x01, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if _, _, _, err := DecodeTLVHeader(bytes.NewReader(b)); err == nil {
+			t.Errorf("expected an error decoding %x", b)
+		}
+	}
+}
+
+func TestWalkTLV(t *testing.T) {
+	b, err := GetResponse{PDU: newPDU(1234, 0, 0, []Varbind{
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(sysUpTime, TimeTicks(12345)),
+	})}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tlv struct {
+		tag     byte
+		content []byte
+	}
+
+	collect := func(data []byte) ([]tlv, error) {
+		var tlvs []tlv
+		err := WalkTLV(data, func(tag byte, content []byte) error {
+			tlvs = append(tlvs, tlv{tag, content})
+			return nil
+		})
+
+		return tlvs, err
+	}
+
+	// The PDU is walked as a whole, then its request-id,
+	// error-status, error-index, and variable bindings
+	tlvs, err := collect(b)
+	if err != nil || len(tlvs) != 1 || tlvs[0].tag != TypeGetResponse {
+		t.Fatalf("expected a GetResponse, got %v, %v", tlvs, err)
+	}
+
+	fields, err := collect(tlvs[0].content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{TypeInteger, TypeInteger, TypeInteger, TypeSequence}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %v", len(expected), fields)
+	}
+
+	for i, field := range fields {
+		if field.tag != expected[i] {
+			t.Errorf("field %d: expected tag %#x, got %#x", i, expected[i], field.tag)
+		}
+	}
+
+	if !bytes.Equal(fields[0].content, []byte{0x04, 0xd2}) {
+		t.Errorf("expected request-id 1234, got %x", fields[0].content)
+	}
+
+	varbinds, err := collect(fields[3].content)
+	if err != nil || len(varbinds) != 2 || varbinds[0].tag != TypeSequence || varbinds[1].tag != TypeSequence {
+		t.Errorf("expected 2 variable bindings, got %v, %v", varbinds, err)
+	}
+
+	// A vendor field after the variable bindings is walked too
+	extra := append(append([]byte(nil), tlvs[0].content...), 0x8a, 0x02, 0xbe, 0xef)
+
+	fields, err = collect(extra)
+	if err != nil || len(fields) != 5 || fields[4].tag != 0x8a || !bytes.Equal(fields[4].content, []byte{0xbe, 0xef}) {
+		t.Errorf("expected a trailing field, got %v, %v", fields, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	if err := WalkTLV(extra, func(byte, []byte) error { calls++; return stop }); err != stop || calls != 1 {
+		t.Errorf("expected the walk to stop at the first error, got %v after %d calls", err, calls)
+	}
+
+	for _, bad := range [][]byte{{0x02}, {0x02, 0x02, 0x01}, {0x02, 0x01, 0x01, 0x04, 0x05, 'a'}} {
+		if err := WalkTLV(bad, func(byte, []byte) error { return nil }); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%x: expected ErrTruncated, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package snmp
+
+// Encoder encodes Messages into a buffer that is reused across calls,
+// avoiding the allocations of Message.Encode. The zero value is an
+// Encoder ready to use. An Encoder is not safe for concurrent use.
+type Encoder struct {
+	buf []byte
+}
+
+// Reset empties the buffer of an Encoder so its storage can be reused.
+// Slices returned by EncodeMessage before Reset are overwritten by later
+// calls and must no longer be used.
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+}
+
+// EncodeMessage appends the encoding of m to the buffer of an Encoder
+// and returns it. The returned slice aliases the buffer: it is only
+// valid until the next Reset, and must not be appended to.
+func (e *Encoder) EncodeMessage(m *Message) ([]byte, error) {
+	start := len(e.buf)
+
+	var err error
+
+	if m.Version == Version3 {
+		// SNMPv3 messages aren't worth special-casing
+		var b []byte
+		if b, err = m.Encode(); err != nil {
+			return nil, err
+		}
+
+		e.buf = append(e.buf, b...)
+	} else {
+		if err = m.validate(); err != nil {
+			return nil, err
+		}
+
+		// appendTLV returns nil on error, so the buffer is only
+		// replaced on success
+		var b []byte
+		b, err = appendTLV(e.buf, TypeSequence, func(b []byte) ([]byte, error) {
+			b = appendTLVHeader(b, TypeInteger, integerLength(int64(m.Version)))
+			b = appendInteger(b, int64(m.Version))
+			b = appendTLVHeader(b, TypeString, len(m.Community))
+			b = append(b, m.Community...)
+
+			return appendValue(b, m.PDU)
+		})
+
+		if err != nil {
+			e.buf = e.buf[:start]
+			return nil, err
+		}
+
+		e.buf = b
+	}
+
+	return e.buf[start:len(e.buf):len(e.buf)], nil
+}
+
+// appendValue appends the encoding of v to b. Common types are
+// encoded in place; any other DataType is encoded with Encode.
+func appendValue(b []byte, v DataType) ([]byte, error) {
+	switch v := v.(type) {
+	case Sequence:
+		return appendSequence(b, TypeSequence, v)
+
+	case Varbind:
+		return appendTLV(b, TypeSequence, func(b []byte) ([]byte, error) {
+			b, err := appendValue(b, v.OID)
+			if err != nil {
+				return nil, err
+			}
+
+			if v.value == nil {
+				return appendValue(b, Null)
+			}
+
+			return appendValue(b, v.value)
+		})
+
+	case Int:
+		b = appendTLVHeader(b, TypeInteger, integerLength(int64(v)))
+		return appendInteger(b, int64(v)), nil
+
+	case String:
+		b = appendTLVHeader(b, TypeString, len(v))
+		return append(b, v...), nil
+
+	case ObjectIdentifier:
+		// Use Encode for its validation of the first two arcs
+		if len(v) < 2 || v[0] > 2 || v[0] < 2 && v[1] > 39 || v[1] > 0xffffffff-80 {
+			break
+		}
+
+		// The length is known, so the contents are never moved
+		b = appendTLVHeader(b, TypeOID, v.contentLen())
+
+		b = appendOIDUint(b, v[0]*40+v[1])
+		for _, arc := range v[2:] {
+			b = appendOIDUint(b, arc)
+		}
+
+		return b, nil
+
+	case tag:
+		return append(b, byte(v), 0), nil
+
+	case Counter:
+		return appendUnsignedTLV(b, TypeCounter, uint64(v)), nil
+
+	case Gauge:
+		return appendUnsignedTLV(b, TypeGauge, uint64(v)), nil
+
+	case TimeTicks:
+		return appendUnsignedTLV(b, TypeTimeTicks, uint64(v)), nil
+
+	case Counter64:
+		return appendUnsignedTLV(b, TypeCounter64, uint64(v)), nil
+
+	case GetRequest:
+		return appendSequence(b, TypeGetRequest, v.rawSequence)
+
+	case GetNextRequest:
+		return appendSequence(b, TypeGetNextRequest, v.rawSequence)
+
+	case GetResponse:
+		return appendSequence(b, TypeGetResponse, v.rawSequence)
+
+	case SetRequest:
+		return appendSequence(b, TypeSetRequest, v.rawSequence)
+
+	case GetBulkRequest:
+		return appendSequence(b, TypeGetBulkRequest, v.rawSequence)
+
+	case InformRequest:
+		return appendSequence(b, TypeInformRequest, v.rawSequence)
+
+	case TrapV2:
+		return appendSequence(b, TypeTrapV2, v.rawSequence)
+	}
+
+	encoded, err := v.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, encoded...), nil
+}
+
+// appendSequence appends items as a constructed value of type t.
+func appendSequence(b []byte, t byte, items []DataType) ([]byte, error) {
+	return appendTLV(b, t, func(b []byte) ([]byte, error) {
+		var err error
+
+		for _, item := range items {
+			if b, err = appendValue(b, item); err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	})
+}
+
+// appendTLV appends a value of type t whose contents are appended by
+// content. Space for a short-form header is reserved up front, and the
+// contents are moved if they need a long-form header.
+func appendTLV(b []byte, t byte, content func([]byte) ([]byte, error)) ([]byte, error) {
+	start := len(b)
+	b = append(b, t, 0)
+
+	b, err := content(b)
+	if err != nil {
+		return nil, err
+	}
+
+	length := len(b) - start - 2
+	if length <= 0x7f {
+		b[start+1] = byte(length)
+		return b, nil
+	}
+
+	lengthBytes := 0
+	for l := length; l > 0; l >>= 8 {
+		lengthBytes++
+	}
+
+	for i := 0; i < lengthBytes; i++ {
+		b = append(b, 0)
+	}
+
+	contents := start + 2
+	copy(b[contents+lengthBytes:], b[contents:contents+length])
+
+	b[start+1] = 0x80 | byte(lengthBytes)
+	for i := 0; i < lengthBytes; i++ {
+		b[contents+i] = byte(length >> (8 * uint(lengthBytes-1-i)))
+	}
+
+	return b, nil
+}
+
+// appendTLVHeader appends the header of a value of type t
+// with the given length, as encodeHeaderSequence does.
+func appendTLVHeader(b []byte, t byte, length int) []byte {
+	if length <= 0x7f {
+		return append(b, t, byte(length))
+	}
+
+	return append(b, encodeHeaderSequence(t, length)...)
+}
+
+// tlvHeaderLen returns the number of bytes
+// appendTLVHeader appends for length.
+func tlvHeaderLen(length int) int {
+	n := 2
+	if length > 0x7f {
+		for ; length > 0; length >>= 8 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// integerLength returns the number of bytes of the
+// two's complement encoding of i.
+func integerLength(i int64) int {
+	n := 1
+	for n < 8 && (i < -(1<<uint(8*n-1)) || i >= 1<<uint(8*n-1)) {
+		n++
+	}
+
+	return n
+}
+
+// appendInteger appends the two's complement encoding of i.
+func appendInteger(b []byte, i int64) []byte {
+	for k := integerLength(i) - 1; k >= 0; k-- {
+		b = append(b, byte(i>>uint(8*k)))
+	}
+
+	return b
+}
+
+// appendUnsignedTLV appends u as a value of type t,
+// with a leading zero byte if its high bit is set.
+func appendUnsignedTLV(b []byte, t byte, u uint64) []byte {
+	n := 1
+	for n < 9 && u >= 1<<uint(8*n-1) {
+		n++
+	}
+
+	b = append(b, t, byte(n))
+	for k := n - 1; k >= 0; k-- {
+		b = append(b, byte(u>>uint(8*k)))
+	}
+
+	return b
+}
@@ -0,0 +1,154 @@
+package snmp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func encoderTestMessages() []*Message {
+	long := String(strings.Repeat("x", 300))
+
+	return []*Message{
+		{Version: Version2c, Community: "public", PDU: NewGetRequest(1, sysDescr, sysUpTime)},
+		{Version: Version1, Community: "private", PDU: NewGetNextRequest(-129, ifDescr)},
+		{Version: Version2c, Community: "public", PDU: NewGetBulkRequest(1<<30, 1, 25, sysUpTime, ifDescr)},
+		{Version: Version2c, Community: "public", PDU: NewSetRequest(7,
+			NewVarbind(sysName, long),
+			NewVarbind(sysUpTime, TimeTicks(0xffffffff)),
+			NewVarbind(ifDescr.Child(1), Counter(128)),
+			NewVarbind(ifDescr.Child(2), Gauge(0)),
+			NewVarbind(ifDescr.Child(3), Counter64(1<<63)),
+			NewVarbind(ifDescr.Child(4), Int(-2147483648)),
+			NewVarbind(ifDescr.Child(5), IpAddress{10, 0, 0, 1}),
+		)},
+		{Version: Version2c, Community: "public", PDU: GetResponse{PDU: newPDU(3, 0, 0, []Varbind{
+			NewVarbind(sysDescr, EndOfMIBView),
+			NewVarbind(MustParseOID(".2.999.3"), Int(127)),
+		})}},
+		{Version: Version2c, Community: "public", PDU: NewTrapV2(9, 100, ifDescr)},
+		{Version: Version1, Community: "public", PDU: TrapV1{Enterprise: ifDescr, AgentAddr: IpAddress{127, 0, 0, 1}}},
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var e Encoder
+
+	for round := 0; round < 2; round++ {
+		var encoded [][]byte
+
+		for _, m := range encoderTestMessages() {
+			b, err := e.EncodeMessage(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := m.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, b) {
+				t.Errorf("expected %x, got %x", expected, b)
+			}
+
+			encoded = append(encoded, b)
+		}
+
+		// Earlier results stay valid until Reset
+		for i, m := range encoderTestMessages() {
+			expected, _ := m.Encode()
+			if !bytes.Equal(expected, encoded[i]) {
+				t.Errorf("message %d was overwritten before Reset", i)
+			}
+		}
+
+		e.Reset()
+	}
+
+	if _, err := e.EncodeMessage(&Message{Version: Version2c, PDU: NewGetRequest(1, ObjectIdentifier{1})}); err == nil {
+		t.Error("expected an error for an invalid OID")
+	}
+
+	if _, err := e.EncodeMessage(&Message{Version: 7, PDU: NewGetRequest(1, sysDescr)}); err != ErrUnsupportedVersion {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestEncoderErrorKeepsBuffer(t *testing.T) {
+	var e Encoder
+
+	good := encoderTestMessages()[0]
+
+	first, err := e.EncodeMessage(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a Reset, the first message is still in the buffer
+	if _, err := e.EncodeMessage(&Message{Version: Version2c, PDU: NewGetRequest(1, ObjectIdentifier{3, 1})}); err == nil {
+		t.Fatal("expected an error for an invalid OID")
+	}
+
+	second, err := e.EncodeMessage(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := good.Encode()
+	if !bytes.Equal(expected, first) || !bytes.Equal(expected, second) {
+		t.Errorf("expected %x twice, got %x and %x", expected, first, second)
+	}
+
+	if len(e.buf) != 2*len(expected) {
+		t.Errorf("expected the buffer to hold 2 messages, got %d bytes", len(e.buf))
+	}
+}
+
+func TestEncoderIntegers(t *testing.T) {
+	for _, i := range []int{0, 1, -1, 127, 128, -128, -129, 255, 256, 32767, 32768, -32769, 1<<31 - 1, -1 << 31, 1 << 40, -1 << 62} {
+		expected, _ := Int(i).Encode()
+
+		b, _ := appendValue(nil, Int(i))
+		if !bytes.Equal(expected, b) {
+			t.Errorf("%d: expected %x, got %x", i, expected, b)
+		}
+	}
+
+	for _, u := range []uint64{0, 1, 127, 128, 255, 256, 1<<32 - 1, 1 << 63, 1<<64 - 1} {
+		expected, _ := Counter64(u).Encode()
+
+		b, _ := appendValue(nil, Counter64(u))
+		if !bytes.Equal(expected, b) {
+			t.Errorf("%d: expected %x, got %x", u, expected, b)
+		}
+	}
+}
+
+func BenchmarkMessageEncode(b *testing.B) {
+	m := encoderTestMessages()[3]
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := m.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	m := encoderTestMessages()[3]
+
+	var e Encoder
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		e.Reset()
+
+		if _, err := e.EncodeMessage(m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
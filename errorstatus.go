@@ -0,0 +1,109 @@
+package snmp
+
+import (
+	"fmt"
+)
+
+// ErrorStatus represents the error-status field of a PDU.
+type ErrorStatus int
+
+// Error-status values defined by SNMPv1.
+const (
+	NoError    ErrorStatus = 0
+	TooBig     ErrorStatus = 1
+	NoSuchName ErrorStatus = 2
+	BadValue   ErrorStatus = 3
+	ReadOnly   ErrorStatus = 4
+	GenErr     ErrorStatus = 5
+)
+
+// Error-status values added by SNMPv2.
+const (
+	NoAccess            ErrorStatus = 6
+	WrongType           ErrorStatus = 7
+	WrongLength         ErrorStatus = 8
+	WrongEncoding       ErrorStatus = 9
+	WrongValue          ErrorStatus = 10
+	NoCreation          ErrorStatus = 11
+	InconsistentValue   ErrorStatus = 12
+	ResourceUnavailable ErrorStatus = 13
+	CommitFailed        ErrorStatus = 14
+	UndoFailed          ErrorStatus = 15
+	AuthorizationError  ErrorStatus = 16
+	NotWritable         ErrorStatus = 17
+	InconsistentName    ErrorStatus = 18
+)
+
+var errorStatusNames = map[ErrorStatus]string{
+	NoError:    "noError",
+	TooBig:     "tooBig",
+	NoSuchName: "noSuchName",
+	BadValue:   "badValue",
+	ReadOnly:   "readOnly",
+	GenErr:     "genErr",
+
+	NoAccess:            "noAccess",
+	WrongType:           "wrongType",
+	WrongLength:         "wrongLength",
+	WrongEncoding:       "wrongEncoding",
+	WrongValue:          "wrongValue",
+	NoCreation:          "noCreation",
+	InconsistentValue:   "inconsistentValue",
+	ResourceUnavailable: "resourceUnavailable",
+	CommitFailed:        "commitFailed",
+	UndoFailed:          "undoFailed",
+	AuthorizationError:  "authorizationError",
+	NotWritable:         "notWritable",
+	InconsistentName:    "inconsistentName",
+}
+
+// String returns the name of an ErrorStatus, e.g. "noSuchName".
+func (e ErrorStatus) String() string {
+	if name, ok := errorStatusNames[e]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("errorStatus(%d)", int(e))
+}
+
+// Error implements the error interface.
+func (e ErrorStatus) Error() string {
+	return "snmp: " + e.String()
+}
+
+// SNMPError is returned when an agent responds with a non-zero
+// error-status.
+type SNMPError struct {
+	Status ErrorStatus
+
+	// Index is the 1-based index of the variable binding
+	// that caused the error, or 0 if there is none.
+	Index int
+
+	// OID is the OID of the variable binding at Index, if any.
+	OID ObjectIdentifier
+
+	// RawIndex is the error-index of the response, kept when it
+	// is out of range for its variable bindings and Index is 0.
+	RawIndex int
+}
+
+// Error implements the error interface.
+func (e SNMPError) Error() string {
+	switch {
+	case e.OID != nil:
+		return fmt.Sprintf("snmp: %s for varbind %d (%v)", e.Status.String(), e.Index, e.OID)
+	case e.Index > 0:
+		return fmt.Sprintf("snmp: %s for varbind %d", e.Status.String(), e.Index)
+	case e.RawIndex != 0:
+		return fmt.Sprintf("snmp: %s (error-index %d out of range)", e.Status.String(), e.RawIndex)
+	}
+
+	return "snmp: " + e.Status.String()
+}
+
+// Unwrap returns the error-status of an SNMPError,
+// so that errors.Is(err, NoSuchName) works.
+func (e SNMPError) Unwrap() error {
+	return e.Status
+}
@@ -0,0 +1,72 @@
+package snmp
+
+import (
+	"io"
+)
+
+// GetBulkRequest represents an SNMPv2 GetBulkRequest-PDU.
+// It is not valid in SNMPv1 messages.
+type GetBulkRequest struct {
+	PDU
+}
+
+// NewGetBulkRequest returns a new GetBulkRequest for oids.
+// The first nonRepeaters OIDs are treated like a GetNextRequest, and
+// up to maxRepetitions successors are returned for each of the rest.
+func NewGetBulkRequest(requestID int32, nonRepeaters, maxRepetitions int, oids ...ObjectIdentifier) GetBulkRequest {
+	return GetBulkRequest{
+		PDU: newPDU(int(requestID), nonRepeaters, maxRepetitions, nullVarbinds(oids)),
+	}
+}
+
+// NonRepeaters returns the non-repeaters field of a GetBulkRequest,
+// which takes the place of error-status.
+func (s GetBulkRequest) NonRepeaters() int {
+	return s.PDU.err
+}
+
+// MaxRepetitions returns the max-repetitions field of a GetBulkRequest,
+// which takes the place of error-index.
+func (s GetBulkRequest) MaxRepetitions() int {
+	return s.PDU.errIndex
+}
+
+// Encode encodes a GetBulkRequest with the proper header.
+func (s GetBulkRequest) Encode() ([]byte, error) {
+	return s.PDU.encode(TypeGetBulkRequest)
+}
+
+// decodeGetBulkRequest decodes a GetBulkRequest up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeGetBulkRequest(length int, r io.Reader) (GetBulkRequest, int, error) {
+	pdu, bytesRead, err := decodePDU(length, r)
+	return GetBulkRequest{PDU: pdu}, bytesRead, err
+}
+
+// BulkColumns splits the repeated bindings of a GETBULK response by the
+// repeating OID they succeed, given the OIDs after the non-repeaters of
+// the request and the bindings after those of the non-repeaters. As in
+// RFC 3416, binding i belongs to column i mod len(repeaters). A column
+// that reaches the end of the MIB view before the others ends with its
+// first endOfMibView binding, and the ones the agent pads it with in
+// later repetitions are dropped.
+func BulkColumns(repeaters []ObjectIdentifier, varbinds []Varbind) [][]Varbind {
+	if len(repeaters) == 0 {
+		return nil
+	}
+
+	columns := make([][]Varbind, len(repeaters))
+	ended := make([]bool, len(repeaters))
+
+	for i, v := range varbinds {
+		column := i % len(repeaters)
+		if ended[column] {
+			continue
+		}
+
+		columns[column] = append(columns[column], v)
+		ended[column] = v.value == EndOfMIBView
+	}
+
+	return columns
+}
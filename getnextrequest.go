@@ -1,7 +1,7 @@
 package snmp
 
 import (
-	"bytes"
+	"io"
 )
 
 // GetNextRequest represents an SNMP GetNextRequest-PDU.
@@ -9,6 +9,13 @@ type GetNextRequest struct {
 	PDU
 }
 
+// NewGetNextRequest returns a new GetNextRequest for oids.
+// Each variable binding has a NULL value. The OIDs in the response
+// are the lexicographic successors of oids rather than oids themselves.
+func NewGetNextRequest(requestID int32, oids ...ObjectIdentifier) GetNextRequest {
+	return newGetNextRequest(int(requestID), nullVarbinds(oids))
+}
+
 func newGetNextRequest(requestID int, varbinds []Varbind) GetNextRequest {
 	pdu := newPDU(requestID, 0, 0, varbinds)
 
@@ -19,21 +26,12 @@ func newGetNextRequest(requestID int, varbinds []Varbind) GetNextRequest {
 
 // Encode encodes a GetNextRequest with the proper header.
 func (s GetNextRequest) Encode() ([]byte, error) {
-	buf := &bytes.Buffer{}
-
-	for _, entry := range s.PDU.rawSequence {
-		encodedEntry, err := entry.Encode()
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = buf.Write(encodedEntry)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	seqLength := buf.Len()
+	return s.PDU.encode(TypeGetNextRequest)
+}
 
-	return append(encodeHeaderSequence(TypeGetNextRequest, seqLength), buf.Bytes()...), nil
+// decodeGetNextRequest decodes a GetNextRequest up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeGetNextRequest(length int, r io.Reader) (GetNextRequest, int, error) {
+	pdu, bytesRead, err := decodePDU(length, r)
+	return GetNextRequest{PDU: pdu}, bytesRead, err
 }
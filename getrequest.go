@@ -1,7 +1,7 @@
 package snmp
 
 import (
-	"bytes"
+	"io"
 )
 
 // GetRequest represents an SNMP GetRequest-PDU.
@@ -9,6 +9,12 @@ type GetRequest struct {
 	PDU
 }
 
+// NewGetRequest returns a new GetRequest for oids.
+// Each variable binding has a NULL value.
+func NewGetRequest(requestID int32, oids ...ObjectIdentifier) GetRequest {
+	return newGetRequest(int(requestID), nullVarbinds(oids))
+}
+
 func newGetRequest(requestID int, varbinds []Varbind) GetRequest {
 	pdu := newPDU(requestID, 0, 0, varbinds)
 
@@ -19,21 +25,12 @@ func newGetRequest(requestID int, varbinds []Varbind) GetRequest {
 
 // Encode encodes a GetRequest with the proper header.
 func (s GetRequest) Encode() ([]byte, error) {
-	buf := &bytes.Buffer{}
-
-	for _, entry := range s.PDU.rawSequence {
-		encodedEntry, err := entry.Encode()
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = buf.Write(encodedEntry)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	seqLength := buf.Len()
+	return s.PDU.encode(TypeGetRequest)
+}
 
-	return append(encodeHeaderSequence(TypeGetRequest, seqLength), buf.Bytes()...), nil
+// decodeGetRequest decodes a GetRequest up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeGetRequest(length int, r io.Reader) (GetRequest, int, error) {
+	pdu, bytesRead, err := decodePDU(length, r)
+	return GetRequest{PDU: pdu}, bytesRead, err
 }
@@ -1,7 +1,6 @@
 package snmp
 
 import (
-	"bytes"
 	"io"
 )
 
@@ -12,23 +11,7 @@ type GetResponse struct {
 
 // Encode encodes a GetResponse with the proper header.
 func (s GetResponse) Encode() ([]byte, error) {
-	buf := &bytes.Buffer{}
-
-	for _, entry := range s.PDU.rawSequence {
-		encodedEntry, err := entry.Encode()
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = buf.Write(encodedEntry)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	seqLength := buf.Len()
-
-	return append(encodeHeaderSequence(0xa2, seqLength), buf.Bytes()...), nil
+	return s.PDU.encode(TypeGetResponse)
 }
 
 // decodeGetResponse decodes a GetResponse up to length bytes from r.
@@ -0,0 +1,142 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidIndex = errors.New("snmp: invalid table index")
+
+// IndexKind describes how one object of a table INDEX clause
+// is encoded in the arcs of instance OIDs.
+type IndexKind struct {
+	// Type is TypeInteger, TypeGauge, TypeIpAddress,
+	// TypeString, or TypeOID.
+	Type byte
+
+	// Size is the length of a fixed-size OCTET STRING, such as
+	// 6 for a MacAddress. Strings without a Size are preceded by
+	// their length.
+	Size int
+
+	// Implied is set for an IMPLIED last index, whose OCTET STRING
+	// or OBJECT IDENTIFIER uses the remaining arcs without a length.
+	Implied bool
+}
+
+// DecodeIndex decodes the index arcs of a table instance, such as
+// those returned by TrimPrefix for a column OID, into a value for each
+// IndexKind of spec. INTEGER indexes are decoded as Int, Unsigned32 as
+// Gauge, and OCTET STRING as String. An error wrapping ErrInvalidIndex
+// is returned if the arcs don't match spec.
+func DecodeIndex(suffix ObjectIdentifier, spec []IndexKind) ([]DataType, error) {
+	values := make([]DataType, 0, len(spec))
+
+	for i, kind := range spec {
+		if kind.Implied && i != len(spec)-1 {
+			return nil, fmt.Errorf("%w: only the last index can be IMPLIED", ErrInvalidIndex)
+		}
+
+		value, n, err := decodeIndexPart(suffix, kind)
+		if err != nil {
+			return nil, fmt.Errorf("%w: part %d of %s: %v", ErrInvalidIndex, i, suffix, err)
+		}
+
+		values = append(values, value)
+		suffix = suffix[n:]
+	}
+
+	if len(suffix) > 0 {
+		return nil, fmt.Errorf("%w: %d arcs left over", ErrInvalidIndex, len(suffix))
+	}
+
+	return values, nil
+}
+
+// decodeIndexPart decodes a value of kind from the leading arcs
+// of suffix. It returns the value and the number of arcs used.
+func decodeIndexPart(suffix ObjectIdentifier, kind IndexKind) (DataType, int, error) {
+	switch kind.Type {
+	case TypeInteger, TypeGauge:
+		if len(suffix) < 1 {
+			return nil, 0, errors.New("missing integer")
+		}
+
+		if kind.Type == TypeGauge {
+			return Gauge(suffix[0]), 1, nil
+		}
+
+		return Int(int32(suffix[0])), 1, nil
+
+	case TypeIpAddress:
+		b, err := indexOctets(suffix, 4)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return IpAddress(b), 4, nil
+
+	case TypeString:
+		start, length, err := indexLength(suffix, kind)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		b, err := indexOctets(suffix[start:], length)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return String(b), start + length, nil
+
+	case TypeOID:
+		start, length, err := indexLength(suffix, kind)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(suffix)-start < length {
+			return nil, 0, errors.New("truncated OBJECT IDENTIFIER")
+		}
+
+		return suffix[start : start+length].Clone(), start + length, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported type 0x%x", kind.Type)
+}
+
+// indexLength returns the number of arcs before a variable-length
+// value of kind at the start of suffix, and the length of the value.
+func indexLength(suffix ObjectIdentifier, kind IndexKind) (int, int, error) {
+	switch {
+	case kind.Implied:
+		return 0, len(suffix), nil
+	case kind.Size > 0:
+		return 0, kind.Size, nil
+	case len(suffix) < 1:
+		return 0, 0, errors.New("missing length")
+	case uint64(suffix[0]) > uint64(len(suffix)-1):
+		// Compared before converting, since int may be 32 bits
+		return 0, 0, fmt.Errorf("length %d exceeds the %d arcs left", suffix[0], len(suffix)-1)
+	}
+
+	return 1, int(suffix[0]), nil
+}
+
+// indexOctets returns the first n arcs of suffix as bytes.
+func indexOctets(suffix ObjectIdentifier, n int) ([]byte, error) {
+	if len(suffix) < n {
+		return nil, fmt.Errorf("expected %d octets, got %d arcs", n, len(suffix))
+	}
+
+	b := make([]byte, n)
+	for i, arc := range suffix[:n] {
+		if arc > 0xff {
+			return nil, fmt.Errorf("arc %d is not an octet", arc)
+		}
+
+		b[i] = byte(arc)
+	}
+
+	return b, nil
+}
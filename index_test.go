@@ -0,0 +1,124 @@
+package snmp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeIndexIpAddress(t *testing.T) {
+	// ipNetToMediaTable is indexed by ipNetToMediaIfIndex
+	// and ipNetToMediaNetAddress
+	column := MustParseOID(".1.3.6.1.2.1.4.22.1.2")
+	instance := column.Append(3, 192, 0, 2, 17)
+
+	suffix, _ := instance.TrimPrefix(column)
+
+	values, err := DecodeIndex(suffix, []IndexKind{{Type: TypeInteger}, {Type: TypeIpAddress}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []DataType{Int(3), IpAddress{192, 0, 2, 17}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	for _, bad := range []ObjectIdentifier{
+		{3, 192, 0, 2},
+		{3, 192, 0, 2, 256},
+		{3, 192, 0, 2, 17, 1},
+	} {
+		if _, err := DecodeIndex(bad, []IndexKind{{Type: TypeInteger}, {Type: TypeIpAddress}}); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("%v: expected ErrInvalidIndex, got %v", bad, err)
+		}
+	}
+}
+
+func TestDecodeIndexString(t *testing.T) {
+	for _, test := range []struct {
+		suffix   ObjectIdentifier
+		spec     []IndexKind
+		expected []DataType
+	}{
+		// vacmAccessTable style: a length-prefixed group name,
+		// a length-prefixed context prefix, and two integers
+		{
+			ObjectIdentifier{3, 'a', 'd', 'm', 0, 3, 1},
+			[]IndexKind{{Type: TypeString}, {Type: TypeString}, {Type: TypeInteger}, {Type: TypeInteger}},
+			[]DataType{String("adm"), String(""), Int(3), Int(1)},
+		},
+		// snmpTargetAddrTable style: an IMPLIED name
+		{
+			ObjectIdentifier{'n', 'm', 's'},
+			[]IndexKind{{Type: TypeString, Implied: true}},
+			[]DataType{String("nms")},
+		},
+		// A fixed-size MacAddress followed by an integer
+		{
+			ObjectIdentifier{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e, 7},
+			[]IndexKind{{Type: TypeString, Size: 6}, {Type: TypeGauge}},
+			[]DataType{String("\x00\x1a\x2b\x3c\x4d\x5e"), Gauge(7)},
+		},
+		{
+			ObjectIdentifier{4, 1, 3, 6, 1, 9},
+			[]IndexKind{{Type: TypeOID}, {Type: TypeInteger}},
+			[]DataType{ObjectIdentifier{1, 3, 6, 1}, Int(9)},
+		},
+	} {
+		values, err := DecodeIndex(test.suffix, test.spec)
+		if err != nil {
+			t.Errorf("%v: %v", test.suffix, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(values, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.suffix, test.expected, values)
+		}
+	}
+
+	if _, err := DecodeIndex(ObjectIdentifier{5, 'a', 'b'}, []IndexKind{{Type: TypeString}}); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("expected ErrInvalidIndex for a truncated string, got %v", err)
+	}
+
+	if _, err := DecodeIndex(ObjectIdentifier{'a', 1}, []IndexKind{{Type: TypeString, Implied: true}, {Type: TypeInteger}}); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("expected ErrInvalidIndex for an IMPLIED index that isn't last, got %v", err)
+	}
+}
+
+func TestDecodeIndexTCPConnection(t *testing.T) {
+	// tcpConnectionTable is indexed by the type, address, and port of
+	// each end, where the addresses are length-prefixed InetAddresses
+	spec := []IndexKind{
+		{Type: TypeInteger}, {Type: TypeString}, {Type: TypeGauge},
+		{Type: TypeInteger}, {Type: TypeString}, {Type: TypeGauge},
+	}
+
+	suffix := ObjectIdentifier{1, 4, 192, 0, 2, 1, 22, 2, 16, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 50000}
+
+	values, err := DecodeIndex(suffix, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []DataType{
+		Int(1), String("\xc0\x00\x02\x01"), Gauge(22),
+		Int(2), String("\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01"), Gauge(50000),
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	// A single length-prefixed string uses exactly its arcs
+	if values, err := DecodeIndex(ObjectIdentifier{2, 'h', 'i'}, []IndexKind{{Type: TypeString}}); err != nil || values[0] != String("hi") {
+		t.Errorf("expected \"hi\", got %v and %v", values, err)
+	}
+
+	// Lengths past the arcs left fail rather than mis-splitting
+	for _, bad := range []ObjectIdentifier{{1, 4, 192, 0, 2}, {1, 1<<32 - 1, 192}} {
+		if _, err := DecodeIndex(bad, spec); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("%v: expected ErrInvalidIndex, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package snmp
+
+import (
+	"io"
+)
+
+// InformRequest represents an SNMPv2 InformRequest-PDU.
+// It is a notification the receiver acknowledges with a GetResponse.
+// It is not valid in SNMPv1 messages.
+type InformRequest struct {
+	PDU
+}
+
+// NewInformRequest returns a new InformRequest for the notification
+// trapOID. The sysUpTime.0 and snmpTrapOID.0 variable bindings are
+// added before extra.
+func NewInformRequest(requestID int32, uptime TimeTicks, trapOID ObjectIdentifier, extra ...Varbind) InformRequest {
+	return InformRequest{
+		PDU: newPDU(int(requestID), 0, 0, notificationVarbinds(uptime, trapOID, extra)),
+	}
+}
+
+// Encode encodes an InformRequest with the proper header.
+func (s InformRequest) Encode() ([]byte, error) {
+	return s.PDU.encode(TypeInformRequest)
+}
+
+// decodeInformRequest decodes an InformRequest up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeInformRequest(length int, r io.Reader) (InformRequest, int, error) {
+	pdu, bytesRead, err := decodePDU(length, r)
+	return InformRequest{PDU: pdu}, bytesRead, err
+}
@@ -1,6 +1,7 @@
 package snmp
 
 import (
+	"errors"
 	"io"
 )
 
@@ -53,10 +54,15 @@ func (i Int) Encode() ([]byte, error) {
 // decodeInteger decodes an integer up to length bytes from r.
 // It returns the SNMP data type, the number of bytes read, and an error.
 func decodeInteger(length int, r io.Reader) (Int, int, error) {
-	intBytes := make([]byte, int(length))
 	bytesRead := 0
 
-	n, err := r.Read(intBytes)
+	if length < 1 || length > 8 {
+		return 0, bytesRead, errors.New("snmp: invalid integer length")
+	}
+
+	intBytes := make([]byte, length)
+
+	n, err := io.ReadFull(r, intBytes)
 	bytesRead += n
 
 	if err != nil {
@@ -87,3 +93,89 @@ func decodeInteger(length int, r io.Reader) (Int, int, error) {
 
 	return Int(i), bytesRead, nil
 }
+
+// paddedInteger is an integer value of type tag, with the minimal
+// two's complement content, that is sign-extended to width bytes.
+type paddedInteger struct {
+	tag     byte
+	content []byte
+	width   int
+}
+
+// Encode encodes a paddedInteger with the proper header.
+func (p paddedInteger) Encode() ([]byte, error) {
+	pad := byte(0)
+	if p.content[0]&0x80 != 0 {
+		pad = 0xff
+	}
+
+	length := len(p.content)
+	if length < p.width {
+		length = p.width
+	}
+
+	result := encodeHeaderSequence(p.tag, length)
+	for n := len(p.content); n < length; n++ {
+		result = append(result, pad)
+	}
+
+	return append(result, p.content...), nil
+}
+
+// encodeUnsigned encodes a length-encoded unsigned integer.
+// A leading zero byte is added when the high bit is set so
+// the value isn't read as negative.
+func encodeUnsigned(u uint64) []byte {
+	result := []byte{byte(u)}
+	u >>= 8
+
+	for u > 0 {
+		result = append(result, byte(u))
+		u >>= 8
+	}
+
+	if result[len(result)-1]&0x80 != 0 {
+		result = append(result, 0x0)
+	}
+
+	return reverseSlice(result)
+}
+
+// decodeUnsigned decodes an unsigned integer up to length bytes from r.
+// It returns the value, the number of bytes read, and an error.
+// An error is returned if the value doesn't fit in bits bits.
+func decodeUnsigned(length int, r io.Reader, bits uint) (uint64, int, error) {
+	bytesRead := 0
+
+	if length < 1 || length > 9 {
+		return 0, bytesRead, errors.New("snmp: invalid unsigned integer length")
+	}
+
+	intBytes := make([]byte, length)
+
+	n, err := io.ReadFull(r, intBytes)
+	bytesRead += n
+
+	if err != nil {
+		return 0, bytesRead, err
+	}
+
+	if intBytes[0]&0x80 != 0 {
+		return 0, bytesRead, errors.New("snmp: negative unsigned integer")
+	}
+
+	if length == 9 && intBytes[0] != 0 {
+		return 0, bytesRead, errors.New("snmp: unsigned integer overflows 64 bits")
+	}
+
+	u := uint64(0)
+	for _, b := range intBytes {
+		u = u<<8 | uint64(b)
+	}
+
+	if bits < 64 && u>>bits != 0 {
+		return 0, bytesRead, errors.New("snmp: unsigned integer out of range")
+	}
+
+	return u, bytesRead, nil
+}
@@ -3,6 +3,7 @@ package snmp
 import (
 	"bytes"
 	"encoding/asn1"
+	"math"
 	"testing"
 )
 
@@ -32,6 +33,12 @@ func TestIntegerEncoding(t *testing.T) {
 		-12345,
 		1,
 		-1,
+		-129,
+		256,
+		math.MaxInt32,
+		math.MinInt32,
+		math.MaxInt64,
+		math.MinInt64,
 	}
 
 	for _, testCase := range cases {
@@ -60,3 +67,151 @@ func TestIntegerDecoding(t *testing.T) {
 		}
 	}
 }
+
+func TestIntegerRoundTrip(t *testing.T) {
+	cases := [...]int{-128, -1, 0, 127, 128, 1 << 40, -(1 << 40), math.MaxInt64, math.MinInt64}
+
+	for _, testCase := range cases {
+		b, err := Int(testCase).Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, n, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("decoding %d: %v", testCase, err)
+		}
+
+		if n != len(b) {
+			t.Errorf("expected %d bytes read for %d, got %d", len(b), testCase, n)
+		}
+
+		if decoded != Int(testCase) {
+			t.Errorf("expected %d, got %v", testCase, decoded)
+		}
+	}
+
+	// 0x00 alone is the minimal encoding of zero
+	if b, _ := Int(0).Encode(); !bytes.Equal(b, []byte{2, 1, 0}) {
+		t.Errorf("expected zero to encode as [2 1 0], got %v", b)
+	}
+
+	for _, b := range [][]byte{
+		{2, 0},
+		{2, 9, 1, 0, 0, 0, 0, 0, 0, 0, 0},
+		{2, 2, 1},
+	} {
+		if _, _, err := decode(bytes.NewReader(b)); err == nil {
+			t.Errorf("expected an error decoding %v", b)
+		}
+	}
+}
+
+func TestCounterEncoding(t *testing.T) {
+	cases := []struct {
+		c        Counter
+		expected []byte
+	}{
+		{0, []byte{0x41, 0x01, 0x00}},
+		{255, []byte{0x41, 0x02, 0x00, 0xff}},
+		{0x80000000, []byte{0x41, 0x05, 0x00, 0x80, 0x00, 0x00, 0x00}},
+		{0xffffffff, []byte{0x41, 0x05, 0x00, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, c := range cases {
+		b, err := c.c.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(c.expected, b) {
+			t.Errorf("encoding %d: expected %v, got %v", c.c, c.expected, b)
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("decoding %d: %v", c.c, err)
+		}
+
+		if decoded != c.c {
+			t.Errorf("expected %v (%T), got %v (%T)", c.c, c.c, decoded, decoded)
+		}
+	}
+
+	for _, b := range [][]byte{
+		{0x41, 0x01, 0x80},
+		{0x41, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if _, _, err := decode(bytes.NewReader(b)); err == nil {
+			t.Errorf("expected an error decoding %v", b)
+		}
+	}
+}
+
+func TestGaugeEncoding(t *testing.T) {
+	cases := []struct {
+		g        Gauge
+		expected []byte
+	}{
+		{0x7f, []byte{0x42, 0x01, 0x7f}},
+		{0x80, []byte{0x42, 0x02, 0x00, 0x80}},
+		{0x7fffffff, []byte{0x42, 0x04, 0x7f, 0xff, 0xff, 0xff}},
+		{0x80000000, []byte{0x42, 0x05, 0x00, 0x80, 0x00, 0x00, 0x00}},
+		{1000000000, []byte{0x42, 0x04, 0x3b, 0x9a, 0xca, 0x00}},
+	}
+
+	for _, c := range cases {
+		b, err := c.g.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(c.expected, b) {
+			t.Errorf("encoding %d: expected %v, got %v", c.g, c.expected, b)
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("decoding %d: %v", c.g, err)
+		}
+
+		if decoded != c.g {
+			t.Errorf("expected %v (%T), got %v (%T)", c.g, c.g, decoded, decoded)
+		}
+	}
+}
+
+func TestCounter64Encoding(t *testing.T) {
+	cases := []struct {
+		c        Counter64
+		expected []byte
+	}{
+		{0, []byte{0x46, 0x01, 0x00}},
+		{1 << 32, []byte{0x46, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{math.MaxUint64, []byte{0x46, 0x09, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, c := range cases {
+		b, err := c.c.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(c.expected, b) {
+			t.Errorf("encoding %d: expected %v, got %v", c.c, c.expected, b)
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("decoding %d: %v", c.c, err)
+		}
+
+		if decoded != c.c {
+			t.Errorf("expected %v (%T), got %v (%T)", c.c, c.c, decoded, decoded)
+		}
+	}
+
+	if _, _, err := decode(bytes.NewReader([]byte{0x46, 0x09, 0x01, 0, 0, 0, 0, 0, 0, 0, 0})); err == nil {
+		t.Error("expected an error decoding a Counter64 larger than 64 bits")
+	}
+}
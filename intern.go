@@ -0,0 +1,74 @@
+package snmp
+
+import (
+	"sync"
+)
+
+// internSlabSize is the number of arcs allocated at a time
+// for interned ObjectIdentifiers.
+const internSlabSize = 4096
+
+// OIDInterner deduplicates ObjectIdentifiers, so that OIDs seen
+// repeatedly, such as the rows of a table walked every poll, share
+// storage. Interned OIDs must not be modified. The zero value is an
+// empty OIDInterner ready to use, and it is safe for concurrent use.
+type OIDInterner struct {
+	mu   sync.Mutex
+	root internNode
+
+	// slab is the unused storage interned OIDs are carved from.
+	slab []uint32
+}
+
+// internNode is a node of the trie of interned OIDs, keyed by arc.
+type internNode struct {
+	children map[uint32]*internNode
+	oid      ObjectIdentifier
+}
+
+// Intern returns the interned ObjectIdentifier equal to oid,
+// interning a copy of oid if there is none yet.
+func (i *OIDInterner) Intern(oid ObjectIdentifier) ObjectIdentifier {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	n := &i.root
+	for _, arc := range oid {
+		child, ok := n.children[arc]
+		if !ok {
+			if n.children == nil {
+				n.children = map[uint32]*internNode{}
+			}
+
+			child = &internNode{}
+			n.children[arc] = child
+		}
+
+		n = child
+	}
+
+	if n.oid == nil {
+		n.oid = i.alloc(oid)
+	}
+
+	return n.oid
+}
+
+// alloc returns a copy of oid carved from the slab. Its capacity is
+// its length, so appending to it never overwrites another OID.
+func (i *OIDInterner) alloc(oid ObjectIdentifier) ObjectIdentifier {
+	if len(i.slab) < len(oid) {
+		size := internSlabSize
+		if len(oid) > size {
+			size = len(oid)
+		}
+
+		i.slab = make([]uint32, size)
+	}
+
+	interned := ObjectIdentifier(i.slab[:len(oid):len(oid)])
+	copy(interned, oid)
+	i.slab = i.slab[len(oid):]
+
+	return interned
+}
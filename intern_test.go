@@ -0,0 +1,100 @@
+package snmp
+
+import (
+	"testing"
+)
+
+func TestOIDInterner(t *testing.T) {
+	var interner OIDInterner
+
+	a := interner.Intern(ifDescr.Child(1))
+	b := interner.Intern(ifDescr.Child(1))
+	c := interner.Intern(ifDescr.Child(2))
+
+	if &a[0] != &b[0] {
+		t.Error("expected equal OIDs to share storage")
+	}
+
+	if !a.Equal(ifDescr.Child(1)) || !c.Equal(ifDescr.Child(2)) {
+		t.Errorf("unexpected interned OIDs %v and %v", a, c)
+	}
+
+	// Appending to an interned OID must not overwrite the next one
+	_ = append(a, 99)
+	if !c.Equal(ifDescr.Child(2)) {
+		t.Errorf("expected %v to be unchanged, got %v", ifDescr.Child(2), c)
+	}
+
+	if prefix := interner.Intern(ifDescr); !prefix.Equal(ifDescr) {
+		t.Errorf("expected %v, got %v", ifDescr, prefix)
+	}
+}
+
+func TestClientWalkInterner(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Interner: &OIDInterner{}}
+
+	var first []ObjectIdentifier
+
+	for walk := 0; walk < 2; walk++ {
+		i := 0
+
+		err := c.BulkWalk(ifDescr, 10, func(v Varbind) error {
+			if walk == 0 {
+				first = append(first, v.OID)
+			} else if &first[i][0] != &v.OID[0] {
+				t.Errorf("expected %v to be interned", v.OID)
+			}
+
+			i++
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tableOIDs simulates the OIDs of a walk of a table with 50000 rows.
+func tableOIDs() []ObjectIdentifier {
+	oids := make([]ObjectIdentifier, 50000)
+	for i := range oids {
+		oids[i] = ifDescr.Child(uint32(i + 1))
+	}
+
+	return oids
+}
+
+// BenchmarkRetainOIDs copies every OID of each walk, as a poller
+// keeping the results of each walk would without an OIDInterner.
+func BenchmarkRetainOIDs(b *testing.B) {
+	oids := tableOIDs()
+	retained := make([]ObjectIdentifier, len(oids))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for i, oid := range oids {
+			retained[i] = append(ObjectIdentifier(nil), oid...)
+		}
+	}
+}
+
+func BenchmarkInternOIDs(b *testing.B) {
+	oids := tableOIDs()
+	retained := make([]ObjectIdentifier, len(oids))
+
+	var interner OIDInterner
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for i, oid := range oids {
+			retained[i] = interner.Intern(oid)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package hexdump parses hex dumps of packets for use as test fixtures.
+//
+// Captures from real agents are kept in testdata as one packet per
+// file, named after the agent and PDU, such as
+// testdata/net-snmp-getresponse.hex. Each file holds the UDP payload
+// as printed by tcpdump -x with the IP and UDP headers removed, and may
+// start with # comment lines noting where the capture came from:
+//
+//	# net-snmp 5.9, snmpget -v2c -c public localhost sysDescr.0
+//	0x0000:  3029 0201 0104 0670 7562 6c69 63a0 1c02
+//	0x0010:  0404 d2a2 2a02 0100 0201 0030 0e30 0c06
+//	...
+package hexdump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// FromHex returns the bytes of a hex dump such as the output of
+// tcpdump -x or xxd -g 1 without its text column. Offsets, which are
+// the first field of a line when it ends with a colon, are stripped,
+// as are blank lines and lines starting with #. The remaining fields
+// must be groups of hex digits of even length. FromHex panics if s
+// isn't a valid hex dump, since fixtures are fixed at compile time.
+func FromHex(s string) []byte {
+	var b []byte
+
+	for n, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if strings.HasSuffix(fields[0], ":") {
+			fields = fields[1:]
+		}
+
+		for _, field := range fields {
+			decoded, err := hex.DecodeString(field)
+			if err != nil {
+				panic(fmt.Sprintf("hexdump: line %d: invalid field %q", n+1, field))
+			}
+
+			b = append(b, decoded...)
+		}
+	}
+
+	return b
+}
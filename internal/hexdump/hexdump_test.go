@@ -0,0 +1,39 @@
+package hexdump
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFromHex(t *testing.T) {
+	dump := `
+# snmpget -v2c -c public localhost sysUpTime.0
+	0x0000:  3026 0201 0104 0670 7562 6c69 63a0 1902
+	0x0010:  0101 0201 0002 0100 300e 300c 0608 2b06
+	0x0020:  0102 0101 0300 0500
+`
+
+	expected := []byte{
+		0x30, 0x26, 0x02, 0x01, 0x01, 0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0xa0, 0x19, 0x02,
+		0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06,
+		0x01, 0x02, 0x01, 0x01, 0x03, 0x00, 0x05, 0x00,
+	}
+
+	if b := FromHex(dump); !bytes.Equal(b, expected) {
+		t.Errorf("expected %x, got %x", expected, b)
+	}
+
+	if b := FromHex("00000000: 30 03 02 01 01\n"); !bytes.Equal(b, []byte{0x30, 0x03, 0x02, 0x01, 0x01}) {
+		t.Errorf("unexpected xxd bytes %x", b)
+	}
+}
+
+func TestFromHexInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an odd-length field")
+		}
+	}()
+
+	FromHex("0x0000:  3026 020")
+}
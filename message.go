@@ -0,0 +1,718 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SNMP versions, as carried in the version field of a Message.
+const (
+	Version1  = 0
+	Version2c = 1
+	Version3  = 3
+)
+
+// SNMPv3 message flags.
+const (
+	FlagAuth       = 0x01
+	FlagPriv       = 0x02
+	FlagReportable = 0x04
+)
+
+// usmSecurityModel is the msgSecurityModel of the user-based security model.
+const usmSecurityModel = 3
+
+// defaultMaxSize is the msgMaxSize of SNMPv3 messages.
+const defaultMaxSize = 65507
+
+// USMSecurityParameters represents the user-based security model
+// parameters of an SNMPv3 message.
+type USMSecurityParameters struct {
+	AuthoritativeEngineID    []byte
+	AuthoritativeEngineBoots int
+	AuthoritativeEngineTime  int
+	UserName                 string
+	AuthenticationParameters []byte
+	PrivacyParameters        []byte
+}
+
+// Encode encodes USMSecurityParameters as a SEQUENCE.
+func (p USMSecurityParameters) Encode() ([]byte, error) {
+	return Sequence{
+		String(p.AuthoritativeEngineID),
+		Int(p.AuthoritativeEngineBoots),
+		Int(p.AuthoritativeEngineTime),
+		String(p.UserName),
+		String(p.AuthenticationParameters),
+		String(p.PrivacyParameters),
+	}.Encode()
+}
+
+// decodeUSMSecurityParameters decodes the msgSecurityParameters
+// of an SNMPv3 message.
+func decodeUSMSecurityParameters(b []byte) (USMSecurityParameters, error) {
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		return USMSecurityParameters{}, err
+	}
+
+	seq, ok := decoded.(Sequence)
+	if !ok || len(seq) != 6 {
+		return USMSecurityParameters{}, ErrDecodingType
+	}
+
+	engineID, ok1 := seq[0].(String)
+	boots, ok2 := seq[1].(Int)
+	engineTime, ok3 := seq[2].(Int)
+	userName, ok4 := seq[3].(String)
+	authParams, ok5 := seq[4].(String)
+	privParams, ok6 := seq[5].(String)
+
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 {
+		return USMSecurityParameters{}, ErrDecodingType
+	}
+
+	return USMSecurityParameters{
+		AuthoritativeEngineID:    []byte(engineID),
+		AuthoritativeEngineBoots: int(boots),
+		AuthoritativeEngineTime:  int(engineTime),
+		UserName:                 string(userName),
+		AuthenticationParameters: []byte(authParams),
+		PrivacyParameters:        []byte(privParams),
+	}, nil
+}
+
+var (
+	ErrUnsupportedVersion = errors.New("snmp: unsupported version")
+	ErrNotCanonical       = errors.New("snmp: message not in canonical form")
+)
+
+// Message represents an SNMP message.
+type Message struct {
+	Version int
+
+	// Community is only used by SNMPv1 and SNMPv2c messages.
+	Community string
+
+	// PDU is one of the PDU types, such as GetRequest or GetResponse.
+	PDU DataType
+
+	// The remaining fields are only used by SNMPv3 messages.
+	MessageID       int
+	MaxSize         int
+	Flags           byte
+	Security        USMSecurityParameters
+	ContextEngineID []byte
+	ContextName     string
+
+	// EncryptedPDU is the encrypted scopedPDU of a message with
+	// FlagPriv set, in which case PDU is nil.
+	EncryptedPDU []byte
+}
+
+// Encode encodes a Message with the proper header.
+// SNMPv3 messages with FlagAuth set are encoded with zeroed
+// authentication parameters, which must then be authenticated.
+func (m Message) Encode() ([]byte, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	if m.Version == Version3 {
+		return m.encodeV3(false)
+	}
+
+	return Sequence{
+		Int(m.Version),
+		String(m.Community),
+		m.PDU,
+	}.Encode()
+}
+
+// WriteTo encodes m and writes it to w, which can be a stream transport
+// such as TCP, as ReadMessageFrom reads it. It returns the number of
+// bytes written and an error.
+func (m Message) WriteTo(w io.Writer) (int64, error) {
+	b, err := m.Encode()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// CanonicalEncode encodes m like Encode, but keeps the authentication
+// parameters of SNMPv3 messages, so that a decoded Message re-encodes
+// to the bytes it was decoded from if they were in canonical form:
+// definite lengths in the fewest bytes, and integers in the fewest
+// octets.
+func (m Message) CanonicalEncode() ([]byte, error) {
+	if m.Version == Version3 {
+		return m.encodeV3(true)
+	}
+
+	return m.Encode()
+}
+
+// VerifyRoundTrip decodes packet and checks that it re-encodes to the
+// same bytes with CanonicalEncode. If it doesn't, the error wraps
+// ErrNotCanonical and reports the first offset that differs. Packets
+// may decode without being canonical, such as those using the long
+// form for short lengths or integers with extra leading octets.
+func VerifyRoundTrip(packet []byte) error {
+	m, err := Unmarshal(packet)
+	if err != nil {
+		return err
+	}
+
+	b, err := m.CanonicalEncode()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(b) || i < len(packet); i++ {
+		switch {
+		case i == len(b):
+			return fmt.Errorf("%w: re-encoded message ends at offset %d of %d", ErrNotCanonical, i, len(packet))
+		case i == len(packet):
+			return fmt.Errorf("%w: re-encoded message is %d bytes, not %d", ErrNotCanonical, len(b), len(packet))
+		case b[i] != packet[i]:
+			return fmt.Errorf("%w: offset %d is 0x%02x, re-encoded as 0x%02x", ErrNotCanonical, i, packet[i], b[i])
+		}
+	}
+
+	return nil
+}
+
+// encodeV3 encodes an SNMPv3 Message, keeping its authentication
+// parameters if keepAuth is set rather than zeroing them.
+func (m Message) encodeV3(keepAuth bool) ([]byte, error) {
+	security := m.Security
+	if m.Flags&FlagAuth != 0 && !keepAuth {
+		security.AuthenticationParameters = make([]byte, authParamsLength)
+	}
+
+	securityParameters, err := security.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := m.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultMaxSize
+	}
+
+	var data DataType = String(m.EncryptedPDU)
+	if m.Flags&FlagPriv == 0 {
+		data = Sequence{
+			String(m.ContextEngineID),
+			String(m.ContextName),
+			m.PDU,
+		}
+	}
+
+	return Sequence{
+		Int(m.Version),
+		Sequence{
+			Int(m.MessageID),
+			Int(maxSize),
+			String([]byte{m.Flags}),
+			Int(usmSecurityModel),
+		},
+		String(securityParameters),
+		data,
+	}.Encode()
+}
+
+// authParamsOffset returns the offset of the authentication
+// parameters in an encoded SNMPv3 message.
+func authParamsOffset(packet []byte) (int, error) {
+	r := bytes.NewReader(packet)
+	offset := 0
+
+	// enter walks into the contents of a TLV, and skip skips over one.
+	enter := func() (int, error) {
+		_, length, n, err := decodeHeader(r)
+		offset += n
+		return length, err
+	}
+
+	skip := func() error {
+		length, err := enter()
+		if err != nil {
+			return err
+		}
+
+		if length > r.Len() {
+			return io.ErrUnexpectedEOF
+		}
+
+		offset += length
+		_, err = r.Seek(int64(length), io.SeekCurrent)
+		return err
+	}
+
+	// The message SEQUENCE, then msgVersion and msgGlobalData
+	if _, err := enter(); err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := skip(); err != nil {
+			return 0, err
+		}
+	}
+
+	// msgSecurityParameters and its SEQUENCE, then the engine ID,
+	// boots, time, and user name
+	for i := 0; i < 2; i++ {
+		if _, err := enter(); err != nil {
+			return 0, err
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := skip(); err != nil {
+			return 0, err
+		}
+	}
+
+	length, err := enter()
+	if err != nil {
+		return 0, err
+	}
+
+	if length != authParamsLength {
+		return 0, ErrAuthenticationFailed
+	}
+
+	return offset, nil
+}
+
+// validate checks that the Message version is supported and
+// that SNMPv1 messages don't carry SNMPv2-only PDUs or values.
+func (m Message) validate() error {
+	if m.Version != Version1 && m.Version != Version2c && m.Version != Version3 {
+		return ErrUnsupportedVersion
+	}
+
+	if m.Version == Version3 && m.Flags&FlagPriv != 0 {
+		if m.Flags&FlagAuth == 0 {
+			return errors.New("snmp: privacy requires authentication")
+		}
+
+		return nil
+	}
+
+	if m.PDU == nil {
+		return errors.New("snmp: Message has no PDU")
+	}
+
+	if m.Version != Version1 {
+		if _, ok := m.PDU.(TrapV1); ok {
+			return errors.New("snmp: TrapV1 is only valid in SNMPv1")
+		}
+
+		return nil
+	}
+
+	switch m.PDU.(type) {
+	case GetBulkRequest:
+		return errors.New("snmp: GetBulkRequest is not valid in SNMPv1")
+	case TrapV2:
+		return errors.New("snmp: TrapV2 is not valid in SNMPv1")
+	case InformRequest:
+		return errors.New("snmp: InformRequest is not valid in SNMPv1")
+	}
+
+	var varbinds []Varbind
+
+	switch p := m.PDU.(type) {
+	case TrapV1:
+		varbinds = p.Varbinds
+	case interface{ Varbinds() []Varbind }:
+		varbinds = p.Varbinds()
+	}
+
+	for _, v := range varbinds {
+		switch v.value.(type) {
+		case Counter64:
+			return fmt.Errorf("snmp: Counter64 value for %v is not valid in SNMPv1", v.OID)
+		}
+
+		if v.IsException() {
+			return fmt.Errorf("snmp: %v value for %v is not valid in SNMPv1", v.value, v.OID)
+		}
+	}
+
+	return nil
+}
+
+// EncodeOptions controls how messages are encoded.
+// The zero value encodes in the standard form.
+type EncodeOptions struct {
+	// IntegerWidth, if 4 or 8, pads the INTEGER, Counter32, and Gauge32
+	// values of variable bindings to that many bytes rather than the
+	// fewest, for agents that reject the minimal form. Values that
+	// need more bytes are encoded in the fewest that hold them.
+	IntegerWidth int
+}
+
+// Encode encodes m like Message.Encode, using the options of o.
+func (o EncodeOptions) Encode(m Message) ([]byte, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	m.PDU = o.pdu(m.PDU)
+
+	return m.Encode()
+}
+
+// validate returns an error if o isn't supported.
+func (o EncodeOptions) validate() error {
+	if o.IntegerWidth != 0 && o.IntegerWidth != 4 && o.IntegerWidth != 8 {
+		return fmt.Errorf("snmp: unsupported integer width %d", o.IntegerWidth)
+	}
+
+	return nil
+}
+
+// pdu returns pdu with the values of its variable bindings
+// replaced by ones that encode as o requires.
+func (o EncodeOptions) pdu(pdu DataType) DataType {
+	if o.IntegerWidth == 0 {
+		return pdu
+	}
+
+	switch p := pdu.(type) {
+	case GetRequest:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case GetNextRequest:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case GetBulkRequest:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case SetRequest:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case GetResponse:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case InformRequest:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case TrapV2:
+		p.PDU = o.varbinds(p.PDU)
+		return p
+	case TrapV1:
+		p.Varbinds = o.values(p.Varbinds)
+		return p
+	}
+
+	return pdu
+}
+
+// varbinds returns a copy of p with the values of its
+// variable bindings replaced as pdu does.
+func (o EncodeOptions) varbinds(p PDU) PDU {
+	return newPDU(p.requestID, p.err, p.errIndex, o.values(p.varbinds))
+}
+
+// values returns a copy of varbinds with their integer values
+// replaced by ones padded to o.IntegerWidth.
+func (o EncodeOptions) values(varbinds []Varbind) []Varbind {
+	replaced := make([]Varbind, len(varbinds))
+
+	for i, v := range varbinds {
+		switch value := v.value.(type) {
+		case Int:
+			v.value = paddedInteger{TypeInteger, encodeInteger(int(value)), o.IntegerWidth}
+		case Counter:
+			v.value = paddedInteger{TypeCounter, encodeUnsigned(uint64(value)), o.IntegerWidth}
+		case Gauge:
+			v.value = paddedInteger{TypeGauge, encodeUnsigned(uint64(value)), o.IntegerWidth}
+		}
+
+		replaced[i] = v
+	}
+
+	return replaced
+}
+
+// DecodeOptions controls how messages are decoded.
+// The zero value decodes strictly.
+type DecodeOptions struct {
+	// ContinueOnVarbindError keeps decoding the variable bindings of
+	// a PDU after one of them fails to decode. The message is returned
+	// with the bindings that decoded, along with a VarbindErrors.
+	ContinueOnVarbindError bool
+
+	// LenientFirstArc decodes the first sub-identifier of each OID,
+	// which holds its first two arcs, as arc0 = x/40 and arc1 = x%40
+	// for any x, the way some noncompliant agents encode it, rather
+	// than as X.690 does, where arcs below 2 can't exceed 39 and any
+	// larger x is 2.(x-80).
+	LenientFirstArc bool
+
+	// MaxVarbinds, if positive, bounds the number of variable bindings
+	// decoded from a PDU. A PDU with more fails with an error wrapping
+	// ErrTooManyBindings before the others are decoded.
+	MaxVarbinds int
+
+	// RejectTrailingData makes Unmarshal fail if data continues past
+	// the end of the message, rather than ignoring the rest, such as
+	// padding some capture tools leave in the UDP payload.
+	RejectTrailingData bool
+}
+
+// Unmarshal decodes a Message from data, delimited by the length of its
+// outer SEQUENCE. Any bytes after the message are ignored. It returns an
+// error wrapping ErrTruncated if data holds fewer bytes than the message.
+func Unmarshal(data []byte) (*Message, error) {
+	return DecodeOptions{}.Unmarshal(data)
+}
+
+// Unmarshal is like the Unmarshal function, using the options of o.
+func (o DecodeOptions) Unmarshal(data []byte) (*Message, error) {
+	r := bytes.NewReader(data)
+
+	m, err := o.DecodeMessage(r)
+	if m == nil {
+		return nil, err
+	}
+
+	if o.RejectTrailingData && r.Len() > 0 {
+		return nil, fmt.Errorf("snmp: %d bytes of trailing data after message", r.Len())
+	}
+
+	return m, err
+}
+
+// DecodeMessage reads and decodes a single Message from r. It returns
+// an error wrapping ErrTruncated if r ends before the message does.
+func DecodeMessage(r io.Reader) (*Message, error) {
+	return DecodeOptions{}.DecodeMessage(r)
+}
+
+// DecodeMessage is like the DecodeMessage function, using the options
+// of o. If o.ContinueOnVarbindError is set and some bindings fail to
+// decode, both the Message and a VarbindErrors are returned.
+func (o DecodeOptions) DecodeMessage(r io.Reader) (*Message, error) {
+	m, _, err := o.readMessage(r)
+	return m, err
+}
+
+// ReadMessageFrom reads and decodes a single Message from a stream
+// transport such as TCP, where the length of the outer SEQUENCE of each
+// message delimits it. It returns the Message, the number of bytes read,
+// and an error wrapping ErrTruncated if r ends before the message does.
+func ReadMessageFrom(r io.Reader) (*Message, int64, error) {
+	m, n, err := DecodeOptions{}.readMessage(r)
+	return m, int64(n), err
+}
+
+// readMessage reads and decodes a single Message from r.
+// It returns the Message, the number of bytes read, and an error.
+func (o DecodeOptions) readMessage(r io.Reader) (*Message, int, error) {
+	packet, n, err := readPacket(r)
+	if err != nil {
+		return nil, n, err
+	}
+
+	// Trailing data is only checked by Unmarshal
+	o.RejectTrailingData = false
+
+	if o == (DecodeOptions{}) {
+		m, _, err := decodeMessage(bytes.NewReader(packet))
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, len(packet), fmt.Errorf("%w: a value overruns its enclosing message", ErrTruncated)
+		}
+
+		return m, len(packet), err
+	}
+
+	lr := &lenientReader{Reader: bytes.NewReader(packet), opts: o}
+
+	m, _, err := decodeMessage(lr)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, len(packet), fmt.Errorf("%w: a value overruns its enclosing message", ErrTruncated)
+	}
+
+	if err != nil || len(lr.errs) == 0 {
+		return m, len(packet), err
+	}
+
+	return m, len(packet), lr.errs
+}
+
+// readPacket reads the bytes of a single message from r, delimited by
+// the length of its outer SEQUENCE. It returns the bytes, the number of
+// bytes read, and an error wrapping ErrTruncated if r ends before the
+// message does.
+func readPacket(r io.Reader) ([]byte, int, error) {
+	header := &bytes.Buffer{}
+
+	_, length, n, err := decodeHeader(io.TeeReader(r, header))
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, n, fmt.Errorf("%w: incomplete message header of %d bytes", ErrTruncated, n)
+		}
+
+		return nil, n, err
+	}
+
+	content, read, err := readContent(length, r)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, n + read, fmt.Errorf("%w: expected %d bytes, %d available", ErrTruncated, n+length, n+read)
+		}
+
+		return nil, n + read, err
+	}
+
+	return append(header.Bytes(), content...), n + read, nil
+}
+
+// decodeMessage decodes a Message from r.
+// It returns the Message, the number of bytes read, and an error.
+func decodeMessage(r io.Reader) (*Message, int, error) {
+	decoded, bytesRead, err := decode(r)
+	if err != nil {
+		return nil, bytesRead, err
+	}
+
+	seq, ok := decoded.(Sequence)
+	if !ok || len(seq) < 1 {
+		return nil, bytesRead, ErrDecodingType
+	}
+
+	version, ok := seq[0].(Int)
+	if !ok {
+		return nil, bytesRead, ErrDecodingType
+	}
+
+	var m *Message
+
+	if version == Version3 {
+		m, err = messageV3FromSequence(seq)
+	} else {
+		m, err = messageFromSequence(seq)
+	}
+
+	if err != nil {
+		return nil, bytesRead, err
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, bytesRead, err
+	}
+
+	return m, bytesRead, nil
+}
+
+// messageFromSequence converts a decoded SEQUENCE into
+// an SNMPv1 or SNMPv2c Message.
+func messageFromSequence(seq Sequence) (*Message, error) {
+	if len(seq) != 3 {
+		return nil, ErrDecodingType
+	}
+
+	community, ok := seq[1].(String)
+	if !ok {
+		return nil, ErrDecodingType
+	}
+
+	return &Message{
+		Version:   int(seq[0].(Int)),
+		Community: string(community),
+		PDU:       seq[2],
+	}, nil
+}
+
+// messageV3FromSequence converts a decoded SEQUENCE into an SNMPv3 Message.
+func messageV3FromSequence(seq Sequence) (*Message, error) {
+	if len(seq) != 4 {
+		return nil, ErrDecodingType
+	}
+
+	globalData, ok := seq[1].(Sequence)
+	if !ok || len(globalData) != 4 {
+		return nil, ErrDecodingType
+	}
+
+	msgID, ok1 := globalData[0].(Int)
+	maxSize, ok2 := globalData[1].(Int)
+	flags, ok3 := globalData[2].(String)
+	securityModel, ok4 := globalData[3].(Int)
+
+	if !ok1 || !ok2 || !ok3 || !ok4 || len(flags) != 1 {
+		return nil, ErrDecodingType
+	}
+
+	if securityModel != usmSecurityModel {
+		return nil, errors.New("snmp: unsupported security model")
+	}
+
+	securityParameters, ok := seq[2].(String)
+	if !ok {
+		return nil, ErrDecodingType
+	}
+
+	security, err := decodeUSMSecurityParameters([]byte(securityParameters))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		Version:   Version3,
+		MessageID: int(msgID),
+		MaxSize:   int(maxSize),
+		Flags:     flags[0],
+		Security:  security,
+	}
+
+	switch data := seq[3].(type) {
+	case String:
+		if m.Flags&FlagPriv == 0 {
+			return nil, ErrDecodingType
+		}
+
+		m.EncryptedPDU = []byte(data)
+
+	case Sequence:
+		if err := m.setScopedPDU(data); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, ErrDecodingType
+	}
+
+	return m, nil
+}
+
+// setScopedPDU sets the context and PDU of m from a decoded scopedPDU.
+func (m *Message) setScopedPDU(scopedPDU Sequence) error {
+	if len(scopedPDU) != 3 {
+		return ErrDecodingType
+	}
+
+	contextEngineID, ok1 := scopedPDU[0].(String)
+	contextName, ok2 := scopedPDU[1].(String)
+
+	if !ok1 || !ok2 {
+		return ErrDecodingType
+	}
+
+	m.ContextEngineID = []byte(contextEngineID)
+	m.ContextName = string(contextName)
+	m.PDU = scopedPDU[2]
+
+	return nil
+}
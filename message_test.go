@@ -0,0 +1,629 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/PreetamJinka/snmp/internal/hexdump"
+)
+
+func TestMessageEncoding(t *testing.T) {
+	m := Message{
+		Version:   Version2c,
+		Community: "public",
+		PDU:       NewGetRequest(1, MustParseOID(".1.3.6.1.2.1.1.1.0")),
+	}
+
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SNMPv2c GetRequest for sysDescr.0 with community "public"
+	expected := []byte{
+		0x30, 0x26,
+		0x02, 0x01, 0x01,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x19,
+		0x02, 0x01, 0x01,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x0e,
+		0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+		0x05, 0x00,
+	}
+
+	if !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+
+	decoded, n, err := decodeMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(b) {
+		t.Errorf("expected %d bytes read, got %d", len(b), n)
+	}
+
+	if decoded.Version != Version2c || decoded.Community != "public" {
+		t.Errorf("expected version %d and community %q, got %d and %q",
+			Version2c, "public", decoded.Version, decoded.Community)
+	}
+
+	if _, ok := decoded.PDU.(GetRequest); !ok {
+		t.Errorf("expected a GetRequest PDU, got %T", decoded.PDU)
+	}
+}
+
+func TestMessageValidation(t *testing.T) {
+	oid := MustParseOID(".1.3.6.1.2.1.31.1.1.1.6.1")
+
+	cases := []Message{
+		{Version: 2, Community: "public", PDU: NewGetRequest(1, oid)},
+		{Version: Version2c, Community: "public"},
+		{Version: Version1, Community: "public", PDU: NewGetBulkRequest(1, 0, 10, oid)},
+		{Version: Version1, Community: "public", PDU: GetResponse{
+			PDU: newPDU(1, 0, 0, []Varbind{NewVarbind(oid, Counter64(1))}),
+		}},
+		{Version: Version1, Community: "public", PDU: GetResponse{
+			PDU: newPDU(1, 0, 0, []Varbind{NewVarbind(oid, EndOfMIBView)}),
+		}},
+	}
+
+	for _, m := range cases {
+		if _, err := m.Encode(); err == nil {
+			t.Errorf("expected an error encoding %+v", m)
+		}
+	}
+
+	// Counter64 values are fine in SNMPv2c
+	m := Message{Version: Version2c, Community: "public", PDU: GetResponse{
+		PDU: newPDU(1, 0, 0, []Varbind{NewVarbind(oid, Counter64(1))}),
+	}}
+
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// but are rejected when decoding an SNMPv1 message
+	b[4] = Version1
+	if _, _, err := decodeMessage(bytes.NewReader(b)); err == nil {
+		t.Error("expected an error decoding an SNMPv1 message with a Counter64 value")
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	// SNMPv1 GetResponse from community "public" for sysUpTime.0
+	v1 := []byte{
+		0x30, 0x2a,
+		0x02, 0x01, 0x00,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa2, 0x1d,
+		0x02, 0x04, 0x1d, 0x0e, 0x5a, 0x74,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x0f,
+		0x30, 0x0d,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00,
+		0x43, 0x01, 0x2a,
+	}
+
+	// SNMPv2c GetResponse from community "private" for ifInOctets.1
+	// and an endOfMibView
+	v2c := []byte{
+		0x30, 0x3e,
+		0x02, 0x01, 0x01,
+		0x04, 0x07, 'p', 'r', 'i', 'v', 'a', 't', 'e',
+		0xa2, 0x30,
+		0x02, 0x01, 0x05,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x25,
+		0x30, 0x12,
+		0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x0a, 0x01,
+		0x41, 0x04, 0x00, 0x98, 0x96, 0x80,
+		0x30, 0x0f,
+		0x06, 0x0b, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x0a, 0x82, 0x00,
+		0x82, 0x00,
+	}
+
+	m, err := Unmarshal(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := m.PDU.(GetResponse)
+	if m.Version != Version1 || m.Community != "public" || !ok || res.RequestID() != 0x1d0e5a74 {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	if v := res.Varbinds(); len(v) != 1 || !v[0].OID.Equal(sysUpTime) || v[0].Value() != TimeTicks(42) {
+		t.Errorf("unexpected varbinds %v", v)
+	}
+
+	m, err = Unmarshal(v2c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok = m.PDU.(GetResponse)
+	if m.Version != Version2c || m.Community != "private" || !ok {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	v := res.Varbinds()
+	if len(v) != 2 || v[0].Value() != Counter(10000000) || v[1].Value() != EndOfMIBView {
+		t.Errorf("unexpected varbinds %v", v)
+	}
+
+	// Padding after the message is ignored unless rejected
+	if m, err := Unmarshal(append(v2c, 0, 0, 0, 0)); err != nil || m.Community != "private" {
+		t.Errorf("expected the padded message to decode, got %+v and %v", m, err)
+	}
+
+	if _, err := (DecodeOptions{RejectTrailingData: true}).Unmarshal(append(v2c, 0)); err == nil {
+		t.Error("expected an error for trailing data")
+	}
+
+	// Streams hold one message after another
+	r := bytes.NewReader(append(append([]byte(nil), v1...), v2c...))
+	for _, version := range []int{Version1, Version2c} {
+		m, err := DecodeMessage(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Version != version {
+			t.Errorf("expected version %d, got %d", version, m.Version)
+		}
+	}
+}
+
+func TestUnmarshalCapture(t *testing.T) {
+	// snmpget -v2c -c public localhost sysUpTime.0
+	m, err := Unmarshal(hexdump.FromHex(`
+	0x0000:  3026 0201 0104 0670 7562 6c69 63a0 1902
+	0x0010:  0101 0201 0002 0100 300e 300c 0608 2b06
+	0x0020:  0102 0101 0300 0500
+	`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, ok := m.PDU.(GetRequest)
+	if !ok || m.Version != Version2c || m.Community != "public" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	if v := req.Varbinds(); len(v) != 1 || !v[0].OID.Equal(sysUpTime) {
+		t.Errorf("unexpected varbinds %v", v)
+	}
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	b, err := Message{
+		Version:   Version2c,
+		Community: "public",
+		PDU:       NewGetRequest(1, sysDescr),
+	}.Encode()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Unmarshal(b[:20])
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+
+	if expected := "snmp: truncated data: expected 40 bytes, 20 available"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	if _, err := Unmarshal(b[:1]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated for a partial header, got %v", err)
+	}
+
+	// An inner length that overruns the message
+	overrun := append([]byte(nil), b...)
+	overrun[14]++
+	if _, err := Unmarshal(overrun); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated for an overrun, got %v", err)
+	}
+}
+
+func TestUnmarshalLenientFirstArc(t *testing.T) {
+	// SNMPv2c GetResponse with one binding, whose OID begins
+	// with the sub-identifier 120 an agent meant as 3.0
+	b := []byte{
+		0x30, 0x20,
+		0x02, 0x01, 0x01,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa2, 0x13,
+		0x02, 0x01, 0x05,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x08,
+		0x30, 0x06,
+		0x06, 0x02, 0x78, 0x01,
+		0x05, 0x00,
+	}
+
+	for _, c := range []struct {
+		opts     DecodeOptions
+		expected ObjectIdentifier
+	}{
+		{DecodeOptions{}, ObjectIdentifier{2, 40, 1}},
+		{DecodeOptions{LenientFirstArc: true}, ObjectIdentifier{3, 0, 1}},
+		{DecodeOptions{LenientFirstArc: true, ContinueOnVarbindError: true}, ObjectIdentifier{3, 0, 1}},
+	} {
+		m, err := c.opts.Unmarshal(b)
+		if err != nil {
+			t.Fatalf("%+v: %v", c.opts, err)
+		}
+
+		varbinds := m.PDU.(GetResponse).Varbinds()
+		if len(varbinds) != 1 || !varbinds[0].OID.Equal(c.expected) {
+			t.Errorf("%+v: expected %v, got %v", c.opts, c.expected, varbinds)
+		}
+	}
+}
+
+func TestUnmarshalContinueOnVarbindError(t *testing.T) {
+	// SNMPv2c GetResponse for ifInOctets.1-3, where the Counter
+	// of the second binding claims five bytes but has four
+	b := []byte{
+		0x30, 0x54,
+		0x02, 0x01, 0x01,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa2, 0x47,
+		0x02, 0x01, 0x05,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x3c,
+		0x30, 0x12,
+		0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x0a, 0x01,
+		0x41, 0x04, 0x00, 0x98, 0x96, 0x80,
+		0x30, 0x12,
+		0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x0a, 0x02,
+		0x41, 0x05, 0x00, 0x98, 0x96, 0x80,
+		0x30, 0x12,
+		0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x0a, 0x03,
+		0x41, 0x04, 0x00, 0x98, 0x96, 0x81,
+	}
+
+	if m, err := Unmarshal(b); err == nil {
+		t.Fatalf("expected an error decoding strictly, got %+v", m)
+	}
+
+	m, err := DecodeOptions{ContinueOnVarbindError: true}.Unmarshal(b)
+	if m == nil {
+		t.Fatal(err)
+	}
+
+	var errs VarbindErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 2 {
+		t.Fatalf("expected an error for binding 2, got %v", err)
+	}
+
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected the error to wrap ErrTruncated, got %v", err)
+	}
+
+	res, ok := m.PDU.(GetResponse)
+	if !ok || res.RequestID() != 5 {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	v := res.Varbinds()
+	if len(v) != 2 || v[0].Value() != Counter(10000000) || v[1].Value() != Counter(10000001) {
+		t.Errorf("unexpected varbinds %v", v)
+	}
+
+	if !v[1].OID.Equal(MustParseOID(".1.3.6.1.2.1.2.2.1.10.3")) {
+		t.Errorf("unexpected OID %v", v[1].OID)
+	}
+}
+
+func TestUnmarshalMaxVarbinds(t *testing.T) {
+	varbinds := make([]Varbind, 1000)
+	for i := range varbinds {
+		varbinds[i] = NewVarbind(sysDescr.Child(uint32(i)), Int(i))
+	}
+
+	b, err := Message{Version: Version2c, Community: "public", PDU: GetResponse{PDU: newPDU(1, 0, 0, varbinds)}}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (DecodeOptions{MaxVarbinds: 10}).Unmarshal(b); !errors.Is(err, ErrTooManyBindings) {
+		t.Errorf("expected ErrTooManyBindings, got %v", err)
+	}
+
+	m, err := DecodeOptions{MaxVarbinds: 1000}.Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(m.PDU.(GetResponse).Varbinds()); n != 1000 {
+		t.Errorf("expected 1000 varbinds, got %d", n)
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	b, err := Message{
+		Version:   Version2c,
+		Community: "public",
+		PDU: GetResponse{PDU: newPDU(7, 0, 0, []Varbind{
+			NewVarbind(sysDescr, String("test agent")),
+			NewVarbind(sysUpTime, TimeTicks(42)),
+		})},
+	}.Encode()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every truncation of a message fails
+	for n := 0; n < len(b); n++ {
+		if _, err := Unmarshal(b[:n]); err == nil {
+			t.Errorf("expected an error for %d of %d bytes", n, len(b))
+		}
+	}
+
+	// Inflating or replacing any length byte must not panic
+	for i := range b {
+		for _, c := range []byte{0x00, 0x7f, 0x80, 0x84, 0xff} {
+			inflated := append([]byte(nil), b...)
+			inflated[i] = c
+			Unmarshal(inflated)
+
+			long := append(append(append([]byte(nil), b[:i]...), 0x84, 0x7f, 0xff, 0xff, 0xff), b[i+1:]...)
+			Unmarshal(long)
+		}
+	}
+
+	for _, test := range []struct {
+		b        []byte
+		expected error
+	}{
+		// An OCTET STRING claiming 2GB inside a short message
+		{[]byte{0x30, 0x0d, 0x02, 0x01, 0x01, 0x04, 0x84, 0x7f, 0xff, 0xff, 0xff, 'p', 'u', 'b', 'l'}, ErrTruncated},
+		// BER indefinite length
+		{[]byte{0x30, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00}, ErrInvalidLength},
+		{[]byte{0x30, 0x07, 0x02, 0x01, 0x01, 0x30, 0x80, 0x00, 0x00}, ErrInvalidLength},
+		// A SEQUENCE whose elements overrun its length
+		{[]byte{0x30, 0x08, 0x30, 0x01, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01}, ErrInvalidLength},
+	} {
+		if _, err := Unmarshal(test.b); !errors.Is(err, test.expected) {
+			t.Errorf("%x: expected %v, got %v", test.b, test.expected, err)
+		}
+	}
+
+	// Readers that don't report their size are read as data arrives
+	// instead of allocating the claimed length up front
+	r := io.MultiReader(bytes.NewReader([]byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff}), bytes.NewReader(b))
+	if _, err := DecodeMessage(r); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated for a stream shorter than its length, got %v", err)
+	}
+}
+
+// captures are packets captured from Net-SNMP tools and agents.
+var captures = []string{
+	// snmpget -v1 -c public localhost sysUpTime.0 and its response
+	`
+	0x0000:  3026 0201 0004 0670 7562 6c69 63a0 1902
+	0x0010:  0101 0201 0002 0100 300e 300c 0608 2b06
+	0x0020:  0102 0101 0300 0500
+	`,
+	`
+	0x0000:  3027 0201 0004 0670 7562 6c69 63a2 1a02
+	0x0010:  0101 0201 0002 0100 300f 300d 0608 2b06
+	0x0020:  0102 0101 0300 4301 2a
+	`,
+
+	// snmpget -v2c -c public localhost sysUpTime.0
+	`
+	0x0000:  3026 0201 0104 0670 7562 6c69 63a0 1902
+	0x0010:  0101 0201 0002 0100 300e 300c 0608 2b06
+	0x0020:  0102 0101 0300 0500
+	`,
+
+	// The response to snmpgetnext -v2c -c public localhost
+	// ifInOctets.256, an endOfMibView
+	`
+	0x0000:  3029 0201 0104 0670 7562 6c69 63a2 1c02
+	0x0010:  0102 0201 0002 0100 3011 300f 060b 2b06
+	0x0020:  0102 0102 0201 0a82 0082 00
+	`,
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	for i, capture := range captures {
+		if err := VerifyRoundTrip(hexdump.FromHex(capture)); err != nil {
+			t.Errorf("capture %d: %v", i, err)
+		}
+	}
+
+	// Authenticated SNMPv3 messages keep their authentication parameters
+	key := []byte("0123456789abcdef0123")
+	packet, err := Message{
+		Version:   Version3,
+		MessageID: 9,
+		Flags:     FlagAuth | FlagReportable,
+		Security: USMSecurityParameters{
+			AuthoritativeEngineID:    []byte("engine"),
+			AuthoritativeEngineBoots: 1,
+			AuthoritativeEngineTime:  300,
+			UserName:                 "admin",
+		},
+		PDU: NewGetRequest(9, sysDescr),
+	}.encodeAuthenticated(SHA, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyRoundTrip(packet); err != nil {
+		t.Errorf("SNMPv3: %v", err)
+	}
+
+	// Agents may send forms that decode but aren't canonical
+	for _, test := range []struct {
+		name   string
+		packet []byte
+		offset string
+	}{
+		{
+			"long form for a short length",
+			[]byte{0x30, 0x81, 0x18, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', 0xa0, 0x0b, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x30, 0x00},
+			"offset 1 ",
+		},
+		{
+			"an integer with a leading zero octet",
+			[]byte{0x30, 0x19, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', 0xa0, 0x0c, 0x02, 0x02, 0x00, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x30, 0x00},
+			"offset 1 ",
+		},
+	} {
+		if _, err := Unmarshal(test.packet); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		err := VerifyRoundTrip(test.packet)
+		if !errors.Is(err, ErrNotCanonical) || !strings.Contains(err.Error(), test.offset) {
+			t.Errorf("%s: expected ErrNotCanonical at %s, got %v", test.name, test.offset, err)
+		}
+	}
+}
+
+func FuzzDecodeMessage(f *testing.F) {
+	for _, capture := range captures {
+		f.Add(hexdump.FromHex(capture))
+	}
+
+	for _, pdu := range []DataType{
+		NewGetBulkRequest(3, 1, 10, sysDescr, ifDescr),
+		NewTrapV2(4, 42, MustParseOID(".1.3.6.1.6.3.1.1.5.3"), NewVarbind(ifDescr.Child(1), String("lo"))),
+	} {
+		b, err := Message{Version: Version2c, Community: "public", PDU: pdu}.Encode()
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Unmarshal(data)
+		if (m == nil) == (err == nil) {
+			t.Fatalf("Unmarshal returned %+v and %v", m, err)
+		}
+
+		m, err = DecodeOptions{ContinueOnVarbindError: true}.Unmarshal(data)
+		if m == nil && err == nil {
+			t.Fatal("Unmarshal returned neither a message nor an error")
+		}
+
+		m, err = DecodeMessage(bytes.NewReader(data))
+		if (m == nil) == (err == nil) {
+			t.Fatalf("DecodeMessage returned %+v and %v", m, err)
+		}
+	})
+}
+
+func TestMessageStream(t *testing.T) {
+	messages := []Message{
+		{Version: Version2c, Community: "public", PDU: NewGetRequest(1, sysDescr)},
+		{Version: Version1, Community: "private", PDU: GetResponse{PDU: newPDU(2, 0, 0, []Varbind{NewVarbind(sysUpTime, TimeTicks(42))})}},
+	}
+
+	r, w := io.Pipe()
+	written := make(chan int64, len(messages))
+
+	go func() {
+		for _, m := range messages {
+			n, err := m.WriteTo(w)
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+
+			written <- n
+		}
+
+		w.Close()
+	}()
+
+	for i, expected := range messages {
+		m, n, err := ReadMessageFrom(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if w := <-written; n != w {
+			t.Errorf("message %d: read %d bytes, but %d were written", i, n, w)
+		}
+
+		if m.Version != expected.Version || m.Community != expected.Community {
+			t.Errorf("message %d: expected %+v, got %+v", i, expected, m)
+		}
+	}
+
+	if _, n, err := ReadMessageFrom(r); !errors.Is(err, ErrTruncated) || n != 0 {
+		t.Errorf("expected ErrTruncated with 0 bytes at the end of the stream, got %v with %d", err, n)
+	}
+}
+
+func TestEncodeOptionsIntegerWidth(t *testing.T) {
+	for _, test := range []struct {
+		value DataType
+		width int
+		tlv   []byte
+	}{
+		{Int(5), 0, []byte{0x02, 0x01, 0x05}},
+		{Int(5), 4, []byte{0x02, 0x04, 0x00, 0x00, 0x00, 0x05}},
+		{Int(5), 8, []byte{0x02, 0x08, 0, 0, 0, 0, 0, 0, 0, 0x05}},
+		{Int(-2), 4, []byte{0x02, 0x04, 0xff, 0xff, 0xff, 0xfe}},
+		{Int(0x123456789), 4, []byte{0x02, 0x05, 0x01, 0x23, 0x45, 0x67, 0x89}},
+		{Counter(200), 4, []byte{0x41, 0x04, 0x00, 0x00, 0x00, 0xc8}},
+		{Gauge(0x80000000), 4, []byte{0x42, 0x05, 0x00, 0x80, 0x00, 0x00, 0x00}},
+		{Gauge(0x80000000), 8, []byte{0x42, 0x08, 0, 0, 0, 0, 0x80, 0, 0, 0}},
+		{TimeTicks(5), 4, []byte{0x43, 0x01, 0x05}},
+	} {
+		m := Message{
+			Version:   Version2c,
+			Community: "private",
+			PDU:       NewSetRequest(7, NewVarbind(sysName, test.value)),
+		}
+
+		b, err := EncodeOptions{IntegerWidth: test.width}.Encode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.HasSuffix(b, test.tlv) {
+			t.Errorf("%v (%T) in %d bytes: expected a value of %x in %x", test.value, test.value, test.width, test.tlv, b)
+		}
+
+		decoded, err := Unmarshal(b)
+		if err != nil {
+			t.Fatalf("%v in %d bytes: %v", test.value, test.width, err)
+		}
+
+		if v := decoded.PDU.(SetRequest).varbinds[0]; v.Value() != test.value {
+			t.Errorf("%v in %d bytes: decoded %v (%T)", test.value, test.width, v.Value(), v.Value())
+		}
+	}
+
+	if _, err := (EncodeOptions{IntegerWidth: 3}).Encode(Message{Version: Version2c, PDU: NewSetRequest(1)}); err == nil {
+		t.Error("expected an error for an integer width of 3")
+	}
+}
@@ -0,0 +1,1056 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MIB is a symbol table of the OBJECT IDENTIFIER assignments of
+// SMI MIB modules. The zero value is an empty MIB ready to load
+// modules into. A MIB is safe for concurrent lookups once loaded.
+type MIB struct {
+	modules map[string]*mibModule
+	order   []*mibModule
+
+	// byOID indexes the resolved nodes by their OID.
+	byOID *mibTrie
+}
+
+// mibTrie is a trie of nodes keyed by the arcs of their OIDs.
+type mibTrie struct {
+	node     *mibNode
+	children map[uint32]*mibTrie
+}
+
+// insert adds node to t under its OID unless a node is already there.
+func (t *mibTrie) insert(node *mibNode) {
+	for _, arc := range node.oid {
+		child, ok := t.children[arc]
+		if !ok {
+			child = &mibTrie{}
+			if t.children == nil {
+				t.children = map[uint32]*mibTrie{}
+			}
+
+			t.children[arc] = child
+		}
+
+		t = child
+	}
+
+	if t.node == nil {
+		t.node = node
+	}
+}
+
+// mibModule is a MIB module and its definitions.
+type mibModule struct {
+	name string
+
+	// imports maps imported symbols to their modules.
+	imports map[string]string
+
+	nodes map[string]*mibNode
+	order []*mibNode
+
+	// types holds the type assignments and textual conventions.
+	types map[string]*mibType
+}
+
+// mibType is a type assignment or TEXTUAL-CONVENTION in a MIB module.
+type mibType struct {
+	syntax mibSyntax
+
+	// hint is the DISPLAY-HINT of a textual convention.
+	hint string
+}
+
+// mibSyntax is a SYNTAX, such as INTEGER, OCTET STRING, or the name
+// of a textual convention. Constraints are not kept.
+type mibSyntax struct {
+	name string
+
+	// enums are the named numbers of an INTEGER or BITS syntax.
+	enums []mibEnum
+}
+
+// mibEnum is a named number, such as up(1).
+type mibEnum struct {
+	name  string
+	value int
+}
+
+// mibNode is an OBJECT IDENTIFIER assignment in a MIB module.
+type mibNode struct {
+	name   string
+	module *mibModule
+
+	// The OID is the OID of parent, if any, followed by arcs.
+	parent string
+	arcs   []uint32
+
+	oid      ObjectIdentifier
+	resolved bool
+
+	// syntax and access are set for OBJECT-TYPEs.
+	syntax mibSyntax
+	access string
+}
+
+// mibRoots are the top-level arcs defined by ASN.1 itself.
+var mibRoots = map[string]uint32{
+	"ccitt":           0,
+	"iso":             1,
+	"joint-iso-ccitt": 2,
+}
+
+// mibMacros are the macros whose values are OBJECT IDENTIFIERs.
+var mibMacros = map[string]bool{
+	"MODULE-IDENTITY":    true,
+	"OBJECT-IDENTITY":    true,
+	"OBJECT-TYPE":        true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+	"TRAP-TYPE":          true,
+}
+
+// mibBaseSyntaxes are the SMI types that values are encoded as,
+// with the tag of each. Textual conventions resolve to these.
+var mibBaseSyntaxes = map[string]byte{
+	"INTEGER":           TypeInteger,
+	"Integer32":         TypeInteger,
+	"Unsigned32":        TypeGauge,
+	"Gauge32":           TypeGauge,
+	"Gauge":             TypeGauge,
+	"Counter32":         TypeCounter,
+	"Counter":           TypeCounter,
+	"Counter64":         TypeCounter64,
+	"TimeTicks":         TypeTimeTicks,
+	"IpAddress":         TypeIpAddress,
+	"OCTET STRING":      TypeString,
+	"BITS":              TypeString,
+	"Opaque":            TypeOpaque,
+	"OBJECT IDENTIFIER": TypeOID,
+}
+
+var (
+	ErrUnknownSymbol = errors.New("snmp: unknown MIB symbol")
+	ErrWrongSyntax   = errors.New("snmp: value doesn't match the SYNTAX of its object")
+)
+
+// LoadFile loads the MIB modules in the file at path.
+func (m *MIB) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return m.Load(f)
+}
+
+// Load loads the MIB modules read from r. Modules may be loaded in
+// any order; symbols imported from modules that aren't loaded yet
+// are resolved once those modules are loaded.
+func (m *MIB) Load(r io.Reader) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := tokenizeMIB(string(src))
+	if err != nil {
+		return err
+	}
+
+	p := &mibParser{tokens: tokens}
+
+	var modules []*mibModule
+	for !p.done() {
+		module, err := p.module()
+		if err != nil {
+			return err
+		}
+
+		modules = append(modules, module)
+	}
+
+	if m.modules == nil {
+		m.modules = map[string]*mibModule{}
+	}
+
+	for i, module := range modules {
+		if _, ok := m.modules[module.name]; ok {
+			return fmt.Errorf("snmp: MIB module %s is already loaded", module.name)
+		}
+
+		for _, other := range modules[:i] {
+			if other.name == module.name {
+				return fmt.Errorf("snmp: MIB module %s is defined twice", module.name)
+			}
+		}
+	}
+
+	for _, module := range modules {
+		m.modules[module.name] = module
+		m.order = append(m.order, module)
+	}
+
+	m.index()
+
+	return nil
+}
+
+// Lookup returns the OID of a symbol, given as a name such as
+// "sysDescr" or qualified with its module as "SNMPv2-MIB::sysDescr".
+// Unqualified names are looked up in the order modules were loaded.
+func (m *MIB) Lookup(name string) (ObjectIdentifier, error) {
+	node := m.node(name)
+	if node == nil || !node.resolved {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSymbol, name)
+	}
+
+	return append(ObjectIdentifier(nil), node.oid...), nil
+}
+
+// node returns the node for a plain or qualified name, or nil.
+func (m *MIB) node(name string) *mibNode {
+	if i := strings.Index(name, "::"); i >= 0 {
+		module, ok := m.modules[name[:i]]
+		if !ok {
+			return nil
+		}
+
+		return module.nodes[name[i+2:]]
+	}
+
+	for _, module := range m.order {
+		if node, ok := module.nodes[name]; ok && node.resolved {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// Name returns the qualified name of the longest known prefix of oid
+// followed by the remaining arcs, such as "SNMPv2-MIB::sysDescr.0".
+// It returns the numeric form of oid if no prefix is known.
+func (m *MIB) Name(oid ObjectIdentifier) string {
+	node, n := m.longestPrefix(oid)
+	if node == nil {
+		return oid.String()
+	}
+
+	name := node.module.name + "::" + node.name
+	if n < len(oid) {
+		name += "." + oid[n:].Dotted()
+	}
+
+	return name
+}
+
+// longestPrefix returns the node for the longest known
+// prefix of oid and the length of the prefix.
+func (m *MIB) longestPrefix(oid ObjectIdentifier) (*mibNode, int) {
+	var (
+		node *mibNode
+		n    int
+	)
+
+	t := m.byOID
+	for i := 0; t != nil && i < len(oid); i++ {
+		if t = t.children[oid[i]]; t != nil && t.node != nil {
+			node, n = t.node, i+1
+		}
+	}
+
+	return node, n
+}
+
+// CheckValue returns an error wrapping ErrWrongSyntax if the value of
+// v can't be encoded as the SYNTAX of its object, such as a String for
+// an INTEGER object. Values of objects with an unknown SYNTAX and
+// exceptions such as Null are accepted.
+func (m *MIB) CheckValue(v Varbind) error {
+	node, _ := m.longestPrefix(v.OID)
+	if node == nil || node.syntax.name == "" {
+		return nil
+	}
+
+	base := m.baseSyntax(node.module, node.syntax.name)
+
+	expected, ok := mibBaseSyntaxes[base]
+	if !ok {
+		return nil
+	}
+
+	value := v.Value()
+	if _, ok := value.(tag); ok || value == nil {
+		return nil
+	}
+
+	b, err := value.Encode()
+	if err != nil {
+		return err
+	}
+
+	if b[0] != expected {
+		return fmt.Errorf("%w: %s::%s is %s, not %T", ErrWrongSyntax, node.module.name, node.name, base, value)
+	}
+
+	return nil
+}
+
+// Render formats the value of v for display using the DISPLAY-HINT of
+// the textual convention of its object, such as "00:1a:2b:3c:4d:5e"
+// for a PhysAddress or "2026-10-14,13:30:15.0,+2:0" for a DateAndTime.
+// Values without a DISPLAY-HINT are formatted with their String method.
+func (m *MIB) Render(v Varbind) string {
+	value := v.Value()
+
+	node, _ := m.longestPrefix(v.OID)
+	if node == nil {
+		return fmt.Sprint(value)
+	}
+
+	if i, ok := value.(Int); ok {
+		if name, ok := m.enumName(node, int(i)); ok {
+			return fmt.Sprintf("%s(%d)", name, i)
+		}
+	}
+
+	hint := m.displayHint(node.module, node.syntax.name)
+	if hint == "" {
+		return fmt.Sprint(value)
+	}
+
+	var (
+		s   string
+		err error
+	)
+
+	switch value := value.(type) {
+	case String:
+		s, err = formatOctetHint(hint, []byte(value))
+	case Int:
+		s, err = formatIntegerHint(hint, int64(value))
+	case Gauge:
+		s, err = formatIntegerHint(hint, int64(value))
+	default:
+		return fmt.Sprint(value)
+	}
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return s
+}
+
+// EnumName returns the label of value in the enumeration of the object
+// of oid, such as "up" for 1 and ifOperStatus. ok is false if the
+// object has no enumeration or value isn't in it.
+func (m *MIB) EnumName(oid ObjectIdentifier, value int) (name string, ok bool) {
+	node, _ := m.longestPrefix(oid)
+	if node == nil {
+		return "", false
+	}
+
+	return m.enumName(node, value)
+}
+
+// EnumValue returns the value of label in the enumeration of the
+// object of oid, such as 2 for "down" and ifOperStatus. It is the
+// inverse of EnumName.
+func (m *MIB) EnumValue(oid ObjectIdentifier, label string) (value int, ok bool) {
+	node, _ := m.longestPrefix(oid)
+	if node == nil {
+		return 0, false
+	}
+
+	for _, enum := range m.enums(node) {
+		if enum.name == label {
+			return enum.value, true
+		}
+	}
+
+	return 0, false
+}
+
+func (m *MIB) enumName(node *mibNode, value int) (string, bool) {
+	for _, enum := range m.enums(node) {
+		if enum.value == value {
+			return enum.name, true
+		}
+	}
+
+	return "", false
+}
+
+// enums returns the named numbers of the SYNTAX of node,
+// following its textual conventions.
+func (m *MIB) enums(node *mibNode) []mibEnum {
+	module, syntax := node.module, node.syntax
+
+	for i := 0; i <= len(m.order); i++ {
+		if len(syntax.enums) > 0 {
+			return syntax.enums
+		}
+
+		name := syntax.name
+		module = m.definingModule(module, name, func(module *mibModule) bool {
+			_, ok := module.types[name]
+			return ok
+		})
+
+		if module == nil {
+			return nil
+		}
+
+		syntax = module.types[name].syntax
+	}
+
+	return nil
+}
+
+// displayHint returns the first DISPLAY-HINT found while following
+// the textual conventions of a SYNTAX name used in module.
+func (m *MIB) displayHint(module *mibModule, name string) string {
+	for i := 0; i <= len(m.order); i++ {
+		module = m.definingModule(module, name, func(module *mibModule) bool {
+			_, ok := module.types[name]
+			return ok
+		})
+
+		if module == nil {
+			return ""
+		}
+
+		typ := module.types[name]
+		if typ.hint != "" {
+			return typ.hint
+		}
+
+		name = typ.syntax.name
+	}
+
+	return ""
+}
+
+// baseSyntax follows the textual conventions and type assignments
+// of a SYNTAX name used in module to its base syntax.
+func (m *MIB) baseSyntax(module *mibModule, name string) string {
+	for i := 0; i <= len(m.order); i++ {
+		if _, ok := mibBaseSyntaxes[name]; ok {
+			return name
+		}
+
+		module = m.definingModule(module, name, func(module *mibModule) bool {
+			_, ok := module.types[name]
+			return ok
+		})
+
+		if module == nil {
+			return ""
+		}
+
+		name = module.types[name].syntax.name
+	}
+
+	return ""
+}
+
+// index resolves every node whose OID is known and indexes them by OID.
+// The first module loaded wins when several define the same OID.
+func (m *MIB) index() {
+	m.byOID = &mibTrie{}
+
+	for _, module := range m.order {
+		for _, node := range module.order {
+			if m.resolve(node, map[*mibNode]bool{}) {
+				m.byOID.insert(node)
+			}
+		}
+	}
+}
+
+// resolve computes the OID of node, returning false if its parent
+// can't be resolved. seen guards against circular definitions.
+func (m *MIB) resolve(node *mibNode, seen map[*mibNode]bool) bool {
+	if node.resolved {
+		return true
+	}
+
+	if seen[node] {
+		return false
+	}
+
+	seen[node] = true
+
+	var oid ObjectIdentifier
+
+	if node.parent != "" {
+		parent := m.symbol(node.module, node.parent)
+
+		switch {
+		case parent != nil:
+			if !m.resolve(parent, seen) {
+				return false
+			}
+
+			oid = append(oid, parent.oid...)
+
+		default:
+			root, ok := mibRoots[node.parent]
+			if !ok {
+				return false
+			}
+
+			oid = append(oid, root)
+		}
+	}
+
+	node.oid = append(oid, node.arcs...)
+	node.resolved = true
+
+	return true
+}
+
+// symbol returns the node a symbol refers to within module,
+// following its imports, or nil.
+func (m *MIB) symbol(module *mibModule, name string) *mibNode {
+	module = m.definingModule(module, name, func(module *mibModule) bool {
+		_, ok := module.nodes[name]
+		return ok
+	})
+
+	if module == nil {
+		return nil
+	}
+
+	return module.nodes[name]
+}
+
+// definingModule returns the module that defines a symbol used in
+// module, following its imports, or nil. defines reports whether
+// a module defines the symbol.
+func (m *MIB) definingModule(module *mibModule, name string, defines func(*mibModule) bool) *mibModule {
+	for i := 0; i <= len(m.order); i++ {
+		if defines(module) {
+			return module
+		}
+
+		from, ok := module.imports[name]
+		if !ok {
+			return nil
+		}
+
+		if module, ok = m.modules[from]; !ok {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// tokenizeMIB splits MIB source into tokens, dropping comments.
+// Quoted strings are kept whole, including their quotes.
+func tokenizeMIB(src string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f':
+			i++
+
+		case strings.HasPrefix(src[i:], "--"):
+			// Comments end at the end of the line or at the next "--"
+			i += 2
+			for i < len(src) && src[i] != '\n' {
+				if strings.HasPrefix(src[i:], "--") {
+					i += 2
+					break
+				}
+
+				i++
+			}
+
+		case c == '"':
+			j := i + 1
+			for {
+				k := strings.IndexByte(src[j:], '"')
+				if k < 0 {
+					return nil, errors.New("snmp: unterminated string in MIB")
+				}
+
+				j += k + 1
+
+				// "" is an escaped quote
+				if j < len(src) && src[j] == '"' {
+					j++
+					continue
+				}
+
+				break
+			}
+
+			tokens = append(tokens, src[i:j])
+			i = j
+
+		case c == '\'':
+			// Binary and hexadecimal strings such as '0F'H
+			k := strings.IndexByte(src[i+1:], '\'')
+			if k < 0 {
+				return nil, errors.New("snmp: unterminated string in MIB")
+			}
+
+			j := i + k + 2
+			if j < len(src) && (src[j] == 'H' || src[j] == 'h' || src[j] == 'B' || src[j] == 'b') {
+				j++
+			}
+
+			tokens = append(tokens, src[i:j])
+			i = j
+
+		case strings.HasPrefix(src[i:], "::="):
+			tokens = append(tokens, "::=")
+			i += 3
+
+		case strings.HasPrefix(src[i:], ".."):
+			tokens = append(tokens, "..")
+			i += 2
+
+		case strings.IndexByte("{}(),;|[]", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+
+		case isMIBWordByte(c):
+			j := i + 1
+			for j < len(src) && isMIBWordByte(src[j]) && !strings.HasPrefix(src[j:], "--") {
+				j++
+			}
+
+			tokens = append(tokens, src[i:j])
+			i = j
+
+		default:
+			return nil, fmt.Errorf("snmp: unexpected character %q in MIB", c)
+		}
+	}
+
+	return tokens, nil
+}
+
+func isMIBWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// mibParser parses MIB modules from tokens.
+type mibParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *mibParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// peek returns the token n tokens ahead, or "" past the end.
+func (p *mibParser) peek(n int) string {
+	if p.pos+n < len(p.tokens) {
+		return p.tokens[p.pos+n]
+	}
+
+	return ""
+}
+
+func (p *mibParser) next() string {
+	t := p.peek(0)
+	p.pos++
+	return t
+}
+
+func (p *mibParser) expect(tokens ...string) error {
+	for _, t := range tokens {
+		if got := p.next(); got != t {
+			return fmt.Errorf("snmp: expected %q in MIB, got %q", t, got)
+		}
+	}
+
+	return nil
+}
+
+// skipTo advances past the first token equal to end
+// outside of braces and parentheses.
+func (p *mibParser) skipTo(end string) error {
+	depth := 0
+
+	for !p.done() {
+		t := p.next()
+
+		switch {
+		case t == "{" || t == "(":
+			depth++
+		case t == "}" || t == ")":
+			depth--
+		case t == end && depth <= 0:
+			return nil
+		}
+	}
+
+	return fmt.Errorf("snmp: expected %q in MIB", end)
+}
+
+// module parses a module from DEFINITIONS to END.
+func (p *mibParser) module() (*mibModule, error) {
+	module := &mibModule{
+		name:    p.next(),
+		imports: map[string]string{},
+		nodes:   map[string]*mibNode{},
+		types:   map[string]*mibType{},
+	}
+
+	if err := p.expect("DEFINITIONS", "::=", "BEGIN"); err != nil {
+		return nil, err
+	}
+
+	depth := 0
+
+	for {
+		if p.done() {
+			return nil, fmt.Errorf("snmp: MIB module %s has no END", module.name)
+		}
+
+		t := p.peek(0)
+
+		switch {
+		case t == "{" || t == "(":
+			depth++
+			p.pos++
+
+		case t == "}" || t == ")":
+			depth--
+			p.pos++
+
+		case depth > 0:
+			p.pos++
+
+		case t == "END":
+			p.pos++
+			return module, nil
+
+		case t == "IMPORTS":
+			p.pos++
+			if err := p.imports(module); err != nil {
+				return nil, err
+			}
+
+		case t == "EXPORTS":
+			if err := p.skipTo(";"); err != nil {
+				return nil, err
+			}
+
+		case p.peek(1) == "MACRO":
+			if err := p.skipTo("END"); err != nil {
+				return nil, err
+			}
+
+		case p.peek(1) == "OBJECT" && p.peek(2) == "IDENTIFIER" && p.peek(3) == "::=":
+			p.pos += 4
+			if _, err := p.assignment(module, t); err != nil {
+				return nil, err
+			}
+
+		case mibMacros[p.peek(1)] && isLowerMIBName(t):
+			macro := p.peek(1)
+			p.pos += 2
+
+			start := p.pos
+			if err := p.skipTo("::="); err != nil {
+				return nil, err
+			}
+
+			body := &mibParser{tokens: p.tokens[start : p.pos-1]}
+
+			// SMIv1 TRAP-TYPE values are numbers
+			if p.peek(0) != "{" {
+				p.pos++
+				continue
+			}
+
+			node, err := p.assignment(module, t)
+			if err != nil {
+				return nil, err
+			}
+
+			if macro == "OBJECT-TYPE" {
+				if err := body.objectType(node); err != nil {
+					return nil, err
+				}
+			}
+
+		case p.peek(1) == "::=" && !isLowerMIBName(t):
+			p.pos += 2
+			if err := p.typeAssignment(module, t); err != nil {
+				return nil, err
+			}
+
+		default:
+			p.pos++
+		}
+	}
+}
+
+// imports parses the symbols of an IMPORTS clause up to its ";".
+func (p *mibParser) imports(module *mibModule) error {
+	var symbols []string
+
+	for {
+		t := p.next()
+
+		switch t {
+		case "":
+			return errors.New("snmp: unterminated IMPORTS in MIB")
+
+		case ";":
+			return nil
+
+		case ",":
+
+		case "FROM":
+			from := p.next()
+			for _, s := range symbols {
+				module.imports[s] = from
+			}
+
+			symbols = nil
+
+		default:
+			symbols = append(symbols, t)
+		}
+	}
+}
+
+// objectType parses the clauses of an OBJECT-TYPE body into node.
+func (p *mibParser) objectType(node *mibNode) error {
+	for !p.done() {
+		switch p.next() {
+		case "{", "(":
+			p.pos--
+			p.skipGroup()
+
+		case "SYNTAX":
+			syntax, err := p.syntax()
+			if err != nil {
+				return fmt.Errorf("%v for %s", err, node.name)
+			}
+
+			node.syntax = syntax
+
+		case "MAX-ACCESS", "ACCESS":
+			node.access = p.next()
+		}
+	}
+
+	return nil
+}
+
+// typeAssignment parses the type assignment or TEXTUAL-CONVENTION
+// of name following its "::=".
+func (p *mibParser) typeAssignment(module *mibModule, name string) error {
+	typ := &mibType{}
+
+	if p.peek(0) == "TEXTUAL-CONVENTION" {
+		for t := p.next(); t != "SYNTAX"; t = p.next() {
+			if p.done() {
+				return fmt.Errorf("snmp: TEXTUAL-CONVENTION %s has no SYNTAX in MIB", name)
+			}
+
+			if t == "DISPLAY-HINT" {
+				typ.hint = strings.Trim(p.next(), `"`)
+			}
+		}
+	}
+
+	// Tags such as [APPLICATION 1] IMPLICIT don't affect the syntax
+	if p.peek(0) == "[" {
+		if err := p.skipTo("]"); err != nil {
+			return err
+		}
+	}
+
+	if p.peek(0) == "IMPLICIT" {
+		p.pos++
+	}
+
+	syntax, err := p.syntax()
+	if err != nil {
+		return fmt.Errorf("%v for %s", err, name)
+	}
+
+	typ.syntax = syntax
+	module.types[name] = typ
+
+	return nil
+}
+
+// syntax parses a SYNTAX and the named numbers of an INTEGER
+// or BITS, skipping any constraints that follow it.
+func (p *mibParser) syntax() (mibSyntax, error) {
+	var s mibSyntax
+
+	switch t := p.next(); {
+	case t == "OCTET" && p.peek(0) == "STRING", t == "OBJECT" && p.peek(0) == "IDENTIFIER":
+		s.name = t + " " + p.next()
+
+	case t == "SEQUENCE" && p.peek(0) == "OF":
+		p.pos += 2
+		s.name = "SEQUENCE OF"
+
+	default:
+		s.name = t
+	}
+
+	if (s.name == "INTEGER" || s.name == "BITS") && p.peek(0) == "{" {
+		enums, err := p.enums()
+		if err != nil {
+			return s, err
+		}
+
+		s.enums = enums
+	}
+
+	for p.peek(0) == "{" || p.peek(0) == "(" {
+		p.skipGroup()
+	}
+
+	return s, nil
+}
+
+// enums parses a list of named numbers, such as { up(1), down(2) }.
+func (p *mibParser) enums() ([]mibEnum, error) {
+	var enums []mibEnum
+
+	p.pos++
+
+	for {
+		name := p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		value, err := strconv.Atoi(p.next())
+		if err != nil {
+			return nil, fmt.Errorf("snmp: bad named number %s in MIB", name)
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		enums = append(enums, mibEnum{name: name, value: value})
+
+		switch t := p.next(); t {
+		case ",":
+		case "}":
+			return enums, nil
+		default:
+			return nil, fmt.Errorf("snmp: unexpected %q in named numbers in MIB", t)
+		}
+	}
+}
+
+// skipGroup advances past a balanced group of braces or parentheses.
+func (p *mibParser) skipGroup() {
+	depth := 0
+
+	for !p.done() {
+		switch p.next() {
+		case "{", "(":
+			depth++
+		case "}", ")":
+			depth--
+		}
+
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+// assignment parses the OBJECT IDENTIFIER value of name,
+// such as { mib-2 1 } or { iso org(3) dod(6) }.
+func (p *mibParser) assignment(module *mibModule, name string) (*mibNode, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	node := &mibNode{name: name, module: module}
+
+	for first := true; ; first = false {
+		t := p.next()
+
+		switch {
+		case t == "}":
+			if len(node.arcs) == 0 && node.parent == "" {
+				return nil, fmt.Errorf("snmp: empty OBJECT IDENTIFIER value for %s in MIB", name)
+			}
+
+			module.nodes[name] = node
+			module.order = append(module.order, node)
+
+			return node, nil
+
+		case t == "":
+			return nil, fmt.Errorf("snmp: unterminated OBJECT IDENTIFIER value for %s in MIB", name)
+
+		case isDigit(t[0]):
+			arc, err := strconv.ParseUint(t, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("snmp: bad sub-identifier %q for %s in MIB", t, name)
+			}
+
+			node.arcs = append(node.arcs, uint32(arc))
+
+		case p.peek(0) == "(":
+			// A named number such as org(3)
+			p.pos++
+
+			arc, err := strconv.ParseUint(p.next(), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("snmp: bad sub-identifier in %s for %s in MIB", t, name)
+			}
+
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+
+			node.arcs = append(node.arcs, uint32(arc))
+
+		case first:
+			node.parent = t
+
+		default:
+			return nil, fmt.Errorf("snmp: unexpected %q in OBJECT IDENTIFIER value for %s in MIB", t, name)
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLowerMIBName(t string) bool {
+	return t != "" && t[0] >= 'a' && t[0] <= 'z'
+}
@@ -0,0 +1,321 @@
+package snmp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testMIB loads the MIB modules in testdata, dependents first
+// to exercise resolution across loads.
+func testMIB(t *testing.T) *MIB {
+	m := &MIB{}
+
+	for _, path := range []string{"testdata/IF-MIB.txt", "testdata/SNMPv2-MIB.txt", "testdata/SNMPv2-SMI.txt", "testdata/SNMPv2-TC.txt"} {
+		if err := m.LoadFile(path); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	return m
+}
+
+func TestMIBLookup(t *testing.T) {
+	m := testMIB(t)
+
+	for name, expected := range map[string]string{
+		"sysUpTime":                 ".1.3.6.1.2.1.1.3",
+		"SNMPv2-MIB::sysDescr":      ".1.3.6.1.2.1.1.1",
+		"ifTable":                   ".1.3.6.1.2.1.2.2",
+		"IF-MIB::ifOperStatus":      ".1.3.6.1.2.1.2.2.1.8",
+		"linkDown":                  ".1.3.6.1.6.3.1.1.5.3",
+		"snmpTrapOID":               ".1.3.6.1.6.3.1.1.4.1",
+		"SNMPv2-SMI::enterprises":   ".1.3.6.1.4.1",
+		"SNMPv2-SMI::zeroDotZero":   ".0.0",
+		"SNMPv2-MIB::systemGroup":   ".1.3.6.1.6.3.1.2.2.6",
+		"SNMPv2-MIB::snmpInPkts":    ".1.3.6.1.2.1.11.1",
+		"IF-MIB::ifMIB":             ".1.3.6.1.2.1.31",
+		"SNMPv2-MIB::snmpMIBGroups": ".1.3.6.1.6.3.1.2.2",
+	} {
+		oid, err := m.Lookup(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if oid.String() != expected {
+			t.Errorf("%s: expected %s, got %v", name, expected, oid)
+		}
+	}
+
+	for _, name := range []string{"ifBogus", "IF-MIB::sysDescr", "NO-SUCH-MIB::sysDescr", "IfEntry", "DisplayString"} {
+		if _, err := m.Lookup(name); !errors.Is(err, ErrUnknownSymbol) {
+			t.Errorf("%s: expected ErrUnknownSymbol, got %v", name, err)
+		}
+	}
+}
+
+func TestMIBName(t *testing.T) {
+	m := testMIB(t)
+
+	for oid, expected := range map[string]string{
+		".1.3.6.1.2.1.1.1.0":      "SNMPv2-MIB::sysDescr.0",
+		".1.3.6.1.2.1.2.2.1.2.3":  "IF-MIB::ifDescr.3",
+		".1.3.6.1.2.1.2.2":        "IF-MIB::ifTable",
+		".1.3.6.1.4.1.9.1.1":      "SNMPv2-SMI::enterprises.9.1.1",
+		".1.3.6.1.2.1.2.2.1.10.7": "IF-MIB::ifInOctets.7",
+		".1.3.6.1.2.1.1":          "SNMPv2-MIB::system",
+		".1.3.6.1.2.1.1.99.1":     "SNMPv2-MIB::system.99.1",
+		".1":                      ".1",
+		".2.999":                  ".2.999",
+	} {
+		if name := m.Name(MustParseOID(oid)); name != expected {
+			t.Errorf("%s: expected %s, got %s", oid, expected, name)
+		}
+	}
+}
+
+func TestMIBCheckValue(t *testing.T) {
+	m := testMIB(t)
+
+	for _, test := range []struct {
+		oid   string
+		value DataType
+		ok    bool
+	}{
+		{".1.3.6.1.2.1.1.5.0", String("router"), true},
+		{".1.3.6.1.2.1.1.5.0", Int(1), false},
+		{".1.3.6.1.2.1.1.3.0", TimeTicks(1), true},
+		{".1.3.6.1.2.1.1.3.0", Gauge(1), false},
+		{".1.3.6.1.2.1.2.2.1.7.1", Int(2), true},
+		{".1.3.6.1.2.1.2.2.1.6.1", String("\x00\x11"), true},
+		{".1.3.6.1.2.1.2.2.1.6.1", ObjectIdentifier{1, 3}, false},
+		{".1.3.6.1.2.1.1.2.0", ObjectIdentifier{1, 3}, true},
+		{".1.3.6.1.2.1.1.5.0", Null, true},
+		{".1.3.6.1.4.1.9.1", Int(1), true},
+	} {
+		err := m.CheckValue(NewVarbind(MustParseOID(test.oid), test.value))
+		if test.ok && err != nil {
+			t.Errorf("%s = %v: %v", test.oid, test.value, err)
+		}
+
+		if !test.ok && !errors.Is(err, ErrWrongSyntax) {
+			t.Errorf("%s = %v: expected ErrWrongSyntax, got %v", test.oid, test.value, err)
+		}
+	}
+}
+
+func TestMIBRender(t *testing.T) {
+	m := testMIB(t)
+
+	for _, test := range []struct {
+		oid      string
+		value    DataType
+		expected string
+	}{
+		{".1.3.6.1.2.1.2.2.1.6.1", String("\x00\x1a\x2b\x3c\x4d\x5e"), "00:1a:2b:3c:4d:5e"},
+		{".1.3.6.1.2.1.2.2.1.8.1", Int(1), "up(1)"},
+		{".1.3.6.1.2.1.2.2.1.8.1", Int(99), "99"},
+		{".1.3.6.1.2.1.1.5.0", String("héllo\n"), "héllo\n"},
+		{".1.3.6.1.4.1.9.1", String("a\x00b"), "Hex-STRING: 61 00 62"},
+		{".1.3.6.1.2.1.1.5.0", NoSuchInstance, "noSuchInstance"},
+	} {
+		if s := m.Render(NewVarbind(MustParseOID(test.oid), test.value)); s != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.oid, test.expected, s)
+		}
+	}
+}
+
+func TestMIBEnumName(t *testing.T) {
+	m := testMIB(t)
+
+	ifOperStatus := MustParseOID(".1.3.6.1.2.1.2.2.1.8.3")
+
+	for value, expected := range map[int]string{1: "up", 2: "down", 7: "lowerLayerDown"} {
+		name, ok := m.EnumName(ifOperStatus, value)
+		if !ok || name != expected {
+			t.Errorf("%d: expected %s, got %q (%v)", value, expected, name, ok)
+		}
+
+		if v, ok := m.EnumValue(ifOperStatus, expected); !ok || v != value {
+			t.Errorf("%s: expected %d, got %d (%v)", expected, value, v, ok)
+		}
+	}
+
+	if name, ok := m.EnumName(ifOperStatus, 8); ok {
+		t.Errorf("expected no label for 8, got %s", name)
+	}
+
+	if _, ok := m.EnumValue(ifOperStatus, "sideways"); ok {
+		t.Error("expected no value for sideways")
+	}
+
+	if _, ok := m.EnumName(sysName, 1); ok {
+		t.Error("expected no enumeration for sysName")
+	}
+}
+
+func TestFormatOctetHint(t *testing.T) {
+	for _, test := range []struct {
+		hint     string
+		value    string
+		expected string
+	}{
+		{"1x:", "\x00\x1a\xff", "00:1a:ff"},
+		{"255a", "router", "router"},
+		{"2d-1d-1d,1d:1d:1d.1d,1a1d:1d", "\x07\xea\x0a\x0e\x0d\x1e\x0f\x00+\x02\x00", "2026-10-14,13:30:15.0,+2:0"},
+		{"1d.", "\x0a\x00\x00\x01", "10.0.0.1"},
+		{"*1x:/1a", "\x02\xab\xcdxyz", "ab:cd/xyz"},
+	} {
+		s, err := formatOctetHint(test.hint, []byte(test.value))
+		if err != nil {
+			t.Errorf("%s: %v", test.hint, err)
+			continue
+		}
+
+		if s != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.hint, test.expected, s)
+		}
+	}
+
+	if _, err := formatOctetHint("x", []byte{1}); err == nil {
+		t.Error("expected an error for a hint without a length")
+	}
+
+	// A length of 0 would never consume the value
+	for _, hint := range []string{"0x", "1x:0d", "*0a"} {
+		if _, err := formatOctetHint(hint, []byte{1, 2}); err != errBadDisplayHint {
+			t.Errorf("%s: expected errBadDisplayHint, got %v", hint, err)
+		}
+	}
+}
+
+func TestFormatIntegerHint(t *testing.T) {
+	for _, test := range []struct {
+		hint     string
+		value    int64
+		expected string
+	}{
+		{"d-2", 1234, "12.34"},
+		{"d-2", -5, "-0.05"},
+		{"x", 255, "ff"},
+		{"d", 7, "7"},
+		{"d-19", -1, "-0.0000000000000000001"},
+	} {
+		s, err := formatIntegerHint(test.hint, test.value)
+		if err != nil || s != test.expected {
+			t.Errorf("%s %d: expected %q, got %q (%v)", test.hint, test.value, test.expected, s, err)
+		}
+	}
+
+	if _, err := formatIntegerHint("d-1000000000", 1); err != errBadDisplayHint {
+		t.Errorf("expected errBadDisplayHint for too many places, got %v", err)
+	}
+}
+
+func TestMIBUnresolvedImports(t *testing.T) {
+	m := &MIB{}
+	if err := m.LoadFile("testdata/SNMPv2-MIB.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Lookup("sysDescr"); !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("expected ErrUnknownSymbol before SNMPv2-SMI is loaded, got %v", err)
+	}
+
+	if err := m.LoadFile("testdata/SNMPv2-SMI.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Lookup("sysDescr"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMIBLoadErrors(t *testing.T) {
+	for _, src := range []string{
+		"BROKEN-MIB DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= { iso 3 }",
+		"BROKEN-MIB DEFINITIONS BEGIN END",
+		"BROKEN-MIB DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= { } END",
+		"BROKEN-MIB DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= { iso bar } END",
+		"BROKEN-MIB DEFINITIONS ::= BEGIN DESCRIPTION \"unterminated END",
+	} {
+		if err := (&MIB{}).Load(strings.NewReader(src)); err == nil {
+			t.Errorf("expected an error loading %q", src)
+		}
+	}
+
+	m := testMIB(t)
+	if err := m.LoadFile("testdata/IF-MIB.txt"); err == nil {
+		t.Error("expected an error loading IF-MIB twice")
+	}
+}
+
+func TestParseOIDWithMIB(t *testing.T) {
+	m := testMIB(t)
+
+	for str, expected := range map[string]string{
+		"sysDescr":                ".1.3.6.1.2.1.1.1",
+		"sysDescr.0":              ".1.3.6.1.2.1.1.1.0",
+		".sysDescr.0":             ".1.3.6.1.2.1.1.1.0",
+		"SNMPv2-MIB::sysDescr":    ".1.3.6.1.2.1.1.1",
+		"SNMPv2-MIB::sysDescr.0":  ".1.3.6.1.2.1.1.1.0",
+		"sysDescr.1.3.6":          ".1.3.6.1.2.1.1.1.1.3.6",
+		"IF-MIB::ifDescr.2":       ".1.3.6.1.2.1.2.2.1.2.2",
+		".1.3.6.1.2.1.1.1.0":      ".1.3.6.1.2.1.1.1.0",
+		"1.3.6.1.2.1.1.1.0":       ".1.3.6.1.2.1.1.1.0",
+		"SNMPv2-SMI::enterprises": ".1.3.6.1.4.1",
+	} {
+		oid, err := ParseOIDWithMIB(str, m)
+		if err != nil {
+			t.Errorf("%s: %v", str, err)
+			continue
+		}
+
+		if oid.String() != expected {
+			t.Errorf("%s: expected %s, got %v", str, expected, oid)
+		}
+	}
+
+	if _, err := ParseOIDWithMIB("ifBogus.1", m); !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("expected ErrUnknownSymbol, got %v", err)
+	}
+
+	for _, str := range []string{"sysDescr.x", "sysDescr..0", "1.3.x"} {
+		if _, err := ParseOIDWithMIB(str, m); err == nil {
+			t.Errorf("expected an error for %q", str)
+		}
+	}
+
+	if _, err := ParseOIDWithMIB("sysDescr.0", nil); err == nil {
+		t.Error("expected an error for a symbol without a MIB")
+	}
+
+	if oid, err := ParseOIDWithMIB(".1.3.6", nil); err != nil || oid.String() != ".1.3.6" {
+		t.Errorf("expected .1.3.6 without a MIB, got %v, %v", oid, err)
+	}
+}
+
+func BenchmarkMIBName(b *testing.B) {
+	m := &MIB{}
+
+	for _, path := range []string{"testdata/IF-MIB.txt", "testdata/SNMPv2-MIB.txt", "testdata/SNMPv2-SMI.txt", "testdata/SNMPv2-TC.txt"} {
+		if err := m.LoadFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// Instances of table columns, as rendered by a poller
+	oids := []ObjectIdentifier{
+		MustParseOID(".1.3.6.1.2.1.2.2.1.10.1000001"),
+		MustParseOID(".1.3.6.1.2.1.31.1.1.1.6.1000001"),
+		MustParseOID(".1.3.6.1.2.1.1.1.0"),
+		MustParseOID(".1.3.6.1.4.1.9.9.109.1.1.1.1.7.1"),
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m.Name(oids[i%len(oids)])
+	}
+}
@@ -0,0 +1,90 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Monitor gets oids every interval until ctx is done and calls onChange
+// for each variable binding whose value differs from the one got by the
+// previous poll, with that previous value. The first poll is made at
+// once and only records the values. Polls that fail are skipped, and
+// their errors can be seen with c.Trace. Monitor returns ctx.Err(), or
+// an error at once if interval isn't positive.
+func (c *Client) Monitor(ctx context.Context, interval time.Duration, oids []ObjectIdentifier, onChange func(v Varbind, old DataType)) error {
+	return c.monitor(ctx, interval, oids, func(v Varbind, old DataType) {
+		if !ValuesEqual(v.value, old) {
+			onChange(v, old)
+		}
+	})
+}
+
+// MonitorDeltas is like Monitor for Counter and Counter64 objects, but
+// calls onDelta with the increase of each counter since the previous
+// poll, allowing for the counter wrapping around, whenever it isn't
+// zero. Bindings of other types are ignored.
+func (c *Client) MonitorDeltas(ctx context.Context, interval time.Duration, oids []ObjectIdentifier, onDelta func(v Varbind, delta uint64)) error {
+	return c.monitor(ctx, interval, oids, func(v Varbind, old DataType) {
+		if delta, ok := counterDelta(old, v.value); ok && delta != 0 {
+			onDelta(v, delta)
+		}
+	})
+}
+
+// monitor gets oids every interval on a single Conn until ctx is done,
+// and calls fn with each binding after the first poll and the value of
+// the same OID from the previous poll.
+func (c *Client) monitor(ctx context.Context, interval time.Duration, oids []ObjectIdentifier, fn func(v Varbind, old DataType)) error {
+	if interval <= 0 {
+		return errors.New("snmp: monitor interval must be positive")
+	}
+
+	conn, err := c.Dial()
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev []Varbind
+
+	for {
+		varbinds, err := conn.GetContext(ctx, oids...)
+		if err == nil && len(varbinds) == len(oids) {
+			for i, v := range varbinds {
+				if prev != nil && prev[i].OID.Equal(v.OID) {
+					fn(v, prev[i].value)
+				}
+			}
+
+			prev = varbinds
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// counterDelta returns the increase from old to value if both are
+// Counters or both are Counter64s, which wrap around at their maximum.
+func counterDelta(old, value DataType) (uint64, bool) {
+	switch v := value.(type) {
+	case Counter:
+		if o, ok := old.(Counter); ok {
+			return uint64(v - o), true
+		}
+	case Counter64:
+		if o, ok := old.(Counter64); ok {
+			return uint64(v - o), true
+		}
+	}
+
+	return 0, false
+}
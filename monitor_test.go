@@ -0,0 +1,107 @@
+package snmp
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pollingAgent returns a stub agent answering each GET for oid with
+// value(n), where n counts the polls from 1.
+func pollingAgent(t *testing.T, oid ObjectIdentifier, value func(n int32) DataType) *stubAgent {
+	agent := newStubAgent(t)
+
+	var polls int32
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetRequest)
+		n := atomic.AddInt32(&polls, 1)
+
+		return []*Message{{
+			Version:   m.Version,
+			Community: m.Community,
+			PDU:       GetResponse{PDU: newPDU(req.requestID, 0, 0, []Varbind{NewVarbind(oid, value(n))})},
+		}}
+	})
+
+	return agent
+}
+
+func TestClientMonitor(t *testing.T) {
+	agent := pollingAgent(t, sysName, func(n int32) DataType {
+		if n == 1 {
+			return String("router")
+		}
+
+		return String("switch")
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var changes []Varbind
+	var olds []DataType
+
+	err := c.Monitor(ctx, 10*time.Millisecond, []ObjectIdentifier{sysName}, func(v Varbind, old DataType) {
+		changes = append(changes, v)
+		olds = append(olds, old)
+
+		// Let later polls show the value is unchanged
+		time.AfterFunc(50*time.Millisecond, cancel)
+	})
+
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %v", changes)
+	}
+
+	if !changes[0].OID.Equal(sysName) || changes[0].Value() != String("switch") || olds[0] != String("router") {
+		t.Errorf("unexpected change of %v from %v", changes[0], olds[0])
+	}
+}
+
+func TestClientMonitorInterval(t *testing.T) {
+	agent := pollingAgent(t, sysName, func(int32) DataType { return String("router") })
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := c.Monitor(context.Background(), interval, []ObjectIdentifier{sysName}, func(Varbind, DataType) {}); err == nil {
+			t.Errorf("%v: expected an error for a non-positive interval", interval)
+		}
+
+		if err := c.MonitorDeltas(context.Background(), interval, []ObjectIdentifier{sysName}, func(Varbind, uint64) {}); err == nil {
+			t.Errorf("%v: expected an error for a non-positive interval", interval)
+		}
+	}
+}
+
+func TestClientMonitorDeltas(t *testing.T) {
+	agent := pollingAgent(t, ifDescr.Child(1), func(n int32) DataType {
+		return Counter(math.MaxUint32 - 10 + 7*uint32(n))
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var deltas []uint64
+
+	c.MonitorDeltas(ctx, 10*time.Millisecond, []ObjectIdentifier{ifDescr.Child(1)}, func(v Varbind, delta uint64) {
+		if deltas = append(deltas, delta); len(deltas) == 3 {
+			cancel()
+		}
+	})
+
+	// The second delta wraps around
+	if len(deltas) != 3 || deltas[0] != 7 || deltas[1] != 7 || deltas[2] != 7 {
+		t.Errorf("expected 3 deltas of 7, got %v", deltas)
+	}
+}
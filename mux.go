@@ -0,0 +1,545 @@
+package snmp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// mux multiplexes requests over a single UDP socket or TCP connection.
+// Each request in flight has a request ID that is unique on the socket,
+// which a reader goroutine uses to route responses to it. SNMPv3
+// requests are routed by their message ID, which is the same as their
+// request ID. A mux may carry requests over a Transport instead, with
+// each response routed as its round trip returns.
+type mux struct {
+	conn  net.Conn
+	trace *Trace
+
+	// transport is set in place of conn for a mux of a Transport,
+	// which closeOnce closes.
+	transport Transport
+	closeOnce sync.Once
+
+	// stream is set for TCP, where messages are delimited by
+	// their length rather than by datagrams.
+	stream bool
+
+	// bufSize is the size of the buffer datagrams are received in.
+	bufSize int
+
+	// opts are the options responses are decoded with.
+	opts DecodeOptions
+
+	mu      sync.Mutex
+	pending map[int32]*pendingRequest
+
+	// raw is the number of pending requests reserved with reserveRaw.
+	raw int
+
+	// stats, if set, collects the statistics of the requests sent on
+	// the mux, which must then only be used by one at a time.
+	stats *requestStats
+
+	// err is the error the reader stopped with,
+	// set before done is closed.
+	err  error
+	done chan struct{}
+}
+
+// pendingRequest is a request in flight on a mux.
+type pendingRequest struct {
+	// match accepts the responses of the request.
+	// It is nil until the request is sent.
+	match     func(m *Message, packet []byte) bool
+	responses chan response
+
+	// raw requests are delivered their response packets without
+	// decoding them, and match is called with a nil Message.
+	raw bool
+}
+
+// response is a Message received for a pendingRequest, the packet
+// received for a raw one, or an error reading from the socket. size is
+// the number of bytes received.
+type response struct {
+	m      *Message
+	packet []byte
+	size   int
+	err    error
+}
+
+// requestStats are the statistics of a request.
+type requestStats struct {
+	attempts     int
+	requestSize  int
+	responseSize int
+	roundTrip    time.Duration
+}
+
+// dialMux returns a mux with a socket connected to addr on network,
+// "udp" or "tcp", which calls the receive callbacks of trace. The
+// socket is bound to the local address local as host or host:port, if
+// it isn't empty. Datagrams are received in a buffer of bufSize bytes,
+// or 65535 if it's zero, and decoded with opts.
+func dialMux(network, local, addr string, bufSize int, opts DecodeOptions, trace *Trace) (*mux, error) {
+	var dialer net.Dialer
+
+	if local != "" {
+		laddr, err := resolveLocal(network, local)
+		if err != nil {
+			return nil, fmt.Errorf("snmp: invalid local address %q: %w", local, err)
+		}
+
+		dialer.LocalAddr = laddr
+	}
+
+	conn, err := dialer.Dial(network, addr)
+	if err != nil {
+		if local != "" {
+			return nil, fmt.Errorf("snmp: can't send from local address %q: %w", local, err)
+		}
+
+		return nil, err
+	}
+
+	if bufSize <= 0 {
+		bufSize = 65535
+	}
+
+	mx := &mux{
+		conn:    conn,
+		trace:   trace,
+		stream:  network == "tcp",
+		bufSize: bufSize,
+		opts:    opts,
+		pending: map[int32]*pendingRequest{},
+		done:    make(chan struct{}),
+	}
+
+	go mx.read()
+
+	return mx, nil
+}
+
+// newTransportMux returns a mux that sends requests as round trips of
+// transport, which calls the receive callbacks of trace. Responses are
+// decoded with opts.
+func newTransportMux(transport Transport, opts DecodeOptions, trace *Trace) *mux {
+	return &mux{
+		trace:     trace,
+		transport: transport,
+		opts:      opts,
+		pending:   map[int32]*pendingRequest{},
+		done:      make(chan struct{}),
+	}
+}
+
+// resolveLocal resolves the local address local on network,
+// with any port if it doesn't include one.
+func resolveLocal(network, local string) (net.Addr, error) {
+	local = withDefaultPort(local, "0")
+
+	if network == "tcp" {
+		return net.ResolveTCPAddr(network, local)
+	}
+
+	return net.ResolveUDPAddr(network, local)
+}
+
+// close closes the socket of mx. Requests in flight fail.
+func (mx *mux) close() error {
+	if mx.transport == nil {
+		return mx.conn.Close()
+	}
+
+	mx.closeOnce.Do(func() {
+		mx.err = net.ErrClosed
+		close(mx.done)
+	})
+
+	return nil
+}
+
+// write sends packet on the socket of mx. For a Transport, it starts a
+// round trip, which is canceled with ctx or once timeout passes, when
+// the attempt has timed out and any retry is left to the caller.
+func (mx *mux) write(ctx context.Context, packet []byte, timeout time.Duration) error {
+	if mx.transport == nil {
+		_, err := mx.conn.Write(packet)
+		return err
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		res, err := mx.transport.RoundTrip(ctx, packet)
+
+		// The attempt is over, and a retry may be in flight
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			mx.fail(packet, err)
+			return
+		}
+
+		mx.trace.receive(res)
+		mx.deliver(res)
+	}()
+
+	return nil
+}
+
+// reserve returns a random request ID that isn't in flight on mx.
+// It must be released once the request is done.
+func (mx *mux) reserve() int32 {
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+
+	for {
+		id := randomRequestID()
+		if _, ok := mx.pending[id]; ok || id == 0 {
+			continue
+		}
+
+		mx.pending[id] = &pendingRequest{responses: make(chan response, 1)}
+
+		return id
+	}
+}
+
+// reserveRaw is like reserve for a request that is delivered its
+// response packet rather than the decoded Message.
+func (mx *mux) reserveRaw() int32 {
+	id := mx.reserve()
+
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+
+	mx.pending[id].raw = true
+	mx.raw++
+
+	return id
+}
+
+// release frees a request ID returned by reserve or reserveRaw.
+func (mx *mux) release(id int32) {
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+
+	if p := mx.pending[id]; p != nil && p.raw {
+		mx.raw--
+	}
+
+	delete(mx.pending, id)
+}
+
+// expect sets the function accepting responses to request id, and
+// returns the channel they are delivered on. Responses that don't
+// match are dropped, as are any beyond one that hasn't been received.
+// Any response left over from an earlier exchange is discarded.
+func (mx *mux) expect(id int32, match func(m *Message, packet []byte) bool) <-chan response {
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+
+	p := mx.pending[id]
+	p.match = match
+
+	select {
+	case <-p.responses:
+	default:
+	}
+
+	return p.responses
+}
+
+// read delivers the messages received by mx until its socket
+// is closed. Messages that can't be decoded are ignored.
+func (mx *mux) read() {
+	buf := make([]byte, mx.bufSize)
+	stream := bufio.NewReader(mx.conn)
+
+	for {
+		var packet []byte
+		var err error
+
+		if mx.stream {
+			var n int
+			packet, n, err = readPacket(stream)
+
+			// The agent closed the connection between messages
+			if n == 0 && errors.Is(err, ErrTruncated) {
+				err = io.EOF
+			}
+		} else {
+			var n int
+			n, err = mx.conn.Read(buf)
+			packet = buf[:n]
+		}
+
+		// A stream can't be read past an error, since
+		// the framing of the messages that follow is lost
+		if errors.Is(err, net.ErrClosed) || (mx.stream && err != nil) {
+			mx.err = err
+			close(mx.done)
+			return
+		}
+
+		// Errors such as ICMP port unreachable fail the requests in
+		// flight, but later requests can still use the socket
+		if err != nil {
+			mx.broadcast(err)
+			continue
+		}
+
+		mx.trace.receive(packet)
+
+		// A datagram larger than buf is cut off, which leaves it
+		// exactly filling buf and shorter than its outer SEQUENCE
+		if !mx.stream && len(packet) == len(buf) {
+			if _, _, err := readPacket(bytes.NewReader(packet)); errors.Is(err, ErrTruncated) {
+				mx.truncated(packet)
+				continue
+			}
+		}
+
+		mx.deliver(packet)
+	}
+}
+
+// deliver delivers the response packet to the request it answers.
+// Responses that can't be decoded or aren't for a request in flight
+// are ignored.
+func (mx *mux) deliver(packet []byte) {
+	if mx.deliverRaw(packet) {
+		return
+	}
+
+	m, err := mx.decode(packet)
+	if err != nil {
+		mx.trace.error(err)
+
+		// The response is for a request, but is too large to use
+		if errors.Is(err, ErrTooManyBindings) {
+			mx.fail(packet, err)
+		}
+
+		return
+	}
+
+	id, ok := responseID(m)
+	if !ok {
+		return
+	}
+
+	mx.mu.Lock()
+	p := mx.pending[id]
+	var match func(*Message, []byte) bool
+	if p != nil {
+		match = p.match
+	}
+	mx.mu.Unlock()
+
+	if match == nil || !match(m, packet) {
+		return
+	}
+
+	select {
+	case p.responses <- response{m: m, size: len(packet)}:
+	default:
+	}
+}
+
+// broadcast delivers err to every request in flight.
+func (mx *mux) broadcast(err error) {
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+
+	for _, p := range mx.pending {
+		if p.match == nil {
+			continue
+		}
+
+		select {
+		case p.responses <- response{err: err}:
+		default:
+		}
+	}
+}
+
+// deliverRaw delivers a copy of packet to the raw request it answers,
+// reporting whether it was for one. Packets are only inspected as far
+// as their request ID, and only while raw requests are pending.
+func (mx *mux) deliverRaw(packet []byte) bool {
+	mx.mu.Lock()
+	raw := mx.raw
+	mx.mu.Unlock()
+
+	if raw == 0 {
+		return false
+	}
+
+	id, ok := peekID(packet)
+	if !ok {
+		return false
+	}
+
+	mx.mu.Lock()
+	p := mx.pending[id]
+	var match func(*Message, []byte) bool
+	if p != nil && p.raw {
+		match = p.match
+	}
+	mx.mu.Unlock()
+
+	if p == nil || !p.raw {
+		return false
+	}
+
+	if match == nil || !match(nil, packet) {
+		return true
+	}
+
+	select {
+	case p.responses <- response{packet: append([]byte(nil), packet...), size: len(packet)}:
+	default:
+	}
+
+	return true
+}
+
+// decode decodes the message packet with mx.opts.
+func (mx *mux) decode(packet []byte) (*Message, error) {
+	if mx.opts == (DecodeOptions{}) {
+		m, _, err := decodeMessage(bytes.NewReader(packet))
+		return m, err
+	}
+
+	m, _, err := decodeMessage(&lenientReader{Reader: bytes.NewReader(packet), opts: mx.opts})
+
+	return m, err
+}
+
+// truncated fails the request a truncated response is for, or every
+// request in flight if its request ID can't be read.
+func (mx *mux) truncated(packet []byte) {
+	err := fmt.Errorf("%w: the response filled the %d byte receive buffer; "+
+		"raise MaxMessageSize or request fewer bindings per GETBULK", ErrResponseTruncated, len(packet))
+
+	mx.trace.error(err)
+	mx.fail(packet, err)
+}
+
+// fail delivers err to the request packet is a response to, or to every
+// request in flight if its request ID can't be read.
+func (mx *mux) fail(packet []byte, err error) {
+	id, ok := peekID(packet)
+	if !ok {
+		mx.broadcast(err)
+		return
+	}
+
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+
+	if p := mx.pending[id]; p != nil && p.match != nil {
+		select {
+		case p.responses <- response{err: err}:
+		default:
+		}
+	}
+}
+
+// peekID returns the ID responseID would return for the message packet
+// begins with, reading only as far as the ID, so that it also works
+// for truncated packets.
+func peekID(packet []byte) (int32, bool) {
+	// The outer SEQUENCE and the PDU or msgGlobalData are entered
+	// by their headers, since they may be cut off
+	enter := func(tag byte) bool {
+		t, _, n, err := splitHeader(packet)
+		if err != nil || t != tag {
+			return false
+		}
+
+		packet = packet[n:]
+
+		return true
+	}
+
+	skip := func(tag byte) ([]byte, bool) {
+		content, rest, err := expectTLV(packet, tag)
+		if err != nil {
+			return nil, false
+		}
+
+		packet = rest
+
+		return content, true
+	}
+
+	if !enter(TypeSequence) {
+		return 0, false
+	}
+
+	version, ok := skip(TypeInteger)
+	if !ok {
+		return 0, false
+	}
+
+	if rawInt(version) == Version3 {
+		if !enter(TypeSequence) {
+			return 0, false
+		}
+	} else if _, ok := skip(TypeString); !ok || !enter(TypeGetResponse) {
+		return 0, false
+	}
+
+	id, ok := skip(TypeInteger)
+	if !ok || len(id) > 4 {
+		return 0, false
+	}
+
+	return int32(rawInt(id)), true
+}
+
+// responseID returns the ID used to route m to its request. IDs outside
+// the range of int32, which no request has, aren't truncated to match.
+func responseID(m *Message) (int32, bool) {
+	id := m.MessageID
+
+	if m.Version != Version3 {
+		res, ok := m.PDU.(GetResponse)
+		if !ok {
+			return 0, false
+		}
+
+		id = res.requestID
+	}
+
+	return int32(id), int64(id) == int64(int32(id))
+}
+
+// randomRequestID returns a random positive request ID from
+// crypto/rand, so that forged responses can't predict it. Responses are
+// matched to requests by their exact ID rather than by order, so IDs
+// don't wrap around. Negative IDs are valid, and are answered by Agent
+// and matched by Client, but Net-SNMP only sends positive ones, so some
+// agents don't handle negative IDs correctly.
+func randomRequestID() int32 {
+	var b [4]byte
+	cryptorand.Read(b[:])
+
+	return int32(binary.BigEndian.Uint32(b[:]) &^ (1 << 31))
+}
@@ -1,34 +1,145 @@
 package snmp
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
-// ObjectIdentifier represents an SNMP OID.
-type ObjectIdentifier []uint
+// ObjectIdentifier represents an SNMP OID. Only OIDs of at least two
+// arcs can be encoded, as the first is 0, 1, or 2 and the second is
+// encoded with it. Shorter ones, such as the empty OID, can still be
+// compared and formatted.
+type ObjectIdentifier []uint32
 
 // ParseOID parses and returns an ObjectIdentifier and an error.
+// The dotted decimal form is normalized: leading and trailing dots and
+// the empty sub-identifiers of repeated dots are ignored, and a leading
+// "iso" stands for arc 1. So ".1.3.6.1", "1.3.6.1", and "iso.3..6.1"
+// parse to the same OID.
 func ParseOID(str string) (ObjectIdentifier, error) {
-	parts := strings.Split(strings.Trim(str, "."), ".")
+	trimmed := strings.Trim(str, ".")
+	if trimmed == "iso" || strings.HasPrefix(trimmed, "iso.") {
+		trimmed = "1" + trimmed[len("iso"):]
+	}
+
+	parts := strings.FieldsFunc(trimmed, func(r rune) bool { return r == '.' })
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("snmp: invalid OID %q: no sub-identifiers", str)
+	}
+
+	oid := make(ObjectIdentifier, 0, len(parts))
+
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("snmp: invalid OID %q: bad sub-identifier %q at index %d", str, part, i)
+		}
+
+		oid = append(oid, uint32(n))
+	}
+
+	return oid, nil
+}
+
+// ParseOIDs parses a list of OIDs separated by commas and/or
+// whitespace, such as ".1.3.6.1.2.1.1.1.0, .1.3.6.1.2.1.1.3.0".
+// Empty entries are skipped. The ParseOID error of the first
+// entry that can't be parsed is returned, which quotes it.
+func ParseOIDs(str string) ([]ObjectIdentifier, error) {
+	fields := strings.FieldsFunc(str, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
 
-	oid := ObjectIdentifier{}
+	oids := make([]ObjectIdentifier, 0, len(fields))
 
-	for _, part := range parts {
-		n, err := strconv.ParseUint(part, 10, 64)
+	for _, field := range fields {
+		oid, err := ParseOID(field)
 		if err != nil {
 			return nil, err
 		}
 
-		oid = append(oid, uint(n))
+		oids = append(oids, oid)
+	}
+
+	return oids, nil
+}
+
+// ParseOIDWithMIB is like ParseOID but also accepts OIDs that start
+// with a symbol defined in m, such as "sysDescr.0" or
+// "SNMPv2-MIB::sysDescr". Any arcs after the symbol are appended to
+// its OID. Numeric OIDs are parsed as by ParseOID.
+func ParseOIDWithMIB(str string, m *MIB) (ObjectIdentifier, error) {
+	trimmed := strings.TrimPrefix(str, ".")
+	if trimmed == "" || isDigit(trimmed[0]) {
+		return ParseOID(str)
+	}
+
+	// Without a MIB, "iso" is the only symbol ParseOID knows
+	if m == nil && (trimmed == "iso" || strings.HasPrefix(trimmed, "iso.")) {
+		return ParseOID(str)
+	}
+
+	if m == nil {
+		return nil, fmt.Errorf("snmp: invalid OID %q: symbols require a MIB", str)
+	}
+
+	// Module names can't contain dots, so the symbol ends at the
+	// first dot after any module qualifier
+	symbol, rest := trimmed, ""
+	start := strings.Index(trimmed, "::") + 1
+	if i := strings.IndexByte(trimmed[start:], '.'); i >= 0 {
+		symbol, rest = trimmed[:start+i], trimmed[start+i+1:]
+	}
+
+	oid, err := m.Lookup(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if rest != "" {
+		arcs, err := ParseOID(rest)
+		if err != nil || rest[0] == '.' {
+			return nil, fmt.Errorf("snmp: invalid OID %q: bad arcs after %s", str, symbol)
+		}
+
+		oid = append(oid, arcs...)
 	}
 
 	return oid, nil
 }
 
+// Canonical returns a copy of oid that doesn't share its array,
+// or nil if oid can't be encoded: it must have at least two arcs,
+// the first 0, 1, or 2, and the second less than 40 unless the
+// first is 2.
+func (oid ObjectIdentifier) Canonical() ObjectIdentifier {
+	if _, err := oid.Encode(); err != nil {
+		return nil
+	}
+
+	return oid.Clone()
+}
+
+// FromUint32Slice returns an ObjectIdentifier with the arcs of a
+// foreign representation, such as the oid array of Net-SNMP, which it
+// doesn't share. Like Canonical, it returns nil if the arcs can't be
+// encoded as an OID.
+func FromUint32Slice(arcs []uint32) ObjectIdentifier {
+	return ObjectIdentifier(arcs).Canonical()
+}
+
+// ToUint32Slice returns the arcs of oid in a new slice, for passing
+// to a foreign representation such as the oid array of Net-SNMP.
+func (oid ObjectIdentifier) ToUint32Slice() []uint32 {
+	return []uint32(oid.Clone())
+}
+
 // MustParseOID parses a string and returns an ObjectIdentifier.
 // It panics if an error is encountered.
 func MustParseOID(str string) ObjectIdentifier {
@@ -40,81 +151,264 @@ func MustParseOID(str string) ObjectIdentifier {
 	return oid
 }
 
-// encodeOIDUint encodes a uint using base 128.
-func encodeOIDUint(i uint) []byte {
-	var b []byte
+// encodeOIDUint encodes a uint32 using base 128.
+func encodeOIDUint(i uint32) []byte {
+	return appendOIDUint(nil, i)
+}
 
-	if i < 128 {
-		return []byte{byte(i)}
-	}
+// appendOIDUint appends a uint32 encoded using base 128.
+// Sub-identifiers are written most significant group first,
+// and every group except the last has the high bit set.
+func appendOIDUint(b []byte, i uint32) []byte {
+	// A uint32 needs at most five 7-bit groups
+	var buf [5]byte
 
-	b = append(b, byte(i)%128)
-	i /= 128
+	pos := len(buf) - 1
+	buf[pos] = byte(i & 0x7f)
+	i >>= 7
 
 	for i > 0 {
-		b = append(b, 128+byte(i)%128)
-		i /= 128
+		pos--
+		buf[pos] = 0x80 | byte(i&0x7f)
+		i >>= 7
 	}
 
-	return reverseSlice(b)
+	return append(b, buf[pos:]...)
 }
 
 // Encode encodes an ObjectIdentifier with the proper header.
 func (oid ObjectIdentifier) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if _, err := oid.EncodeTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// EncodeTo writes oid with the proper header to w, returning the number
+// of bytes written and an error. Nothing is allocated if w is also an
+// io.ByteWriter, as a *bytes.Buffer is.
+func (oid ObjectIdentifier) EncodeTo(w io.Writer) (int, error) {
+	if err := oid.validate(); err != nil {
+		return 0, err
+	}
+
+	length := oid.contentLen()
+
+	bw, ok := w.(io.ByteWriter)
+	if !ok {
+		b := appendTLVHeader(make([]byte, 0, tlvHeaderLen(length)+length), TypeOID, length)
+
+		b = appendOIDUint(b, oid[0]*40+oid[1])
+		for _, arc := range oid[2:] {
+			b = appendOIDUint(b, arc)
+		}
+
+		return w.Write(b)
+	}
+
+	var header [6]byte
+	n := 0
+
+	for _, c := range appendTLVHeader(header[:0], TypeOID, length) {
+		if err := bw.WriteByte(c); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	// The first two arcs are combined into a single sub-identifier
+	for i, arc := range oid[1:] {
+		if i == 0 {
+			arc += oid[0] * 40
+		}
+
+		m, err := writeOIDUint(bw, arc)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// EncodedLen returns the number of bytes Encode returns for oid,
+// including its header, without encoding it. It returns 0 if oid
+// can't be encoded.
+func (oid ObjectIdentifier) EncodedLen() int {
+	if oid.validate() != nil {
+		return 0
+	}
+
+	length := oid.contentLen()
+
+	return tlvHeaderLen(length) + length
+}
+
+// contentLen returns the length of the contents of the encoding of
+// oid, which must be valid.
+func (oid ObjectIdentifier) contentLen() int {
+	length := oidUintLen(oid[0]*40 + oid[1])
+	for _, arc := range oid[2:] {
+		length += oidUintLen(arc)
+	}
+
+	return length
+}
+
+// validate returns an error if oid can't be encoded.
+func (oid ObjectIdentifier) validate() error {
 	if len(oid) < 2 {
-		return nil, errors.New("snmp: invalid ObjectIdentifier length")
+		return errors.New("snmp: invalid ObjectIdentifier length")
+	}
+
+	if oid[0] > 2 {
+		return errors.New("snmp: first ObjectIdentifier arc must be 0, 1, or 2")
+	}
+
+	if oid[0] < 2 && oid[1] > 39 {
+		return errors.New("snmp: second ObjectIdentifier arc must be less than 40")
 	}
 
-	if oid[0] != 1 && oid[1] != 3 {
-		return nil, errors.New("ObjectIdentifier does not start with .1.3")
+	if oid[1] > 0xffffffff-80 {
+		return errors.New("snmp: second ObjectIdentifier arc is too large")
 	}
 
-	b := make([]byte, 0, len(oid)+1)
+	return nil
+}
+
+// writeOIDUint writes a uint32 encoded as appendOIDUint does.
+func writeOIDUint(w io.ByteWriter, i uint32) (int, error) {
+	n := oidUintLen(i)
+
+	for k := n - 1; k >= 0; k-- {
+		c := byte(i>>uint(7*k)) & 0x7f
+		if k > 0 {
+			c |= 0x80
+		}
+
+		if err := w.WriteByte(c); err != nil {
+			return n - 1 - k, err
+		}
+	}
 
-	b = append(b, 0x2b)
+	return n, nil
+}
 
-	for i := 2; i < len(oid); i++ {
-		b = append(b, encodeOIDUint(oid[i])...)
+// oidUintLen returns the number of bytes appendOIDUint uses for i.
+func oidUintLen(i uint32) int {
+	n := 1
+	for i >>= 7; i > 0; i >>= 7 {
+		n++
 	}
 
-	return append(encodeHeaderSequence(0x6, len(b)), b...), nil
+	return n
 }
 
-// decodeOID decodes an OID up to length bytes from r.
+// decodeOID decodes an OID up to length bytes from r, which may be a
+// lenientReader with the LenientFirstArc option set.
 // It returns the SNMP data type, the number of bytes read, and an error.
 func decodeOID(length int, r io.Reader) (ObjectIdentifier, int, error) {
 	bytesRead := 0
 
+	if length < 1 {
+		return nil, bytesRead, errors.New("snmp: empty ObjectIdentifier")
+	}
+
 	// Read into a buffer
-	b := make([]byte, length)
-	n, err := r.Read(b)
+	b, n, err := readContent(length, r)
 	bytesRead += n
 
 	if err != nil {
 		return nil, bytesRead, err
 	}
 
-	oid := ObjectIdentifier{uint(b[0]) / 40, uint(b[0]) % 40}
+	lr, lenient := r.(*lenientReader)
+
+	oid, err := decodeOIDContent(b, lenient && lr.opts.LenientFirstArc)
+	if err != nil {
+		return nil, bytesRead, err
+	}
+
+	return oid, bytesRead, nil
+}
+
+// ParseOIDBytes decodes the contents of a BER-encoded OBJECT IDENTIFIER,
+// without its tag and length, such as those sliced from a packet with
+// WalkRaw. It is faster than decoding the OID from a message and
+// allocates only the result, which doesn't share b.
+func ParseOIDBytes(b []byte) (ObjectIdentifier, error) {
+	if len(b) < 1 {
+		return nil, errors.New("snmp: empty ObjectIdentifier")
+	}
+
+	return decodeOIDContent(b, false)
+}
 
-	for i := 1; i < length; i++ {
-		val := uint(0)
+// decodeOIDContent decodes the contents b of an OID, which isn't empty.
+// With lenientFirstArc, the first sub-identifier is split as described
+// by DecodeOptions.LenientFirstArc.
+func decodeOIDContent(b []byte, lenientFirstArc bool) (ObjectIdentifier, error) {
+	// Each sub-identifier ends with a byte with the high bit clear,
+	// and the first holds two arcs
+	arcs := 1
+	for _, c := range b {
+		if c&0x80 == 0 {
+			arcs++
+		}
+	}
+
+	oid := make(ObjectIdentifier, 0, arcs)
 
-		for b[i] >= 128 {
-			val += uint(b[i]) - 128
-			val *= 128
-			i++
+	val := uint32(0)
+	for i, c := range b {
+		if val > 0xffffffff>>7 {
+			return nil, errors.New("snmp: OID sub-identifier overflows 32 bits")
 		}
 
-		val += uint(b[i])
+		// X.690 requires the fewest groups, so none lead with zero bits
+		// and each sub-identifier has a single encoding
+		if c == 0x80 && (i == 0 || b[i-1]&0x80 == 0) {
+			return nil, errors.New("snmp: OID sub-identifier isn't minimally encoded")
+		}
 
-		oid = append(oid, val)
+		val = val<<7 | uint32(c&0x7f)
+
+		// The last group of a sub-identifier has the high bit clear
+		if c&0x80 == 0 {
+			if len(oid) == 0 {
+				// The first sub-identifier holds the first two arcs
+				switch {
+				case lenientFirstArc:
+					oid = append(oid, val/40, val%40)
+				case val < 40:
+					oid = append(oid, 0, val)
+				case val < 80:
+					oid = append(oid, 1, val-40)
+				default:
+					oid = append(oid, 2, val-80)
+				}
+			} else {
+				oid = append(oid, val)
+			}
+
+			val = 0
+		} else if i == len(b)-1 {
+			return nil, errors.New("snmp: truncated OID sub-identifier")
+		}
 	}
 
-	return oid, bytesRead, nil
+	return oid, nil
 }
 
-// String returns the string representation of an ObjectIdentifer.
-// This value can be parsed into the original OID as well.
+// String returns the string representation of an ObjectIdentifer
+// with a leading dot, e.g. ".1.3.6.1.2.1.1.1.0".
+// This is the canonical form; ParseOID is its inverse, except for the
+// empty OID, which is formatted as "" and which ParseOID rejects.
 func (oid ObjectIdentifier) String() string {
 	str := ""
 
@@ -124,3 +418,240 @@ func (oid ObjectIdentifier) String() string {
 
 	return str
 }
+
+// Dotted returns the string representation of an ObjectIdentifer
+// without a leading dot, e.g. "1.3.6.1.2.1.1.1.0".
+// ParseOID accepts this form as well.
+func (oid ObjectIdentifier) Dotted() string {
+	return strings.TrimPrefix(oid.String(), ".")
+}
+
+// Compare compares two ObjectIdentifiers arc by arc.
+// It returns -1 if oid sorts before other, 1 if it sorts after,
+// and 0 if they are equal. An OID sorts before any OID it is a
+// prefix of, which is the ordering used by GETNEXT.
+func (oid ObjectIdentifier) Compare(other ObjectIdentifier) int {
+	for i := 0; i < len(oid) && i < len(other); i++ {
+		if oid[i] < other[i] {
+			return -1
+		}
+
+		if oid[i] > other[i] {
+			return 1
+		}
+	}
+
+	switch {
+	case len(oid) < len(other):
+		return -1
+	case len(oid) > len(other):
+		return 1
+	}
+
+	return 0
+}
+
+// HasPrefix returns true if the leading arcs of oid match prefix.
+// It is used to check whether an OID is within a subtree.
+func (oid ObjectIdentifier) HasPrefix(prefix ObjectIdentifier) bool {
+	if len(prefix) > len(oid) {
+		return false
+	}
+
+	for i := range prefix {
+		if oid[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TrimPrefix returns the arcs of oid after prefix, such as the index
+// of a table instance given its column OID, and whether oid has the
+// prefix. The result shares its backing array with oid.
+func (oid ObjectIdentifier) TrimPrefix(prefix ObjectIdentifier) (ObjectIdentifier, bool) {
+	if !oid.HasPrefix(prefix) {
+		return nil, false
+	}
+
+	return oid[len(prefix):], true
+}
+
+// Suffix returns the last n arcs of oid, or all of oid if it has
+// fewer than n arcs. The result shares its backing array with oid.
+func (oid ObjectIdentifier) Suffix(n int) ObjectIdentifier {
+	if n < 0 {
+		n = 0
+	}
+
+	if n > len(oid) {
+		n = len(oid)
+	}
+
+	return oid[len(oid)-n:]
+}
+
+// Arc returns the arc of oid at position i, counting from 0, and
+// whether oid has one there.
+func (oid ObjectIdentifier) Arc(i int) (uint32, bool) {
+	if i < 0 || i >= len(oid) {
+		return 0, false
+	}
+
+	return oid[i], true
+}
+
+// Len returns the number of arcs of oid.
+func (oid ObjectIdentifier) Len() int {
+	return len(oid)
+}
+
+// Equal returns true if oid and other have identical arcs.
+// Nil and empty ObjectIdentifiers are equal.
+func (oid ObjectIdentifier) Equal(other ObjectIdentifier) bool {
+	if len(oid) != len(other) {
+		return false
+	}
+
+	for i := range oid {
+		if oid[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ByOID implements sort.Interface for a slice of ObjectIdentifiers
+// using the ordering defined by Compare.
+type ByOID []ObjectIdentifier
+
+func (s ByOID) Len() int           { return len(s) }
+func (s ByOID) Less(i, j int) bool { return s[i].Compare(s[j]) < 0 }
+func (s ByOID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// SortOIDs sorts a slice of ObjectIdentifiers in the order
+// an agent would return them with GETNEXT.
+func SortOIDs(oids []ObjectIdentifier) {
+	sort.Sort(ByOID(oids))
+}
+
+// MarshalText implements encoding.TextMarshaler. The OID is
+// written in dotted form without a leading dot, e.g. "1.3.6.1".
+func (oid ObjectIdentifier) MarshalText() ([]byte, error) {
+	return []byte(oid.Dotted()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseOID.
+func (oid *ObjectIdentifier) UnmarshalText(text []byte) error {
+	parsed, err := ParseOID(string(text))
+	if err != nil {
+		return err
+	}
+
+	*oid = parsed
+
+	return nil
+}
+
+// Clone returns a copy of oid that doesn't share its backing array, or
+// nil if oid is nil. An OID kept while the slice it was sliced from may
+// be written to, such as by append with spare capacity or by reusing a
+// buffer, should be cloned first. OIDs decoded by this package never
+// share their arrays with the message or with each other.
+func (oid ObjectIdentifier) Clone() ObjectIdentifier {
+	if oid == nil {
+		return nil
+	}
+
+	return append(make(ObjectIdentifier, 0, len(oid)), oid...)
+}
+
+// Append returns a new ObjectIdentifier with arcs appended to oid.
+// The result never shares a backing array with oid.
+func (oid ObjectIdentifier) Append(arcs ...uint32) ObjectIdentifier {
+	result := make(ObjectIdentifier, len(oid), len(oid)+len(arcs))
+	copy(result, oid)
+
+	return append(result, arcs...)
+}
+
+// Parent returns a new ObjectIdentifier with the last arc of oid
+// removed, or false if oid has no arcs.
+func (oid ObjectIdentifier) Parent() (ObjectIdentifier, bool) {
+	if len(oid) == 0 {
+		return nil, false
+	}
+
+	return oid[:len(oid)-1].Append(), true
+}
+
+// CommonPrefix returns a new ObjectIdentifier with the leading arcs
+// oid and other share, which is empty if their first arcs differ.
+func (oid ObjectIdentifier) CommonPrefix(other ObjectIdentifier) ObjectIdentifier {
+	return oid[:oid.DivergeIndex(other)].Append()
+}
+
+// DivergeIndex returns the index of the first arc where oid and other
+// differ, or the length of the shorter if it is a prefix of the other,
+// without allocating. It's the length of their CommonPrefix.
+func (oid ObjectIdentifier) DivergeIndex(other ObjectIdentifier) int {
+	n := 0
+	for n < len(oid) && n < len(other) && oid[n] == other[n] {
+		n++
+	}
+
+	return n
+}
+
+// Child returns a new ObjectIdentifier with a single arc appended to oid.
+func (oid ObjectIdentifier) Child(arc uint32) ObjectIdentifier {
+	return oid.Append(arc)
+}
+
+// Range returns the children of oid with the last arcs start through
+// end inclusive, such as ifDescr.1 through ifDescr.48 for GetMany. Each
+// is a new ObjectIdentifier. It returns nil if start is after end.
+func (oid ObjectIdentifier) Range(start, end uint16) []ObjectIdentifier {
+	if start > end {
+		return nil
+	}
+
+	oids := make([]ObjectIdentifier, 0, int(end)-int(start)+1)
+	for arc := int(start); arc <= int(end); arc++ {
+		oids = append(oids, oid.Child(uint32(arc)))
+	}
+
+	return oids
+}
+
+// Next returns a new ObjectIdentifier with a .0 appended to oid, the
+// smallest OID after oid. A GETNEXT for it returns the first OID after
+// oid.0, skipping an object bound at oid.0 such as the instance of a
+// scalar; a GETNEXT for oid itself returns the first object under it,
+// including oid.0.
+func (oid ObjectIdentifier) Next() ObjectIdentifier {
+	return oid.Child(0)
+}
+
+// NextSibling returns a new ObjectIdentifier with the last arc of oid
+// incremented, the smallest OID after every one under oid, so that a
+// GETNEXT for it skips the subtree of oid. If the last arc is already
+// 4294967295, the next sibling of the parent is returned instead. It
+// returns nil if no OID is after the subtree of oid.
+func (oid ObjectIdentifier) NextSibling() ObjectIdentifier {
+	n := len(oid)
+	for n > 0 && oid[n-1] == 1<<32-1 {
+		n--
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	next := oid[:n].Clone()
+	next[n-1]++
+
+	return next
+}
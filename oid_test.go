@@ -2,7 +2,12 @@ package snmp
 
 import (
 	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestEncodeAndParseOID(t *testing.T) {
@@ -37,3 +42,954 @@ func TestOIDLargeNumbers(t *testing.T) {
 			".1.3.6.1.2.1.7.7.1.8.1.4.0.0.0.0.68.1.4.0.0.0.0.0.2464081", oid.String())
 	}
 }
+
+func TestOIDSubidentifierRange(t *testing.T) {
+	oid, err := ParseOID(".1.3.6.1.4.1.4294967295")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if oid[len(oid)-1] != 4294967295 {
+		t.Errorf("expected last sub-identifier %d, got %d", uint32(4294967295), oid[len(oid)-1])
+	}
+
+	if _, err := ParseOID(".1.3.6.1.4.1.4294967296"); err == nil {
+		t.Error("expected an error for a sub-identifier larger than 32 bits")
+	}
+}
+
+func TestEncodeOIDUint(t *testing.T) {
+	cases := []struct {
+		in       uint32
+		expected []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x00}},
+		{2680, []byte{0x94, 0x78}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x81, 0x80, 0x00}},
+		{4294967295, []byte{0x8f, 0xff, 0xff, 0xff, 0x7f}},
+	}
+
+	for _, c := range cases {
+		if b := encodeOIDUint(c.in); !bytes.Equal(c.expected, b) {
+			t.Errorf("encodeOIDUint(%d): expected %v, got %v", c.in, c.expected, b)
+		}
+	}
+}
+
+func TestOIDRoundTrip(t *testing.T) {
+	cases := []ObjectIdentifier{
+		{1, 3, 6, 1, 2, 1, 1, 1, 0},
+		{1, 3, 6, 1, 4, 1, 2636, 3, 2, 3, 1, 20},
+		{1, 3, 6, 1, 4, 1, 16384, 2097151},
+		{1, 3, 6, 1, 4, 1, 2097152, 268435455},
+		{1, 3, 6, 1, 4, 1, 268435456, 4294967295},
+	}
+
+	for _, oid := range cases {
+		b, err := oid.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, _, err := decodeOID(len(b)-2, bytes.NewReader(b[2:]))
+		if err != nil {
+			t.Fatalf("decoding %v: %v", oid, err)
+		}
+
+		if decoded.String() != oid.String() {
+			t.Errorf("expected decoded ObjectIdentifier %v, got %v", oid, decoded)
+		}
+	}
+}
+
+func TestDecodeOIDTruncated(t *testing.T) {
+	b := []byte{0x2b, 0x06, 0x81}
+
+	if _, _, err := decodeOID(len(b), bytes.NewReader(b)); err == nil {
+		t.Error("expected an error for a truncated sub-identifier")
+	}
+}
+
+func TestDecodeOIDShortReads(t *testing.T) {
+	oid := ObjectIdentifier{1, 3, 6, 1, 4, 1, 2636, 3, 2, 3, 1, 20}
+
+	b, err := oid.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, n, err := decodeOID(len(b)-2, iotest.OneByteReader(bytes.NewReader(b[2:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(b)-2 {
+		t.Errorf("expected %d bytes read, got %d", len(b)-2, n)
+	}
+
+	if decoded.String() != oid.String() {
+		t.Errorf("expected decoded ObjectIdentifier %v, got %v", oid, decoded)
+	}
+
+	_, _, err = decodeOID(len(b)-2, bytes.NewReader(b[2:5]))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected %v for a short buffer, got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestOIDFirstArcs(t *testing.T) {
+	cases := []struct {
+		oid   ObjectIdentifier
+		first []byte
+	}{
+		{ObjectIdentifier{0, 0}, []byte{0x00}},
+		{ObjectIdentifier{1, 2}, []byte{0x2a}},
+		{ObjectIdentifier{1, 3, 6}, []byte{0x2b}},
+		{ObjectIdentifier{2, 100, 3}, []byte{0x81, 0x34}},
+	}
+
+	for _, c := range cases {
+		b, err := c.oid.Encode()
+		if err != nil {
+			t.Fatalf("encoding %v: %v", c.oid, err)
+		}
+
+		if !bytes.HasPrefix(b[2:], c.first) {
+			t.Errorf("expected %v to start with %v, got %v", c.oid, c.first, b[2:])
+		}
+
+		decoded, _, err := decodeOID(len(b)-2, bytes.NewReader(b[2:]))
+		if err != nil {
+			t.Fatalf("decoding %v: %v", c.oid, err)
+		}
+
+		if decoded.String() != c.oid.String() {
+			t.Errorf("expected decoded ObjectIdentifier %v, got %v", c.oid, decoded)
+		}
+	}
+
+	for _, oid := range []ObjectIdentifier{{3, 1}, {1, 40}, {0, 40}, {1}} {
+		if _, err := oid.Encode(); err == nil {
+			t.Errorf("expected an error encoding %v", oid)
+		}
+	}
+}
+
+func TestOIDCompare(t *testing.T) {
+	cases := []struct {
+		a, b     ObjectIdentifier
+		expected int
+	}{
+		{ObjectIdentifier{}, ObjectIdentifier{}, 0},
+		{nil, ObjectIdentifier{1}, -1},
+		{ObjectIdentifier{1}, nil, 1},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6}, 0},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6, 1}, -1},
+		{ObjectIdentifier{1, 3, 6, 1}, ObjectIdentifier{1, 3, 6}, 1},
+		{ObjectIdentifier{1, 3, 6, 2}, ObjectIdentifier{1, 3, 6, 10}, -1},
+		{ObjectIdentifier{1, 4}, ObjectIdentifier{1, 3, 6, 1}, 1},
+		{ObjectIdentifier{2}, ObjectIdentifier{1, 3, 6, 1}, 1},
+		{ObjectIdentifier{1, 3, 6, 1, 2}, ObjectIdentifier{1, 3, 6, 1, 10, 0}, -1},
+	}
+
+	for _, c := range cases {
+		if result := c.a.Compare(c.b); result != c.expected {
+			t.Errorf("%v.Compare(%v): expected %d, got %d", c.a, c.b, c.expected, result)
+		}
+	}
+}
+
+func TestOIDHasPrefix(t *testing.T) {
+	ifTable := ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2}
+
+	cases := []struct {
+		oid, prefix ObjectIdentifier
+		expected    bool
+	}{
+		{ifTable, ifTable, true},
+		{ifTable, nil, true},
+		{ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 1}, ifTable, true},
+		{ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 1, 0}, ifTable, false},
+		{ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 3}, ifTable, false},
+		{ObjectIdentifier{1, 3, 6, 1, 2, 1}, ifTable, false},
+	}
+
+	for _, c := range cases {
+		if result := c.oid.HasPrefix(c.prefix); result != c.expected {
+			t.Errorf("%v.HasPrefix(%v): expected %v, got %v", c.oid, c.prefix, c.expected, result)
+		}
+	}
+}
+
+func TestOIDTrimPrefix(t *testing.T) {
+	column := MustParseOID(".1.3.6.1.2.1.4.22.1.2")
+	instance := column.Append(3, 192, 0, 2, 1)
+
+	index, ok := instance.TrimPrefix(column)
+	if !ok || !index.Equal(ObjectIdentifier{3, 192, 0, 2, 1}) {
+		t.Errorf("expected index 3.192.0.2.1, got %v (%v)", index, ok)
+	}
+
+	if index, ok := instance.TrimPrefix(MustParseOID(".1.3.6.1.2.1.4.22.1.3")); ok {
+		t.Errorf("expected no match, got %v", index)
+	}
+
+	if index, ok := column.TrimPrefix(instance); ok {
+		t.Errorf("expected no match for a longer prefix, got %v", index)
+	}
+
+	if index, ok := column.TrimPrefix(column); !ok || len(index) != 0 {
+		t.Errorf("expected an empty index, got %v (%v)", index, ok)
+	}
+}
+
+func TestOIDSuffix(t *testing.T) {
+	oid := ObjectIdentifier{1, 3, 6, 1, 2}
+
+	for n, expected := range map[int]ObjectIdentifier{
+		0:  {},
+		2:  {1, 2},
+		5:  {1, 3, 6, 1, 2},
+		9:  {1, 3, 6, 1, 2},
+		-1: {},
+	} {
+		if suffix := oid.Suffix(n); !suffix.Equal(expected) {
+			t.Errorf("%d: expected %v, got %v", n, expected, suffix)
+		}
+	}
+}
+
+func TestOIDEqual(t *testing.T) {
+	cases := []struct {
+		a, b     ObjectIdentifier
+		expected bool
+	}{
+		{nil, nil, true},
+		{nil, ObjectIdentifier{}, true},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6}, true},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6, 1}, false},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 7}, false},
+		{nil, ObjectIdentifier{1}, false},
+	}
+
+	for _, c := range cases {
+		if result := c.a.Equal(c.b); result != c.expected {
+			t.Errorf("%v.Equal(%v): expected %v, got %v", c.a, c.b, c.expected, result)
+		}
+	}
+}
+
+func TestSortOIDs(t *testing.T) {
+	expected := []ObjectIdentifier{
+		MustParseOID(".1.3.6.1.2.1.1"),
+		MustParseOID(".1.3.6.1.2.1.1.1.0"),
+		MustParseOID(".1.3.6.1.2.1.1.2.0"),
+		MustParseOID(".1.3.6.1.2.1.1.10.0"),
+		MustParseOID(".1.3.6.1.2.1.2.2.1.1.1"),
+		MustParseOID(".1.3.6.1.2.1.2.2.1.1.2"),
+		MustParseOID(".1.3.6.1.2.1.2.2.1.2.1"),
+		MustParseOID(".1.3.6.1.4.1.2636"),
+	}
+
+	oids := make([]ObjectIdentifier, len(expected))
+	copy(oids, expected)
+
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(oids), func(i, j int) { oids[i], oids[j] = oids[j], oids[i] })
+
+	SortOIDs(oids)
+
+	for i := range expected {
+		if !oids[i].Equal(expected[i]) {
+			t.Errorf("expected %v at position %d, got %v", expected[i], i, oids[i])
+		}
+	}
+}
+
+func TestParseOIDErrors(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{"1.3.six.1", `snmp: invalid OID "1.3.six.1": bad sub-identifier "six" at index 2`},
+		{"isoo.3", `snmp: invalid OID "isoo.3": bad sub-identifier "isoo" at index 0`},
+		{"1.iso.3", `snmp: invalid OID "1.iso.3": bad sub-identifier "iso" at index 1`},
+		{"", `snmp: invalid OID "": no sub-identifiers`},
+		{"...", `snmp: invalid OID "...": no sub-identifiers`},
+		{".1.3.-6", `snmp: invalid OID ".1.3.-6": bad sub-identifier "-6" at index 2`},
+	}
+
+	for _, c := range cases {
+		_, err := ParseOID(c.in)
+		if err == nil {
+			t.Errorf("expected an error parsing %q", c.in)
+			continue
+		}
+
+		if err.Error() != c.expected {
+			t.Errorf("expected error %q, got %q", c.expected, err.Error())
+		}
+	}
+
+	for _, in := range []string{".1.3.6.1", "1.3.6.1", "1.3.6.1.", ".1.3.6.1."} {
+		oid, err := ParseOID(in)
+		if err != nil {
+			t.Errorf("parsing %q: %v", in, err)
+			continue
+		}
+
+		if !oid.Equal(ObjectIdentifier{1, 3, 6, 1}) {
+			t.Errorf("expected %q to parse as .1.3.6.1, got %v", in, oid)
+		}
+	}
+}
+
+func TestOIDShortBoundaries(t *testing.T) {
+	var empty ObjectIdentifier
+	if s := empty.String(); s != "" {
+		t.Errorf("expected the empty OID to format as \"\", got %q", s)
+	}
+
+	if _, err := ParseOID(empty.String()); err == nil {
+		t.Error("expected an error parsing the empty OID")
+	}
+
+	for _, oid := range []ObjectIdentifier{{1}, {1, 3}} {
+		parsed, err := ParseOID(oid.String())
+		if err != nil || !parsed.Equal(oid) {
+			t.Errorf("expected %q to parse as %v, got %v (%v)", oid.String(), oid, parsed, err)
+		}
+	}
+
+	for _, oid := range []ObjectIdentifier{nil, {}, {1}} {
+		if _, err := oid.Encode(); err == nil {
+			t.Errorf("expected an error encoding %q", oid.String())
+		}
+	}
+
+	if b, err := (ObjectIdentifier{1, 3}).Encode(); err != nil || !bytes.Equal(b, []byte{0x06, 0x01, 0x2b}) {
+		t.Errorf("expected .1.3 to encode as 06012b, got %x (%v)", b, err)
+	}
+}
+
+func TestParseOIDs(t *testing.T) {
+	expected := []ObjectIdentifier{{1, 3, 6, 1}, {1, 3, 6, 2}, {1, 3, 6, 3}}
+
+	for _, str := range []string{
+		".1.3.6.1,.1.3.6.2,.1.3.6.3",
+		".1.3.6.1 .1.3.6.2\t.1.3.6.3\n",
+		" 1.3.6.1, .1.3.6.2 ,\n.1.3.6.3,, ",
+	} {
+		oids, err := ParseOIDs(str)
+		if err != nil {
+			t.Errorf("%q: %v", str, err)
+			continue
+		}
+
+		if len(oids) != len(expected) {
+			t.Errorf("%q: expected %d OIDs, got %v", str, len(expected), oids)
+			continue
+		}
+
+		for i := range oids {
+			if !oids[i].Equal(expected[i]) {
+				t.Errorf("%q: expected %v at %d, got %v", str, expected[i], i, oids[i])
+			}
+		}
+	}
+
+	if oids, err := ParseOIDs(" , "); err != nil || len(oids) != 0 {
+		t.Errorf("expected no OIDs, got %v, %v", oids, err)
+	}
+
+	_, err := ParseOIDs(".1.3.6.1, .1.3.x.2 .1.3.6.3")
+	if err == nil || !strings.Contains(err.Error(), `".1.3.x.2"`) {
+		t.Errorf("expected an error naming .1.3.x.2, got %v", err)
+	}
+}
+
+func TestOIDTextMarshaling(t *testing.T) {
+	type config struct {
+		Name string
+		OID  ObjectIdentifier
+	}
+
+	b, err := json.Marshal(config{Name: "sysDescr", OID: MustParseOID(".1.3.6.1.2.1.1.1.0")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"Name":"sysDescr","OID":"1.3.6.1.2.1.1.1.0"}`; string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+
+	var c config
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.OID.Equal(MustParseOID(".1.3.6.1.2.1.1.1.0")) {
+		t.Errorf("expected unmarshaled OID .1.3.6.1.2.1.1.1.0, got %v", c.OID)
+	}
+
+	if err := json.Unmarshal([]byte(`{"OID":"1.3.x"}`), &c); err == nil {
+		t.Error("expected an error unmarshaling an invalid OID")
+	}
+}
+
+func TestOIDAppend(t *testing.T) {
+	// Spare capacity would let a plain append share the backing array
+	base := make(ObjectIdentifier, 0, 16)
+	base = append(base, 1, 3, 6, 1, 2, 1, 2, 2, 1, 2)
+
+	first := base.Child(1)
+	second := base.Child(2)
+	third := base.Append(3, 0)
+
+	if !first.Equal(MustParseOID(".1.3.6.1.2.1.2.2.1.2.1")) {
+		t.Errorf("expected .1.3.6.1.2.1.2.2.1.2.1, got %v", first)
+	}
+
+	if !second.Equal(MustParseOID(".1.3.6.1.2.1.2.2.1.2.2")) {
+		t.Errorf("expected .1.3.6.1.2.1.2.2.1.2.2, got %v", second)
+	}
+
+	if !third.Equal(MustParseOID(".1.3.6.1.2.1.2.2.1.2.3.0")) {
+		t.Errorf("expected .1.3.6.1.2.1.2.2.1.2.3.0, got %v", third)
+	}
+
+	if len(base) != 10 {
+		t.Errorf("expected base to be unchanged, got %v", base)
+	}
+}
+
+func TestOIDClone(t *testing.T) {
+	backing := make(ObjectIdentifier, 0, 16)
+	backing = append(backing, 1, 3, 6, 1, 2, 1, 1, 5, 0)
+
+	original := backing[:8]
+	clone := original.Clone()
+
+	// Appending to original writes to the spare capacity of backing
+	_ = append(original, 7)
+	original[7] = 6
+
+	if !clone.Equal(MustParseOID(".1.3.6.1.2.1.1.5")) {
+		t.Errorf("expected the clone to be unchanged, got %v", clone)
+	}
+
+	if backing[8] != 7 {
+		t.Fatalf("expected append to share the backing array, got %v", backing)
+	}
+
+	if cap(clone) != len(clone) {
+		t.Errorf("expected no spare capacity in the clone, got %d", cap(clone))
+	}
+
+	if ObjectIdentifier(nil).Clone() != nil {
+		t.Error("expected a nil clone of a nil OID")
+	}
+}
+
+func TestOIDStringForms(t *testing.T) {
+	for _, str := range []string{
+		".1.3.6.1.2.1.1.1.0",
+		".1.3.6.1.4.1.2636.3.2.3.1.20",
+		".0.0",
+		".2.100.3",
+	} {
+		oid := MustParseOID(str)
+
+		if oid.String() != str {
+			t.Errorf("expected String() %s, got %s", str, oid.String())
+		}
+
+		if oid.Dotted() != str[1:] {
+			t.Errorf("expected Dotted() %s, got %s", str[1:], oid.Dotted())
+		}
+
+		for _, form := range []string{oid.String(), oid.Dotted()} {
+			if parsed := MustParseOID(form); !parsed.Equal(oid) {
+				t.Errorf("expected %q to parse as %v, got %v", form, oid, parsed)
+			}
+		}
+	}
+}
+
+func TestParseOIDNormalization(t *testing.T) {
+	expected := MustParseOID(".1.3.6.1.2.1.1.1.0")
+
+	for _, in := range []string{
+		".1.3.6.1.2.1.1.1.0",
+		"1.3.6.1.2.1.1.1.0",
+		"1.3.6.1.2.1.1.1.0.",
+		"iso.3.6.1.2.1.1.1.0",
+		".iso.3.6.1.2.1.1.1.0",
+		"1.3.6.1..2.1.1.1.0",
+		"iso.3.6.1..2...1.1.1.0",
+	} {
+		oid, err := ParseOID(in)
+		if err != nil {
+			t.Errorf("parsing %q: %v", in, err)
+			continue
+		}
+
+		if !oid.Canonical().Equal(expected) {
+			t.Errorf("%q: expected %v, got %v", in, expected, oid)
+		}
+	}
+
+	if oid, err := ParseOIDWithMIB("iso.3.6", nil); err != nil || !oid.Equal(ObjectIdentifier{1, 3, 6}) {
+		t.Errorf("expected iso.3.6 to parse without a MIB, got %v, %v", oid, err)
+	}
+}
+
+func TestOIDCanonical(t *testing.T) {
+	oid := MustParseOID(".1.3.6.1")
+
+	canonical := oid.Canonical()
+	if !canonical.Equal(oid) {
+		t.Fatalf("expected %v, got %v", oid, canonical)
+	}
+
+	canonical[0] = 2
+	if oid[0] != 1 {
+		t.Error("expected Canonical to return a copy")
+	}
+
+	for _, invalid := range []ObjectIdentifier{{1}, {3, 1}, {1, 40}, nil} {
+		if c := invalid.Canonical(); c != nil {
+			t.Errorf("%v: expected nil, got %v", invalid, c)
+		}
+	}
+
+	if c := (ObjectIdentifier{2, 999, 3}).Canonical(); !c.Equal(ObjectIdentifier{2, 999, 3}) {
+		t.Errorf("expected arcs under 2 to be unbounded, got %v", c)
+	}
+}
+
+func TestOIDUint32Slice(t *testing.T) {
+	oid := MustParseOID(".1.3.6.1.4.1.2021.4294967295.0")
+
+	arcs := oid.ToUint32Slice()
+	if len(arcs) != 9 || arcs[7] != 4294967295 {
+		t.Fatalf("unexpected arcs %v", arcs)
+	}
+
+	arcs[0] = 2
+	if oid[0] != 1 {
+		t.Error("expected ToUint32Slice to return a copy")
+	}
+
+	arcs[0] = 1
+
+	back := FromUint32Slice(arcs)
+	if !back.Equal(oid) || back.String() != ".1.3.6.1.4.1.2021.4294967295.0" {
+		t.Fatalf("expected %v, got %v", oid, back)
+	}
+
+	arcs[1] = 4
+	if back[1] != 3 {
+		t.Error("expected FromUint32Slice to return a copy")
+	}
+
+	for _, invalid := range [][]uint32{nil, {1}, {3, 1}, {1, 40}, {2, 4294967295}} {
+		if oid := FromUint32Slice(invalid); oid != nil {
+			t.Errorf("%v: expected nil, got %v", invalid, oid)
+		}
+	}
+}
+
+// writerOnly hides the io.ByteWriter of a *bytes.Buffer.
+type writerOnly struct {
+	io.Writer
+}
+
+func TestOIDEncodedLen(t *testing.T) {
+	oids := []ObjectIdentifier{
+		{0, 0},
+		{1, 3},
+		{2, 0xffffffff - 80},
+		{1, 3, 6, 1, 2, 1, 1, 1, 0},
+		{1, 3, 6, 1, 4, 1, 0x7f, 0x80, 0x3fff, 0x4000, 0xffffffff},
+	}
+
+	r := rand.New(rand.NewSource(1))
+
+	// Up to 800 arcs, whose encodings need long-form lengths
+	for i := 0; i < 500; i++ {
+		oid := ObjectIdentifier{uint32(r.Intn(3)), uint32(r.Intn(40))}
+		for n := r.Intn(800); n > 0; n-- {
+			oid = append(oid, r.Uint32()>>uint(r.Intn(32)))
+		}
+
+		oids = append(oids, oid)
+	}
+
+	for _, oid := range oids {
+		b, err := oid.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := oid.EncodedLen(); n != len(b) {
+			t.Fatalf("%v: expected an EncodedLen of %d, got %d", oid, len(b), n)
+		}
+	}
+
+	for _, oid := range []ObjectIdentifier{nil, {1}, {3, 1}, {1, 40}, {2, 0xffffffff}} {
+		if n := oid.EncodedLen(); n != 0 {
+			t.Errorf("%v: expected an EncodedLen of 0 for an invalid OID, got %d", oid, n)
+		}
+	}
+
+	if testing.AllocsPerRun(10, func() { oids[4].EncodedLen() }) != 0 {
+		t.Error("expected EncodedLen not to allocate")
+	}
+}
+
+func TestOIDEncodeTo(t *testing.T) {
+	long := ObjectIdentifier{1, 3}
+	for i := 0; i < 100; i++ {
+		long = append(long, 1<<28)
+	}
+
+	for _, oid := range []ObjectIdentifier{
+		{1, 3},
+		{0, 39},
+		{2, 999, 3},
+		{1, 3, 6, 1, 4, 1, 0xffffffff},
+		sysDescr,
+		long,
+	} {
+		expected, err := oid.Encode()
+		if err != nil {
+			t.Fatalf("%v: %v", oid, err)
+		}
+
+		var buf bytes.Buffer
+
+		n, err := oid.EncodeTo(&buf)
+		if err != nil || n != len(expected) || !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("%v: expected %x, got %x (%d bytes, %v)", oid, expected, buf.Bytes(), n, err)
+		}
+
+		buf.Reset()
+
+		n, err = oid.EncodeTo(writerOnly{&buf})
+		if err != nil || n != len(expected) || !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("%v: expected %x from an io.Writer, got %x (%d bytes, %v)", oid, expected, buf.Bytes(), n, err)
+		}
+
+		decoded, _, err := decodeOID(len(expected)-2, bytes.NewReader(expected[2:]))
+		if oid.Equal(long) {
+			// A long form length precedes the content
+			decoded, _, err = decodeOID(len(expected)-4, bytes.NewReader(expected[4:]))
+		}
+
+		if err != nil || !decoded.Equal(oid) {
+			t.Errorf("%v: decoded %v, %v", oid, decoded, err)
+		}
+	}
+
+	for _, oid := range []ObjectIdentifier{nil, {1}, {3, 1}, {1, 40}} {
+		var buf bytes.Buffer
+
+		if n, err := oid.EncodeTo(&buf); err == nil || n != 0 || buf.Len() != 0 {
+			t.Errorf("%v: expected an error and nothing written, got %d bytes, %v", oid, n, err)
+		}
+	}
+}
+
+func BenchmarkOIDEncode(b *testing.B) {
+	oid := MustParseOID(".1.3.6.1.2.1.2.2.1.10.1073741824")
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := oid.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOIDEncodeTo(b *testing.B) {
+	oid := MustParseOID(".1.3.6.1.2.1.2.2.1.10.1073741824")
+
+	var buf bytes.Buffer
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+
+		if _, err := oid.EncodeTo(&buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseOIDBytes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		oid := ObjectIdentifier{uint32(r.Intn(3)), uint32(r.Intn(40))}
+		for n := r.Intn(30); n > 0; n-- {
+			oid = append(oid, r.Uint32()>>uint(r.Intn(32)))
+		}
+
+		b, err := oid.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, _, err := expectTLV(b, TypeOID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := ParseOIDBytes(content)
+		if err != nil || !parsed.Equal(oid) {
+			t.Fatalf("expected %v, got %v and %v", oid, parsed, err)
+		}
+
+		if cap(parsed) != len(parsed) {
+			t.Errorf("%v: expected an exact allocation, got a capacity of %d", oid, cap(parsed))
+		}
+	}
+
+	for _, b := range [][]byte{nil, {0x2b, 0x86}, {0x2b, 0x90, 0x80, 0x80, 0x80, 0x00}} {
+		if oid, err := ParseOIDBytes(b); err == nil {
+			t.Errorf("% x: expected an error, got %v", b, oid)
+		}
+	}
+}
+
+func TestParseOIDBytesNonMinimal(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x80, 0x2b},
+		{0x2b, 0x80, 0x06},
+		{0x2b, 0x06, 0x80, 0x80, 0x01},
+		{0x2b, 0x06, 0x01, 0x80, 0x82, 0x37},
+	} {
+		oid, err := ParseOIDBytes(b)
+		if err == nil || !strings.Contains(err.Error(), "minimally") {
+			t.Errorf("% x: expected a non-minimal encoding error, got %v and %v", b, oid, err)
+		}
+
+		if _, _, err := decodeOID(len(b), bytes.NewReader(b)); err == nil {
+			t.Errorf("% x: expected decodeOID to fail too", b)
+		}
+	}
+
+	// A zero in the middle of a sub-identifier is minimal
+	if oid, err := ParseOIDBytes([]byte{0x2b, 0x06, 0x81, 0x80, 0x00}); err != nil || !oid.Equal(ObjectIdentifier{1, 3, 6, 1 << 14}) {
+		t.Errorf("expected .1.3.6.16384, got %v and %v", oid, err)
+	}
+
+	// The encoder never leads with a zero group
+	for _, arc := range []uint32{0, 127, 128, 1 << 14, 1<<32 - 1} {
+		b, err := ObjectIdentifier{1, 3, arc}.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b[3] == 0x80 {
+			t.Errorf("%d: non-minimal encoding % x", arc, b)
+		}
+	}
+}
+
+// ifInOctetsContent is the content of the encoding of ifInOctets.1073741824.
+var ifInOctetsContent = []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x0a, 0x84, 0x80, 0x80, 0x80, 0x00}
+
+func BenchmarkParseOIDBytes(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseOIDBytes(ifInOctetsContent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseOIDDecodeString(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		oid, _, err := decodeOID(len(ifInOctetsContent), bytes.NewReader(ifInOctetsContent))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := ParseOID(oid.String()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestOIDArc(t *testing.T) {
+	oid := MustParseOID(".1.3.6.1.2.1.2.2.1.2.7")
+
+	for i, expected := range []uint32{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 7} {
+		if arc, ok := oid.Arc(i); !ok || arc != expected {
+			t.Errorf("%d: expected %d, got %d, %v", i, expected, arc, ok)
+		}
+	}
+
+	for _, i := range []int{-1, oid.Len(), 100} {
+		if arc, ok := oid.Arc(i); ok || arc != 0 {
+			t.Errorf("%d: expected no arc, got %d, %v", i, arc, ok)
+		}
+	}
+
+	if oid.Len() != 11 {
+		t.Errorf("expected 11 arcs, got %d", oid.Len())
+	}
+
+	var empty ObjectIdentifier
+	if _, ok := empty.Arc(0); ok || empty.Len() != 0 {
+		t.Errorf("expected no arcs in an empty OID")
+	}
+}
+
+func TestOIDParent(t *testing.T) {
+	oid := MustParseOID(".1.3.6.1.2.1.1.1.0")
+
+	parent, ok := oid.Parent()
+	if !ok || !parent.Equal(sysDescr[:len(sysDescr)-1]) {
+		t.Fatalf("expected .1.3.6.1.2.1.1.1, got %v, %v", parent, ok)
+	}
+
+	// The parent doesn't alias oid
+	if grown := append(parent, 99); oid[len(oid)-1] != 0 || grown[len(grown)-1] != 99 {
+		t.Errorf("appending to the parent modified oid: %v", oid)
+	}
+
+	root, ok := ObjectIdentifier{1}.Parent()
+	if !ok || len(root) != 0 {
+		t.Errorf("expected an empty parent of .1, got %v, %v", root, ok)
+	}
+
+	if _, ok := root.Parent(); ok {
+		t.Errorf("expected no parent of the root")
+	}
+}
+
+func TestOIDCommonPrefix(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected ObjectIdentifier
+	}{
+		{".1.3.6.1.2.1.2.2.1.2.1", ".1.3.6.1.2.1.2.2.1.10.1", ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1}},
+		{".1.3.6.1.2.1", ".1.3.6.1.2.1.1.1.0", ObjectIdentifier{1, 3, 6, 1, 2, 1}},
+		{".1.3.6.1", ".1.3.6.1", ObjectIdentifier{1, 3, 6, 1}},
+		{".1.3.6.1", ".2.3.6.1", ObjectIdentifier{}},
+	} {
+		a, b := MustParseOID(test.a), MustParseOID(test.b)
+
+		prefix := a.CommonPrefix(b)
+		if !prefix.Equal(test.expected) || !b.CommonPrefix(a).Equal(test.expected) {
+			t.Errorf("%s, %s: expected %v, got %v", test.a, test.b, test.expected, prefix)
+		}
+
+		if len(prefix) > 0 {
+			prefix[0] = 99
+			if a[0] == 99 || b[0] == 99 {
+				t.Errorf("%s, %s: the common prefix aliases its operands", test.a, test.b)
+			}
+		}
+	}
+
+	if prefix := ObjectIdentifier(nil).CommonPrefix(sysDescr); len(prefix) != 0 {
+		t.Errorf("expected an empty common prefix with an empty OID, got %v", prefix)
+	}
+}
+
+func TestOIDRange(t *testing.T) {
+	ifDescr := MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+	oids := ifDescr.Range(1, 48)
+	if len(oids) != 48 {
+		t.Fatalf("expected 48 OIDs, got %d", len(oids))
+	}
+
+	for i, oid := range oids {
+		if expected := ifDescr.Child(uint32(i + 1)); !oid.Equal(expected) {
+			t.Errorf("%d: expected %v, got %v", i, expected, oid)
+		}
+	}
+
+	// Neither the OIDs nor the parent share arrays
+	oids[0][0] = 99
+	oids[1] = append(oids[1], 7)
+	if ifDescr[0] != 1 || oids[2][0] != 1 || len(oids[2]) != len(ifDescr)+1 {
+		t.Errorf("expected independent OIDs, got %v and %v", ifDescr, oids[2])
+	}
+
+	if oids := ifDescr.Range(65535, 65535); len(oids) != 1 || oids[0][len(ifDescr)] != 65535 {
+		t.Errorf("expected only .65535, got %v", oids)
+	}
+
+	if oids := ifDescr.Range(2, 1); oids != nil {
+		t.Errorf("expected no OIDs for an empty range, got %v", oids)
+	}
+}
+
+func TestOIDNext(t *testing.T) {
+	ifDescr := MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+	next := ifDescr.Next()
+	if !next.Equal(ifDescr.Child(0)) || next.Compare(ifDescr) <= 0 {
+		t.Errorf("expected %v.0, got %v", ifDescr, next)
+	}
+
+	sibling := ifDescr.NextSibling()
+	if expected := MustParseOID(".1.3.6.1.2.1.2.2.1.3"); !sibling.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, sibling)
+	}
+
+	// Neither aliases the receiver
+	next[0], sibling[0] = 99, 99
+	if ifDescr[0] != 1 || ifDescr[len(ifDescr)-1] != 2 {
+		t.Errorf("expected the receiver to be unchanged, got %v", ifDescr)
+	}
+
+	for _, test := range []struct {
+		oid, expected ObjectIdentifier
+	}{
+		{ObjectIdentifier{1, 3, 1<<32 - 1}, ObjectIdentifier{1, 4}},
+		{ObjectIdentifier{1, 1<<32 - 1, 1<<32 - 1}, ObjectIdentifier{2}},
+		{ObjectIdentifier{1<<32 - 1}, nil},
+		{nil, nil},
+	} {
+		if sibling := test.oid.NextSibling(); !sibling.Equal(test.expected) || (test.expected == nil) != (sibling == nil) {
+			t.Errorf("%v: expected %v, got %v", test.oid, test.expected, sibling)
+		}
+	}
+}
+
+func TestOIDDivergeIndex(t *testing.T) {
+	for _, test := range []struct {
+		a, b     ObjectIdentifier
+		expected int
+	}{
+		{ObjectIdentifier{1, 3, 6, 1}, ObjectIdentifier{1, 3, 6, 1}, 4},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6, 1, 2}, 3},
+		{ObjectIdentifier{1, 3, 6, 1, 2, 1, 2}, ObjectIdentifier{1, 3, 6, 1, 2, 1, 31}, 6},
+		{ObjectIdentifier{1, 3}, ObjectIdentifier{2, 3}, 0},
+		{nil, ObjectIdentifier{1, 3}, 0},
+	} {
+		if n := test.a.DivergeIndex(test.b); n != test.expected || test.b.DivergeIndex(test.a) != n {
+			t.Errorf("%v, %v: expected %d, got %d", test.a, test.b, test.expected, n)
+		}
+	}
+
+	a, b := MustParseOID(".1.3.6.1.2.1.2.2.1.2.1"), MustParseOID(".1.3.6.1.2.1.2.2.1.10.1")
+	if allocs := testing.AllocsPerRun(100, func() { a.DivergeIndex(b) }); allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,89 @@
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An OIDPattern matches OIDs against a dotted OID in which arcs may be
+// "*". A "*" as the last arc matches one or more arcs, such as the
+// index of any row of a column, and elsewhere a single arc.
+type OIDPattern struct {
+	arcs []uint32
+
+	// wild marks the arcs that are "*".
+	wild []bool
+}
+
+// ParseOIDPattern parses a pattern such as ".1.3.6.1.2.1.31.1.1.1.6.*"
+// or ".1.3.6.1.2.1.2.2.1.*.1", in the dotted form ParseOID accepts.
+func ParseOIDPattern(s string) (OIDPattern, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(parts) == 1 && parts[0] == "" {
+		return OIDPattern{}, fmt.Errorf("snmp: invalid OID pattern %q: no sub-identifiers", s)
+	}
+
+	p := OIDPattern{arcs: make([]uint32, len(parts)), wild: make([]bool, len(parts))}
+
+	for i, part := range parts {
+		if part == "*" {
+			p.wild[i] = true
+			continue
+		}
+
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return OIDPattern{}, fmt.Errorf("snmp: invalid OID pattern %q: bad sub-identifier %q at index %d", s, part, i)
+		}
+
+		p.arcs[i] = uint32(n)
+	}
+
+	return p, nil
+}
+
+// MustParseOIDPattern is like ParseOIDPattern but panics on error.
+func MustParseOIDPattern(s string) OIDPattern {
+	p, err := ParseOIDPattern(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Match reports whether oid matches p.
+func (p OIDPattern) Match(oid ObjectIdentifier) bool {
+	n := len(p.arcs)
+	trailing := n > 0 && p.wild[n-1]
+
+	if len(oid) < n || (!trailing && len(oid) > n) {
+		return false
+	}
+
+	for i, arc := range p.arcs {
+		if !p.wild[i] && oid[i] != arc {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns p in the form ParseOIDPattern accepts.
+func (p OIDPattern) String() string {
+	var b strings.Builder
+
+	for i, arc := range p.arcs {
+		b.WriteByte('.')
+
+		if p.wild[i] {
+			b.WriteByte('*')
+		} else {
+			b.WriteString(strconv.FormatUint(uint64(arc), 10))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package snmp
+
+import "testing"
+
+func TestOIDPatternTrailingWildcard(t *testing.T) {
+	p := MustParseOIDPattern(".1.3.6.1.2.1.31.1.1.1.6.*")
+
+	for oid, expected := range map[string]bool{
+		".1.3.6.1.2.1.31.1.1.1.6.1":       true,
+		".1.3.6.1.2.1.31.1.1.1.6.1000001": true,
+		".1.3.6.1.2.1.31.1.1.1.6.1.4.2":   true,
+		".1.3.6.1.2.1.31.1.1.1.6":         false,
+		".1.3.6.1.2.1.31.1.1.1.10.1":      false,
+		".1.3.6.1.2.1.2.2.1.10.1":         false,
+	} {
+		if p.Match(MustParseOID(oid)) != expected {
+			t.Errorf("expected %v matching %s to be %t", p, oid, expected)
+		}
+	}
+}
+
+func TestOIDPatternArcWildcard(t *testing.T) {
+	// Any column of the row with index 1
+	p := MustParseOIDPattern(".1.3.6.1.2.1.2.2.1.*.1")
+
+	for oid, expected := range map[string]bool{
+		".1.3.6.1.2.1.2.2.1.2.1":   true,
+		".1.3.6.1.2.1.2.2.1.10.1":  true,
+		".1.3.6.1.2.1.2.2.1.2.2":   false,
+		".1.3.6.1.2.1.2.2.1.2":     false,
+		".1.3.6.1.2.1.2.2.1.2.1.0": false,
+	} {
+		if p.Match(MustParseOID(oid)) != expected {
+			t.Errorf("expected %v matching %s to be %t", p, oid, expected)
+		}
+	}
+}
+
+func TestParseOIDPattern(t *testing.T) {
+	for s, expected := range map[string]string{".1.3.*.1.*": ".1.3.*.1.*", "1.3.6": ".1.3.6"} {
+		p, err := ParseOIDPattern(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if p.String() != expected {
+			t.Errorf("expected %q, got %q", expected, p.String())
+		}
+	}
+
+	for _, s := range []string{"", ".", ".1.3.x", ".1..3", ".1.3.**"} {
+		if _, err := ParseOIDPattern(s); err == nil {
+			t.Errorf("expected an error parsing %q", s)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetries is the number of retries of a Client from NewClient.
+const defaultRetries = 3
+
+// An Option configures a Client created by NewClient.
+type Option func(*clientOptions)
+
+// clientOptions collects the options given to NewClient,
+// remembering which were set to detect conflicts.
+type clientOptions struct {
+	client *Client
+	port   int
+
+	communitySet bool
+	versionSet   bool
+}
+
+// WithAddr sets the agent address, as for Client.Addr.
+func WithAddr(addr string) Option {
+	return func(o *clientOptions) { o.client.Addr = addr }
+}
+
+// WithPort sets the agent port, replacing any port in the address.
+func WithPort(port int) Option {
+	return func(o *clientOptions) { o.port = port }
+}
+
+// WithCommunity sets the community of SNMPv1 and SNMPv2c requests.
+func WithCommunity(community string) Option {
+	return func(o *clientOptions) {
+		o.client.Community = community
+		o.communitySet = true
+	}
+}
+
+// WithVersion sets the SNMP version: Version1, Version2c, or Version3.
+func WithVersion(version int) Option {
+	return func(o *clientOptions) {
+		o.client.Version = version
+		o.versionSet = true
+	}
+}
+
+// WithTimeout sets how long to wait for each attempt of a request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) { o.client.Timeout = timeout }
+}
+
+// WithRetries sets the number of times a request is resent.
+func WithRetries(retries int) Option {
+	return func(o *clientOptions) { o.client.Retries = retries }
+}
+
+// WithV3User sets the SNMPv3 credentials, which selects Version3
+// unless another version is set.
+func WithV3User(user USMUser) Option {
+	return func(o *clientOptions) { o.client.User = &user }
+}
+
+// WithNetwork sets the transport, "udp" or "tcp".
+func WithNetwork(network string) Option {
+	return func(o *clientOptions) { o.client.Network = network }
+}
+
+// NewClient returns a Client configured by opts. Unless set, it uses
+// SNMPv2c with the community "public", or SNMPv3 if a user is set, a
+// timeout of 5 seconds, 3 retries, and port 161. An error is returned
+// if the address is missing or the options contradict each other, such
+// as a community set together with SNMPv3 credentials.
+func NewClient(opts ...Option) (*Client, error) {
+	o := &clientOptions{client: &Client{Timeout: defaultTimeout, Retries: defaultRetries}}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	c := o.client
+
+	if c.Addr == "" {
+		return nil, errors.New("snmp: NewClient requires an address")
+	}
+
+	if !o.versionSet {
+		c.Version = Version2c
+		if c.User != nil {
+			c.Version = Version3
+		}
+	}
+
+	switch {
+	case c.Version != Version1 && c.Version != Version2c && c.Version != Version3:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, c.Version)
+	case c.Version == Version3 && c.User == nil:
+		return nil, errors.New("snmp: SNMPv3 requires a user")
+	case c.Version != Version3 && c.User != nil:
+		return nil, errors.New("snmp: a user requires SNMPv3")
+	case c.User != nil && o.communitySet:
+		return nil, errors.New("snmp: a community can't be used with SNMPv3 credentials")
+	case c.User != nil && c.User.PrivProtocol != NoPriv && c.User.AuthProtocol == NoAuth:
+		return nil, errors.New("snmp: privacy requires authentication")
+	case c.Timeout <= 0:
+		return nil, errors.New("snmp: timeout must be positive")
+	case c.Retries < 0:
+		return nil, errors.New("snmp: retries can't be negative")
+	case o.port < 0 || o.port > 65535:
+		return nil, fmt.Errorf("snmp: invalid port %d", o.port)
+	case c.Network != "" && c.Network != "udp" && c.Network != "tcp":
+		return nil, fmt.Errorf("snmp: unsupported network %q", c.Network)
+	}
+
+	if c.Version != Version3 && !o.communitySet {
+		c.Community = "public"
+	}
+
+	if o.port > 0 {
+		host := c.Addr
+		if h, _, err := net.SplitHostPort(withDefaultPort(c.Addr, defaultPort)); err == nil {
+			host = h
+		}
+
+		c.Addr = net.JoinHostPort(host, strconv.Itoa(o.port))
+	}
+
+	return c, nil
+}
+
+// ParseTarget returns a Client from NewClient configured by a URI such
+// as "snmp://public@192.0.2.1:161" or "snmpv3://user:auth:priv@host".
+// The scheme is snmp or snmpv2c for SNMPv2c, snmpv1, or snmpv3. With
+// SNMPv1 and SNMPv2c, the user is the community, "public" if none is
+// given. With SNMPv3, the user is followed by the authentication and
+// privacy passphrases, if any, which use SHA and AES unless the query
+// sets auth=MD5 or priv=DES. Colons in passphrases must be escaped as
+// %3A. Errors don't include the URI, so as not to reveal credentials.
+func ParseTarget(uri string) (*Client, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.New("snmp: invalid target: malformed URI")
+	}
+
+	if u.Opaque != "" || (u.Path != "" && u.Path != "/") || u.Fragment != "" {
+		return nil, errors.New("snmp: invalid target: unexpected path")
+	}
+
+	opts := []Option{WithAddr(u.Host)}
+
+	var user []string
+	if u.User != nil {
+		// url.Userinfo splits at the first colon only, and unescapes
+		// the parts, so the user information is split here
+		authority := uri[len(u.Scheme)+len("://"):]
+		if i := strings.IndexAny(authority, "/?#"); i >= 0 {
+			authority = authority[:i]
+		}
+
+		userinfo := authority[:strings.LastIndex(authority, "@")]
+
+		for _, part := range strings.Split(userinfo, ":") {
+			part, err := url.PathUnescape(part)
+			if err != nil {
+				return nil, errors.New("snmp: invalid target: malformed credentials")
+			}
+
+			user = append(user, part)
+		}
+	}
+
+	switch u.Scheme {
+	case "snmp", "snmpv1", "snmpv2c":
+		if len(user) > 1 {
+			return nil, fmt.Errorf("snmp: invalid target: %s takes only a community", u.Scheme)
+		}
+
+		if u.RawQuery != "" {
+			return nil, fmt.Errorf("snmp: invalid target: %s takes no query", u.Scheme)
+		}
+
+		version := Version2c
+		if u.Scheme == "snmpv1" {
+			version = Version1
+		}
+
+		opts = append(opts, WithVersion(version))
+
+		if len(user) == 1 {
+			opts = append(opts, WithCommunity(user[0]))
+		}
+
+	case "snmpv3":
+		usm, err := parseTargetUser(user, u.Query())
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, WithVersion(Version3), WithV3User(usm))
+
+	default:
+		return nil, fmt.Errorf("snmp: invalid target: unsupported scheme %q", u.Scheme)
+	}
+
+	return NewClient(opts...)
+}
+
+// parseTargetUser returns the SNMPv3 user of the parts of the user
+// information of a ParseTarget URI and the protocols of its query.
+func parseTargetUser(parts []string, query url.Values) (USMUser, error) {
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return USMUser{}, errors.New("snmp: invalid target: snmpv3 takes user[:auth[:priv]]")
+	}
+
+	user := USMUser{Name: parts[0], AuthProtocol: SHA, PrivProtocol: AES}
+
+	for key, values := range query {
+		if len(values) != 1 {
+			return USMUser{}, fmt.Errorf("snmp: invalid target: repeated %s", key)
+		}
+
+		switch value := strings.ToUpper(values[0]); {
+		case key == "auth" && value == "MD5":
+			user.AuthProtocol = MD5
+		case key == "auth" && value == "SHA":
+			user.AuthProtocol = SHA
+		case key == "priv" && value == "DES":
+			user.PrivProtocol = DES
+		case key == "priv" && value == "AES":
+			user.PrivProtocol = AES
+		default:
+			return USMUser{}, fmt.Errorf("snmp: invalid target: unsupported %s=%s", key, values[0])
+		}
+	}
+
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+
+	user.AuthPassphrase, user.PrivPassphrase = parts[1], parts[2]
+
+	// NewClient rejects privacy without authentication
+	if user.AuthPassphrase == "" {
+		user.AuthProtocol = NoAuth
+	}
+
+	if user.PrivPassphrase == "" {
+		user.PrivProtocol = NoPriv
+	}
+
+	return user, nil
+}
@@ -1,6 +1,8 @@
 package snmp
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 )
 
@@ -13,16 +15,49 @@ type PDU struct {
 	varbinds    []Varbind
 }
 
+// Varbinds returns the variable bindings of a PDU.
 func (p PDU) Varbinds() []Varbind {
 	return p.varbinds
 }
 
+// RequestID returns the request-id of a PDU.
+func (p PDU) RequestID() int {
+	return p.requestID
+}
+
+// ErrorStatus returns the error-status of a PDU.
+func (p PDU) ErrorStatus() ErrorStatus {
+	return ErrorStatus(p.err)
+}
+
+// ErrorIndex returns the error-index of a PDU. It is the 1-based
+// index of the variable binding that caused the error, if any.
+func (p PDU) ErrorIndex() int {
+	return p.errIndex
+}
+
+// Err returns an SNMPError for the error-status of a PDU,
+// or nil if it is NoError. An error-index that is negative or
+// beyond the variable bindings gives an SNMPError without an
+// Index or OID.
+func (p PDU) Err() error {
+	if p.err == int(NoError) {
+		return nil
+	}
+
+	err := SNMPError{Status: ErrorStatus(p.err), RawIndex: p.errIndex}
+	if p.errIndex > 0 && p.errIndex <= len(p.varbinds) {
+		err.Index = p.errIndex
+		err.OID = p.varbinds[p.errIndex-1].OID
+	}
+
+	return err
+}
+
 func newPDU(requestID int, err int, errIndex int, varbinds []Varbind) PDU {
 	varbindsSequence := Sequence{}
 	for _, v := range varbinds {
-		varbindsSequence = append(varbindsSequence, Sequence{
-			v.OID, v.value,
-		})
+		varbindsSequence = append(varbindsSequence, v)
 	}
 
 	rawSequence := []DataType{
@@ -41,6 +76,27 @@ func newPDU(requestID int, err int, errIndex int, varbinds []Varbind) PDU {
 	}
 }
 
+// encode encodes a PDU with the given PDU type as its header.
+func (p PDU) encode(pduType byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	for _, entry := range p.rawSequence {
+		encodedEntry, err := entry.Encode()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = buf.Write(encodedEntry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seqLength := buf.Len()
+
+	return append(encodeHeaderSequence(pduType, seqLength), buf.Bytes()...), nil
+}
+
 func decodePDU(length int, r io.Reader) (PDU, int, error) {
 	pdu := PDU{}
 
@@ -48,7 +104,17 @@ func decodePDU(length int, r io.Reader) (PDU, int, error) {
 	bytesRead := 0
 
 	for seqBytes < length {
-		item, read, err := decode(r)
+		var item DataType
+		var read int
+		var err error
+
+		// The variable bindings are the fourth element of a PDU
+		if lr, ok := r.(*lenientReader); ok && (lr.opts.ContinueOnVarbindError || lr.opts.MaxVarbinds > 0) && len(pdu.rawSequence) == 3 {
+			item, read, err = decodeVarbindsLenient(lr)
+		} else {
+			item, read, err = decode(r)
+		}
+
 		if read > 0 && item != nil {
 			pdu.rawSequence = append(pdu.rawSequence, item)
 			bytesRead += read
@@ -60,40 +126,144 @@ func decodePDU(length int, r io.Reader) (PDU, int, error) {
 		}
 	}
 
+	if seqBytes > length {
+		return pdu, bytesRead, errOverrun(length, seqBytes)
+	}
+
+	pdu, err := pduFromSequence(pdu.rawSequence)
+	return pdu, bytesRead, err
+}
+
+// pduFromSequence converts the decoded elements of a PDU into a PDU.
+func pduFromSequence(rawSequence []DataType) (PDU, error) {
+	pdu := PDU{rawSequence: rawSequence}
+
+	if len(pdu.rawSequence) != 4 {
+		return pdu, ErrDecodingType
+	}
+
 	reqID, ok := pdu.rawSequence[0].(Int)
 	if !ok {
-		return pdu, bytesRead, ErrDecodingType
+		return pdu, ErrDecodingType
 	}
 	pdu.requestID = int(reqID)
 
 	errorCode, ok := pdu.rawSequence[1].(Int)
 	if !ok {
-		return pdu, bytesRead, ErrDecodingType
+		return pdu, ErrDecodingType
 	}
 	pdu.err = int(errorCode)
 
 	errIndex, ok := pdu.rawSequence[2].(Int)
 	if !ok {
-		return pdu, bytesRead, ErrDecodingType
+		return pdu, ErrDecodingType
 	}
 	pdu.errIndex = int(errIndex)
 
 	varbindSeq, ok := pdu.rawSequence[3].(Sequence)
 	if !ok {
-		return pdu, bytesRead, ErrDecodingType
+		return pdu, ErrDecodingType
 	}
 	for _, varbindElem := range varbindSeq {
-		varbindPair, ok := varbindElem.(Sequence)
-		if !ok {
-			return pdu, bytesRead, ErrDecodingType
+		v, err := varbindFromSequence(varbindElem)
+		if err != nil {
+			return pdu, err
 		}
 
-		oid, ok := varbindPair[0].(ObjectIdentifier)
-		if ok {
-			val := varbindPair[1]
-			pdu.varbinds = append(pdu.varbinds, NewVarbind(oid, val))
+		pdu.varbinds = append(pdu.varbinds, v)
+	}
+
+	return pdu, nil
+}
+
+// lenientReader reads a message decoded with the options opts. If
+// opts.ContinueOnVarbindError or opts.MaxVarbinds is set, its variable
+// bindings are decoded by decodeVarbindsLenient, collecting the errors
+// of those that fail.
+type lenientReader struct {
+	*bytes.Reader
+	opts DecodeOptions
+	errs VarbindErrors
+}
+
+// decodeVarbindsLenient decodes a SEQUENCE of variable bindings from r,
+// up to r.opts.MaxVarbinds of them if it is set. With
+// r.opts.ContinueOnVarbindError, a binding that fails to decode is
+// skipped and its error is added to r.errs, as long as its own header
+// frames it within the sequence. It returns the bindings that decoded,
+// the number of bytes read, and an error.
+func decodeVarbindsLenient(r *lenientReader) (DataType, int, error) {
+	t, length, bytesRead, err := decodeHeader(r)
+	if err != nil {
+		return nil, bytesRead, err
+	}
+
+	if t != TypeSequence {
+		return nil, bytesRead, ErrDecodingType
+	}
+
+	seq := Sequence{}
+
+	for index, seqBytes := 1, 0; seqBytes < length; index++ {
+		if max := r.opts.MaxVarbinds; max > 0 && index > max {
+			return seq, bytesRead, fmt.Errorf("%w: more than %d in a PDU", ErrTooManyBindings, max)
 		}
+
+		itemType, itemLength, n, err := decodeHeader(r)
+		bytesRead += n
+		seqBytes += n
+
+		if err != nil {
+			return seq, bytesRead, err
+		}
+
+		if seqBytes+itemLength > length {
+			return seq, bytesRead, errOverrun(length, seqBytes+itemLength)
+		}
+
+		content, n, err := readContent(itemLength, r)
+		bytesRead += n
+		seqBytes += n
+
+		if err != nil {
+			return seq, bytesRead, err
+		}
+
+		item, err := decodeVarbindContent(itemType, content, r.opts)
+		if err != nil && !r.opts.ContinueOnVarbindError {
+			return seq, bytesRead, err
+		}
+
+		if err != nil {
+			r.errs = append(r.errs, VarbindError{Index: index, Err: err})
+			continue
+		}
+
+		seq = append(seq, item)
+	}
+
+	return seq, bytesRead, nil
+}
+
+// decodeVarbindContent decodes the content of a variable binding with
+// the given type, checking that it is an OID and value pair.
+func decodeVarbindContent(t byte, content []byte, opts DecodeOptions) (Sequence, error) {
+	if t != TypeSequence {
+		return nil, ErrDecodingType
+	}
+
+	pair, _, err := decodeSequence(len(content), &lenientReader{Reader: bytes.NewReader(content), opts: opts})
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("%w: a value overruns its variable binding", ErrTruncated)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := varbindFromSequence(pair); err != nil {
+		return nil, err
 	}
 
-	return pdu, bytesRead, nil
+	return pair, nil
 }
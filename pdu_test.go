@@ -0,0 +1,378 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestGetRequestEncoding(t *testing.T) {
+	b, err := NewGetRequest(1, MustParseOID(".1.3.6.1.2.1.1.3.0")).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GetRequest for sysUpTime.0 with request-id 1
+	if expected := []byte{
+		0xa0, 0x19,
+		0x02, 0x01, 0x01,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x0e,
+		0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00,
+		0x05, 0x00,
+	}; !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+}
+
+func TestNegativeRequestID(t *testing.T) {
+	for id, content := range map[int32][]byte{
+		-1:             {0xff},
+		-128:           {0x80},
+		-129:           {0xff, 0x7f},
+		math.MinInt32:  {0x80, 0x00, 0x00, 0x00},
+		math.MaxInt32:  {0x7f, 0xff, 0xff, 0xff},
+		-0x12345678:    {0xed, 0xcb, 0xa9, 0x88},
+		-256:           {0xff, 0x00},
+		-(1 << 23) - 1: {0xff, 0x7f, 0xff, 0xff},
+	} {
+		m := Message{Version: Version2c, Community: "public", PDU: NewGetRequest(id, MustParseOID(".1.3.6.1.2.1.1.3.0"))}
+
+		b, err := m.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var e Encoder
+		if fast, err := e.EncodeMessage(&m); err != nil || !bytes.Equal(fast, b) {
+			t.Errorf("%d: expected the Encoder to agree, got % x and %v", id, fast, err)
+		}
+
+		// The request-id follows the version and community
+		header := append([]byte{0x02, byte(len(content))}, content...)
+		if !bytes.Equal(b[15:15+len(header)], header) {
+			t.Errorf("%d: expected the request-id % x, got % x", id, header, b[15:15+len(header)])
+		}
+
+		decoded, _, err := decodeMessage(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := decoded.PDU.(GetRequest).RequestID(); got != int(id) {
+			t.Errorf("expected request-id %d, got %d", id, got)
+		}
+	}
+}
+
+func TestGetNextRequestEncoding(t *testing.T) {
+	b, err := NewGetNextRequest(0x1234, MustParseOID(".1.3.6.1.2.1.2.2.1.2")).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GetNextRequest for ifDescr with request-id 0x1234
+	if expected := []byte{
+		0xa1, 0x1b,
+		0x02, 0x02, 0x12, 0x34,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x0f,
+		0x30, 0x0d,
+		0x06, 0x09, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x02,
+		0x05, 0x00,
+	}; !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+}
+
+func TestSetRequestEncoding(t *testing.T) {
+	req := NewSetRequest(2,
+		NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.7.3"), Int(2)),
+		NewVarbind(MustParseOID(".1.3.6.1.2.1.1.5.0"), String("core1")),
+	)
+
+	b, err := req.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SetRequest for ifAdminStatus.3 = 2 and sysName.0 = "core1"
+	if expected := []byte{
+		0xa3, 0x2f,
+		0x02, 0x01, 0x02,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x24,
+		0x30, 0x0f,
+		0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x07, 0x03,
+		0x02, 0x01, 0x02,
+		0x30, 0x11,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00,
+		0x04, 0x05, 'c', 'o', 'r', 'e', '1',
+	}; !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setReq, ok := decoded.(SetRequest)
+	if !ok {
+		t.Fatalf("expected a SetRequest, got %T", decoded)
+	}
+
+	varbinds := setReq.Varbinds()
+	if len(varbinds) != 2 {
+		t.Fatalf("expected 2 varbinds, got %d", len(varbinds))
+	}
+
+	if varbinds[0].Value() != Int(2) || varbinds[1].Value() != String("core1") {
+		t.Errorf("unexpected decoded values %v and %v", varbinds[0].Value(), varbinds[1].Value())
+	}
+}
+
+func TestGetResponseDecoding(t *testing.T) {
+	// GetResponse with request-id 42, error-status noSuchName,
+	// error-index 2, and bindings for sysDescr.0 and sysObjectID.0
+	b := []byte{
+		0xa2, 0x2c,
+		0x02, 0x01, 0x2a,
+		0x02, 0x01, 0x02,
+		0x02, 0x01, 0x02,
+		0x30, 0x21,
+		0x30, 0x11,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+		0x04, 0x05, 'r', 'o', 'u', 't', 'e',
+		0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x02, 0x00,
+		0x05, 0x00,
+	}
+
+	decoded, n, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(b) {
+		t.Errorf("expected %d bytes read, got %d", len(b), n)
+	}
+
+	res, ok := decoded.(GetResponse)
+	if !ok {
+		t.Fatalf("expected a GetResponse, got %T", decoded)
+	}
+
+	if res.RequestID() != 42 {
+		t.Errorf("expected request-id 42, got %d", res.RequestID())
+	}
+
+	if res.ErrorStatus() != NoSuchName {
+		t.Errorf("expected error-status %v, got %v", NoSuchName, res.ErrorStatus())
+	}
+
+	if res.ErrorStatus().Error() != "snmp: noSuchName" {
+		t.Errorf("expected error message %q, got %q", "snmp: noSuchName", res.ErrorStatus().Error())
+	}
+
+	if res.ErrorIndex() != 2 {
+		t.Errorf("expected error-index 2, got %d", res.ErrorIndex())
+	}
+
+	varbinds := res.Varbinds()
+	if len(varbinds) != 2 {
+		t.Fatalf("expected 2 varbinds, got %d", len(varbinds))
+	}
+
+	if failed := varbinds[res.ErrorIndex()-1].OID; !failed.Equal(MustParseOID(".1.3.6.1.2.1.1.2.0")) {
+		t.Errorf("expected error-index to identify sysObjectID.0, got %v", failed)
+	}
+
+	err = res.Err()
+
+	var snmpErr SNMPError
+	if !errors.As(err, &snmpErr) {
+		t.Fatalf("expected an SNMPError, got %v", err)
+	}
+
+	if snmpErr.Status != NoSuchName || snmpErr.Index != 2 || !snmpErr.OID.Equal(MustParseOID(".1.3.6.1.2.1.1.2.0")) {
+		t.Errorf("unexpected SNMPError %+v", snmpErr)
+	}
+
+	if !errors.Is(err, NoSuchName) {
+		t.Error("expected the SNMPError to match NoSuchName")
+	}
+
+	if expected := "snmp: noSuchName for varbind 2 (.1.3.6.1.2.1.1.2.0)"; err.Error() != expected {
+		t.Errorf("expected error message %q, got %q", expected, err.Error())
+	}
+}
+
+func TestGetResponseErrorIndexOutOfRange(t *testing.T) {
+	for _, index := range []byte{0x07, 0xff} {
+		// GetResponse with error-status genErr, the error-index,
+		// and a single binding for sysDescr.0
+		b := []byte{
+			0xa2, 0x1d,
+			0x02, 0x01, 0x2a,
+			0x02, 0x01, 0x05,
+			0x02, 0x01, index,
+			0x30, 0x12,
+			0x30, 0x10,
+			0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+			0x04, 0x04, 'r', 'o', 'u', 't',
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw := int(int8(index))
+
+		var snmpErr SNMPError
+		if !errors.As(decoded.(GetResponse).Err(), &snmpErr) {
+			t.Fatalf("error-index %d: expected an SNMPError", raw)
+		}
+
+		if snmpErr.Status != GenErr || snmpErr.Index != 0 || snmpErr.OID != nil || snmpErr.RawIndex != raw {
+			t.Errorf("error-index %d: unexpected SNMPError %+v", raw, snmpErr)
+		}
+
+		if expected := fmt.Sprintf("snmp: genErr (error-index %d out of range)", raw); snmpErr.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, snmpErr.Error())
+		}
+	}
+}
+
+func TestErrorStatusNames(t *testing.T) {
+	if InconsistentName.String() != "inconsistentName" || NoAccess.String() != "noAccess" {
+		t.Errorf("unexpected names %v and %v", InconsistentName, NoAccess)
+	}
+
+	if ErrorStatus(19).String() != "errorStatus(19)" {
+		t.Errorf("unexpected name %v for an unknown error-status", ErrorStatus(19))
+	}
+
+	if (PDU{}).Err() != nil {
+		t.Error("expected no error for noError")
+	}
+}
+
+func TestGetBulkRequestEncoding(t *testing.T) {
+	req := NewGetBulkRequest(7, 0, 10, MustParseOID(".1.3.6.1.2.1.2.2.1.2"))
+
+	b, err := req.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GetBulkRequest for ifDescr with non-repeaters 0 and max-repetitions 10
+	if expected := []byte{
+		0xa5, 0x1a,
+		0x02, 0x01, 0x07,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x0a,
+		0x30, 0x0f,
+		0x30, 0x0d,
+		0x06, 0x09, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x02,
+		0x05, 0x00,
+	}; !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bulk, ok := decoded.(GetBulkRequest)
+	if !ok {
+		t.Fatalf("expected a GetBulkRequest, got %T", decoded)
+	}
+
+	if bulk.NonRepeaters() != 0 || bulk.MaxRepetitions() != 10 {
+		t.Errorf("expected non-repeaters 0 and max-repetitions 10, got %d and %d",
+			bulk.NonRepeaters(), bulk.MaxRepetitions())
+	}
+}
+
+func TestGetBulkResponseDecoding(t *testing.T) {
+	ifDescr := MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+	res := GetResponse{PDU: newPDU(7, 0, 0, []Varbind{
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(ifDescr.Child(3), String("eth1")),
+	})}
+
+	b, err := res.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	varbinds := decoded.(GetResponse).Varbinds()
+	if len(varbinds) != 3 {
+		t.Fatalf("expected 3 varbinds, got %d", len(varbinds))
+	}
+
+	for i, name := range []string{"lo", "eth0", "eth1"} {
+		if !varbinds[i].OID.Equal(ifDescr.Child(uint32(i+1))) || varbinds[i].Value() != String(name) {
+			t.Errorf("unexpected varbind %v = %v at position %d", varbinds[i].OID, varbinds[i].Value(), i)
+		}
+	}
+}
+
+func TestBulkColumns(t *testing.T) {
+	ifDescr := MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+	// The last object of the agent's MIB view
+	last := MustParseOID(".1.3.6.1.6.3.16.1.5.2.1.6.0")
+
+	// Three repetitions of ifDescr and an object that ends the MIB
+	// view after one, of which the agent pads the column with
+	// endOfMibView, and a fourth repetition truncated after ifDescr
+	varbinds := []Varbind{
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(last, Int(1)),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(last, EndOfMIBView),
+		NewVarbind(ifDescr.Child(3), String("eth1")),
+		NewVarbind(last, EndOfMIBView),
+		NewVarbind(ifDescr.Child(4), String("eth2")),
+	}
+
+	columns := BulkColumns([]ObjectIdentifier{ifDescr, last[:len(last)-2]}, varbinds)
+	if len(columns) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(columns))
+	}
+
+	if len(columns[0]) != 4 {
+		t.Fatalf("expected 4 bindings in the first column, got %v", columns[0])
+	}
+
+	for i, v := range columns[0] {
+		if !v.OID.Equal(ifDescr.Child(uint32(i + 1))) {
+			t.Errorf("unexpected binding %v at position %d of the first column", v, i)
+		}
+	}
+
+	if len(columns[1]) != 2 || columns[1][0].Value() != Int(1) || columns[1][1].Value() != EndOfMIBView {
+		t.Errorf("expected the second column to end after one binding, got %v", columns[1])
+	}
+
+	if columns := BulkColumns(nil, varbinds); columns != nil {
+		t.Errorf("expected no columns without repeaters, got %v", columns)
+	}
+}
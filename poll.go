@@ -0,0 +1,102 @@
+package snmp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Target is an agent polled by GetAll.
+type Target struct {
+	Addr      string
+	Community string
+	Version   int
+
+	// Timeout and Retries are used like those of a Client.
+	Timeout time.Duration
+	Retries int
+}
+
+// Result is the outcome of polling a Target, or of Client.GetWithStats.
+type Result struct {
+	Varbinds []Varbind
+	Err      error
+
+	// RoundTripTime is the time from sending the last attempt of the
+	// request to receiving the response, or 0 if there was none.
+	RoundTripTime time.Duration
+
+	// Attempts is the number of times the request was sent,
+	// including SNMPv3 discovery.
+	Attempts int
+
+	// RequestSize and ResponseSize are the sizes in bytes of the last
+	// message sent and of the response, or 0 if there was none.
+	RequestSize  int
+	ResponseSize int
+}
+
+// GetAll gets oids from every target, polling up to concurrency
+// targets at a time, and returns the results keyed by target address,
+// with the statistics of GetWithStats.
+// Targets that aren't polled before ctx is done get ctx.Err() as their
+// error. GetAll returns once every poll has finished.
+func GetAll(ctx context.Context, targets []Target, oids []ObjectIdentifier, concurrency int) map[string]Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		results = make(map[string]Result, len(targets))
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+
+	queue := make(chan Target)
+
+	for i := 0; i < concurrency && i < len(targets); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for target := range queue {
+				c := &Client{
+					Addr:      target.Addr,
+					Community: target.Community,
+					Version:   target.Version,
+					Timeout:   target.Timeout,
+					Retries:   target.Retries,
+				}
+
+				res, _ := c.GetWithStatsContext(ctx, oids...)
+
+				mu.Lock()
+				results[target.Addr] = res
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i, target := range targets {
+		select {
+		case queue <- target:
+			continue
+		case <-ctx.Done():
+		}
+
+		// Record the targets that weren't polled
+		mu.Lock()
+		for _, t := range targets[i:] {
+			results[t.Addr] = Result{Err: ctx.Err()}
+		}
+		mu.Unlock()
+
+		break
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return results
+}
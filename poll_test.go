@@ -0,0 +1,81 @@
+package snmp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetAll(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	var targets []Target
+
+	for i := 0; i < 8; i++ {
+		agent := newStubAgent(t, NewVarbind(sysDescr, String("agent")), NewVarbind(sysUpTime, TimeTicks(i)))
+
+		agent.handle(func(m *Message) []*Message {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			return []*Message{agent.respond(m)}
+		})
+
+		targets = append(targets, Target{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second})
+	}
+
+	results := GetAll(context.Background(), targets, []ObjectIdentifier{sysDescr, sysUpTime}, 3)
+
+	if len(results) != len(targets) {
+		t.Fatalf("expected %d results, got %d", len(targets), len(results))
+	}
+
+	for i, target := range targets {
+		res := results[target.Addr]
+		if res.Err != nil {
+			t.Errorf("%s: %v", target.Addr, res.Err)
+			continue
+		}
+
+		if len(res.Varbinds) != 2 || res.Varbinds[1].Value() != TimeTicks(i) {
+			t.Errorf("%s: unexpected varbinds %v", target.Addr, res.Varbinds)
+		}
+
+		if res.Attempts != 1 || res.RoundTripTime <= 0 {
+			t.Errorf("%s: expected the statistics of 1 attempt, got %+v", target.Addr, res)
+		}
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max > 3 {
+		t.Errorf("expected at most 3 polls at a time, got %d", max)
+	}
+}
+
+func TestGetAllCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	targets := []Target{
+		{Addr: "127.0.0.1:1", Version: Version2c},
+		{Addr: "127.0.0.1:2", Version: Version2c},
+		{Addr: "127.0.0.1:3", Version: Version2c},
+	}
+
+	results := GetAll(ctx, targets, []ObjectIdentifier{sysDescr}, 1)
+
+	for _, target := range targets {
+		if err := results[target.Addr].Err; err != context.Canceled {
+			t.Errorf("%s: expected context.Canceled, got %v", target.Addr, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package snmp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// placeholderRequestID is the request ID a PreparedGet is encoded with.
+// It takes the four bytes of content that any ID from reserve fits in.
+const placeholderRequestID = 0x7fffffff
+
+// PreparedGet is a GetRequest for a fixed set of OIDs, encoded once by
+// Client.Prepare so that each Do only sets a fresh request ID in the
+// encoding instead of encoding the request again. It may be used
+// concurrently.
+type PreparedGet struct {
+	client *Client
+	oids   []ObjectIdentifier
+
+	// template is the encoded request, with the 4 bytes of the
+	// content of its request-id at idOffset.
+	template []byte
+	idOffset int
+}
+
+// Prepare encodes a GetRequest for oids, which is sent by the Do method
+// of the PreparedGet. The Client must not be modified while the
+// PreparedGet is in use. Prepare doesn't support SNMPv3, where each
+// message is authenticated.
+func (c *Client) Prepare(oids ...ObjectIdentifier) (*PreparedGet, error) {
+	if c.Version == Version3 {
+		return nil, errors.New("snmp: Prepare doesn't support SNMPv3")
+	}
+
+	if err := c.checkDuplicates(oids); err != nil {
+		return nil, err
+	}
+
+	template, err := c.encodeRequest(newGetRequest(placeholderRequestID, nullVarbinds(oids)))
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := requestIDOffset(template)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]ObjectIdentifier, len(oids))
+	for i, oid := range oids {
+		kept[i] = oid.Clone()
+	}
+
+	return &PreparedGet{
+		client:   c,
+		oids:     kept,
+		template: template,
+		idOffset: offset,
+	}, nil
+}
+
+// Do sends the request and returns the variable bindings of the
+// response, like Client.Get.
+func (p *PreparedGet) Do() ([]Varbind, error) {
+	return p.DoContext(context.Background())
+}
+
+// DoContext is like Do but aborts when ctx is done.
+func (p *PreparedGet) DoContext(ctx context.Context) ([]Varbind, error) {
+	conn, err := p.client.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return p.do(ctx, conn.mx)
+}
+
+// do sends the request on mx, like Client.get. An OID that an agent
+// fails with noSuchName is left out of a request prepared again for
+// the rest, as Client.get does.
+func (p *PreparedGet) do(ctx context.Context, mx *mux) ([]Varbind, error) {
+	c := p.client
+
+	varbinds, err := p.doOnce(ctx, mx)
+
+	return c.retryNoSuchName(p.oids, varbinds, err, func(rest []ObjectIdentifier) ([]Varbind, error) {
+		q, err := c.Prepare(rest...)
+		if err != nil {
+			return nil, err
+		}
+
+		return q.do(ctx, mx)
+	})
+}
+
+// doOnce sends the request on mx once.
+func (p *PreparedGet) doOnce(ctx context.Context, mx *mux) ([]Varbind, error) {
+	c := p.client
+
+	id := mx.reserve()
+	defer mx.release(id)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m, err := c.exchange(ctx, mx, id, p.packet(id), func(m *Message, _ []byte) bool {
+		res, ok := m.PDU.(GetResponse)
+		return ok && res.requestID == int(id)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := getResponse(m)
+	if err != nil {
+		return nil, err
+	}
+
+	varbinds := res.Varbinds()
+
+	if err := c.checkOIDs(p.oids, varbinds); err != nil {
+		return nil, err
+	}
+
+	return varbinds, nil
+}
+
+// packet returns a copy of the encoded request with the request ID id,
+// which must not be negative.
+func (p *PreparedGet) packet(id int32) []byte {
+	packet := append([]byte(nil), p.template...)
+	binary.BigEndian.PutUint32(packet[p.idOffset:], uint32(id))
+
+	return packet
+}
+
+// requestIDOffset returns the offset of the content of the request-id
+// of the SNMPv1 or SNMPv2c message packet, which must be 4 bytes long.
+func requestIDOffset(packet []byte) (int, error) {
+	rest := packet
+
+	_, _, n, err := splitHeader(rest)
+	if err != nil {
+		return 0, err
+	}
+
+	rest = rest[n:]
+
+	// The version and community
+	for _, tag := range []byte{TypeInteger, TypeString} {
+		if _, rest, err = expectTLV(rest, tag); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, _, n, err = splitHeader(rest); err != nil {
+		return 0, err
+	}
+
+	rest = rest[n:]
+
+	tag, length, n, err := splitHeader(rest)
+	if err != nil {
+		return 0, err
+	}
+
+	if tag != TypeInteger || length != 4 {
+		return 0, fmt.Errorf("%w: request-id of %d bytes", ErrDecodingType, length)
+	}
+
+	return len(packet) - len(rest) + n, nil
+}
+
+// EncodeGet returns the encoding of the GetRequest for oids that c
+// would send with the request ID requestID, without sending it, such as
+// to write it to a file. Like Prepare, it doesn't support SNMPv3, where
+// messages depend on the state of the agent's engine.
+func (c *Client) EncodeGet(requestID int32, oids ...ObjectIdentifier) ([]byte, error) {
+	return c.encodeRequest(NewGetRequest(requestID, oids...))
+}
+
+// EncodeGetNext is like EncodeGet for a GetNextRequest.
+func (c *Client) EncodeGetNext(requestID int32, oids ...ObjectIdentifier) ([]byte, error) {
+	return c.encodeRequest(NewGetNextRequest(requestID, oids...))
+}
+
+// EncodeGetBulk is like EncodeGet for a GetBulkRequest,
+// which requires SNMPv2c.
+func (c *Client) EncodeGetBulk(requestID int32, nonRepeaters, maxRepetitions int, oids ...ObjectIdentifier) ([]byte, error) {
+	if c.Version == Version1 {
+		return nil, errors.New("snmp: GetBulkRequest requires SNMPv2c")
+	}
+
+	return c.encodeRequest(NewGetBulkRequest(requestID, nonRepeaters, maxRepetitions, oids...))
+}
+
+// EncodeSet is like EncodeGet for a SetRequest. If c.MIB is set, the
+// values are checked as Set does.
+func (c *Client) EncodeSet(requestID int32, varbinds ...Varbind) ([]byte, error) {
+	if c.MIB != nil {
+		for _, v := range varbinds {
+			if err := c.MIB.CheckValue(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c.encodeRequest(NewSetRequest(requestID, varbinds...))
+}
+
+// encodeRequest returns the encoding of the message of c with pdu.
+func (c *Client) encodeRequest(pdu DataType) ([]byte, error) {
+	if c.Version == Version3 {
+		return nil, errors.New("snmp: encoding requests without sending them doesn't support SNMPv3")
+	}
+
+	if err := c.EncodeOptions.validate(); err != nil {
+		return nil, err
+	}
+
+	packet, err := Message{
+		Version:   c.Version,
+		Community: c.Community,
+		PDU:       c.EncodeOptions.pdu(pdu),
+	}.Encode()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if maxSize := c.maxMessageSize(); maxSize > 0 && len(packet) > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrMessageTooLarge, len(packet), maxSize)
+	}
+
+	return packet, nil
+}
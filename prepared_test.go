@@ -0,0 +1,225 @@
+package snmp
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPreparedGet(t *testing.T) {
+	agent := newStubAgent(t,
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(sysUpTime, TimeTicks(12345)),
+	)
+
+	var (
+		mu  sync.Mutex
+		ids []int
+	)
+
+	agent.handle(func(m *Message) []*Message {
+		mu.Lock()
+		ids = append(ids, m.PDU.(GetRequest).requestID)
+		mu.Unlock()
+
+		return []*Message{agent.respond(m)}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	p, err := c.Prepare(sysDescr, sysUpTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		varbinds, err := p.Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(varbinds) != 2 || varbinds[0].Value() != String("test agent") || varbinds[1].Value() != TimeTicks(12345) {
+			t.Errorf("unexpected varbinds %v", varbinds)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(ids) != 2 || ids[0] == ids[1] {
+		t.Errorf("expected 2 requests with fresh IDs, got %v", ids)
+	}
+}
+
+func TestPreparedGetNoSuchNameV2c(t *testing.T) {
+	agent := newStubAgent(t, NewVarbind(sysDescr, String("test agent")))
+
+	// The agent fails a request for a missing object as SNMPv1 does
+	agent.handle(func(m *Message) []*Message {
+		res := agent.respond(m)
+		req := m.PDU.(GetRequest)
+
+		for i, v := range res.PDU.(GetResponse).varbinds {
+			if v.IsException() {
+				res.PDU = GetResponse{PDU: newPDU(req.requestID, int(NoSuchName), i+1, req.varbinds)}
+				break
+			}
+		}
+
+		return []*Message{res}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	expected, err := c.Get(sysDescr, sysUpTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.Prepare(sysDescr, sysUpTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	varbinds, err := p.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(varbinds, expected) || varbinds[1].Value() != NoSuchObject {
+		t.Errorf("expected %v like Get, got %v", expected, varbinds)
+	}
+}
+
+func TestPreparedGetPacket(t *testing.T) {
+	c := &Client{Community: "public", Version: Version1}
+
+	p, err := c.Prepare(sysDescr, sysUpTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int32{1, 0x7f, 0x80, 0x1234, 0x7fffffff} {
+		m, err := Unmarshal(p.packet(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, ok := m.PDU.(GetRequest)
+		if !ok || req.requestID != int(id) {
+			t.Fatalf("expected a GetRequest with ID %d, got %+v", id, m.PDU)
+		}
+
+		if varbinds := req.Varbinds(); len(varbinds) != 2 || !varbinds[0].OID.Equal(sysDescr) || !varbinds[1].OID.Equal(sysUpTime) {
+			t.Errorf("unexpected varbinds %v", varbinds)
+		}
+	}
+
+	c.Version = Version3
+	if _, err := c.Prepare(sysDescr); err == nil {
+		t.Error("expected an error preparing an SNMPv3 request")
+	}
+}
+
+// pollOIDs returns the OIDs of ten columns of ifEntry for one row.
+func pollOIDs() []ObjectIdentifier {
+	oids := make([]ObjectIdentifier, 10)
+	for i := range oids {
+		oids[i] = MustParseOID(".1.3.6.1.2.1.2.2.1").Child(uint32(i + 1)).Child(1)
+	}
+
+	return oids
+}
+
+func BenchmarkGetRequestEncode(b *testing.B) {
+	oids := pollOIDs()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Message{Version: Version2c, Community: "public", PDU: newGetRequest(i, nullVarbinds(oids))}.Encode()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPreparedGetPacket(b *testing.B) {
+	c := &Client{Community: "public", Version: Version2c}
+
+	p, err := c.Prepare(pollOIDs()...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		p.packet(int32(i))
+	}
+}
+
+func TestClientEncodeRequests(t *testing.T) {
+	c := &Client{Community: "public", Version: Version2c}
+
+	for _, test := range []struct {
+		encode func() ([]byte, error)
+		check  func(pdu DataType) bool
+	}{
+		{
+			func() ([]byte, error) { return c.EncodeGet(7, sysDescr, sysUpTime) },
+			func(pdu DataType) bool {
+				req, ok := pdu.(GetRequest)
+				return ok && len(req.Varbinds()) == 2 && req.Varbinds()[1].OID.Equal(sysUpTime)
+			},
+		},
+		{
+			func() ([]byte, error) { return c.EncodeGetNext(7, ifDescr) },
+			func(pdu DataType) bool {
+				req, ok := pdu.(GetNextRequest)
+				return ok && len(req.Varbinds()) == 1 && req.Varbinds()[0].OID.Equal(ifDescr)
+			},
+		},
+		{
+			func() ([]byte, error) { return c.EncodeGetBulk(7, 1, 10, sysDescr, ifDescr) },
+			func(pdu DataType) bool {
+				req, ok := pdu.(GetBulkRequest)
+				return ok && req.NonRepeaters() == 1 && req.MaxRepetitions() == 10 && len(req.Varbinds()) == 2
+			},
+		},
+		{
+			func() ([]byte, error) { return c.EncodeSet(7, NewVarbind(sysName, String("router"))) },
+			func(pdu DataType) bool {
+				req, ok := pdu.(SetRequest)
+				return ok && len(req.Varbinds()) == 1 && req.Varbinds()[0].Value() == String("router")
+			},
+		},
+	} {
+		b, err := test.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := Unmarshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Version != Version2c || m.Community != "public" || !test.check(m.PDU) {
+			t.Errorf("unexpected message %+v", m)
+		}
+
+		if req, ok := m.PDU.(interface{ RequestID() int }); !ok || req.RequestID() != 7 {
+			t.Errorf("expected request ID 7, got %+v", m.PDU)
+		}
+	}
+
+	if _, err := (&Client{Version: Version1}).EncodeGetBulk(1, 0, 10, ifDescr); err == nil {
+		t.Error("expected an error encoding a GetBulkRequest for SNMPv1")
+	}
+
+	if _, err := (&Client{Version: Version3}).EncodeGet(1, sysDescr); err == nil {
+		t.Error("expected an error encoding a request for SNMPv3")
+	}
+}
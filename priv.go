@@ -0,0 +1,235 @@
+package snmp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+)
+
+// PrivProtocol is an SNMPv3 privacy protocol.
+type PrivProtocol int
+
+const (
+	NoPriv PrivProtocol = iota
+
+	// DES is CBC-DES as described in RFC 3414 section 8.
+	DES
+
+	// AES is CFB128-AES-128 as described in RFC 3826.
+	AES
+)
+
+// privParamsLength is the length of DES and AES privacy parameters.
+const privParamsLength = 8
+
+// Salts are initialized randomly and incremented for every
+// encrypted message, as recommended by RFC 3414 and RFC 3826.
+var (
+	desSalt = rand.Uint32()
+	aesSalt = rand.Uint64()
+)
+
+// String returns the name of a PrivProtocol.
+func (p PrivProtocol) String() string {
+	switch p {
+	case NoPriv:
+		return "none"
+	case DES:
+		return "DES"
+	case AES:
+		return "AES"
+	}
+
+	return "unknown"
+}
+
+// LocalizePrivKey returns the privacy key for passphrase localized to
+// engineID. Privacy keys are localized using the hash of auth.
+func LocalizePrivKey(auth AuthProtocol, protocol PrivProtocol, passphrase string, engineID []byte) ([]byte, error) {
+	if protocol != DES && protocol != AES {
+		return nil, errors.New("snmp: unsupported privacy protocol")
+	}
+
+	key, err := LocalizeKey(auth, passphrase, engineID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both protocols use 16 bytes, which MD5 and SHA keys provide
+	return key[:16], nil
+}
+
+// encrypt encrypts plaintext with key for a message with the given
+// engine boots and time. It returns the ciphertext and the privacy
+// parameters of the message.
+func (p PrivProtocol) encrypt(key []byte, boots, engineTime int32, plaintext []byte) ([]byte, []byte, error) {
+	if len(key) < 16 {
+		return nil, nil, errors.New("snmp: privacy key too short")
+	}
+
+	salt := make([]byte, privParamsLength)
+
+	switch p {
+	case DES:
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], atomic.AddUint32(&desSalt, 1))
+
+		block, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The padding is ignored when decoding the scopedPDU
+		padded := plaintext
+		if rem := len(padded) % des.BlockSize; rem != 0 {
+			padded = append(append([]byte(nil), plaintext...), make([]byte, des.BlockSize-rem)...)
+		}
+
+		ciphertext := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, desIV(key, salt)).CryptBlocks(ciphertext, padded)
+
+		return ciphertext, salt, nil
+
+	case AES:
+		binary.BigEndian.PutUint64(salt, atomic.AddUint64(&aesSalt, 1))
+
+		block, err := aes.NewCipher(key[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ciphertext := make([]byte, len(plaintext))
+		cipher.NewCFBEncrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(ciphertext, plaintext)
+
+		return ciphertext, salt, nil
+	}
+
+	return nil, nil, errors.New("snmp: unsupported privacy protocol")
+}
+
+// decrypt decrypts ciphertext with key for a message with the given
+// engine boots, time, and privacy parameters.
+func (p PrivProtocol) decrypt(key []byte, boots, engineTime int32, ciphertext, privParams []byte) ([]byte, error) {
+	if len(key) < 16 {
+		return nil, errors.New("snmp: privacy key too short")
+	}
+
+	if len(privParams) != privParamsLength {
+		return nil, ErrDecryption
+	}
+
+	switch p {
+	case DES:
+		if len(ciphertext)%des.BlockSize != 0 {
+			return nil, ErrDecryption
+		}
+
+		block, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, desIV(key, privParams)).CryptBlocks(plaintext, ciphertext)
+
+		return plaintext, nil
+
+	case AES:
+		block, err := aes.NewCipher(key[:16])
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCFBDecrypter(block, aesIV(boots, engineTime, privParams)).XORKeyStream(plaintext, ciphertext)
+
+		return plaintext, nil
+	}
+
+	return nil, errors.New("snmp: unsupported privacy protocol")
+}
+
+// desIV returns the DES IV, which is the pre-IV in the last 8 bytes
+// of key XORed with salt.
+func desIV(key, salt []byte) []byte {
+	iv := make([]byte, des.BlockSize)
+	for i := range iv {
+		iv[i] = key[8+i] ^ salt[i]
+	}
+
+	return iv
+}
+
+// aesIV returns the AES IV, which is the engine boots and time
+// followed by salt.
+func aesIV(boots, engineTime int32, salt []byte) []byte {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+
+	return iv
+}
+
+// encrypt replaces the scopedPDU of m with its encryption using key,
+// and sets FlagPriv and the privacy parameters of m.
+func (m *Message) encrypt(protocol PrivProtocol, key []byte) error {
+	scopedPDU, err := Sequence{
+		String(m.ContextEngineID),
+		String(m.ContextName),
+		m.PDU,
+	}.Encode()
+
+	if err != nil {
+		return err
+	}
+
+	ciphertext, privParams, err := protocol.encrypt(key,
+		int32(m.Security.AuthoritativeEngineBoots), int32(m.Security.AuthoritativeEngineTime), scopedPDU)
+
+	if err != nil {
+		return err
+	}
+
+	m.Flags |= FlagPriv
+	m.Security.PrivacyParameters = privParams
+	m.EncryptedPDU = ciphertext
+	m.PDU = nil
+
+	return nil
+}
+
+// decrypt decrypts the scopedPDU of m using key,
+// and sets the context and PDU of m.
+func (m *Message) decrypt(protocol PrivProtocol, key []byte) error {
+	plaintext, err := protocol.decrypt(key,
+		int32(m.Security.AuthoritativeEngineBoots), int32(m.Security.AuthoritativeEngineTime),
+		m.EncryptedPDU, m.Security.PrivacyParameters)
+
+	if err != nil {
+		return err
+	}
+
+	decoded, _, err := decode(bytes.NewReader(plaintext))
+	if err != nil {
+		return ErrDecryption
+	}
+
+	scopedPDU, ok := decoded.(Sequence)
+	if !ok {
+		return ErrDecryption
+	}
+
+	if err := m.setScopedPDU(scopedPDU); err != nil {
+		return err
+	}
+
+	m.EncryptedPDU = nil
+
+	return nil
+}
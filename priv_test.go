@@ -0,0 +1,135 @@
+package snmp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrivacyRoundTrip(t *testing.T) {
+	key := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	}
+
+	m := Message{
+		Version:         Version3,
+		MessageID:       9,
+		Flags:           FlagAuth,
+		Security:        USMSecurityParameters{AuthoritativeEngineBoots: 2, AuthoritativeEngineTime: 300},
+		ContextEngineID: testEngineID,
+		ContextName:     "ctx",
+		PDU:             NewGetRequest(9, sysDescr, sysName),
+	}
+
+	plaintext, err := Sequence{String(m.ContextEngineID), String(m.ContextName), m.PDU}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, protocol := range []PrivProtocol{DES, AES} {
+		encrypted := m
+		if err := encrypted.encrypt(protocol, key); err != nil {
+			t.Fatalf("%v: %v", protocol, err)
+		}
+
+		if encrypted.PDU != nil || encrypted.Flags&FlagPriv == 0 || len(encrypted.Security.PrivacyParameters) != privParamsLength {
+			t.Fatalf("%v: unexpected encrypted message %+v", protocol, encrypted)
+		}
+
+		if bytes.Contains(encrypted.EncryptedPDU, []byte("ctx")) {
+			t.Errorf("%v: scopedPDU is not encrypted", protocol)
+		}
+
+		if protocol == DES && len(encrypted.EncryptedPDU)%8 != 0 {
+			t.Errorf("DES: ciphertext of %d bytes is not padded", len(encrypted.EncryptedPDU))
+		}
+
+		if protocol == AES && len(encrypted.EncryptedPDU) != len(plaintext) {
+			t.Errorf("AES: expected %d bytes of ciphertext, got %d", len(plaintext), len(encrypted.EncryptedPDU))
+		}
+
+		b, err := encrypted.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, _, err := decodeMessage(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := decoded.decrypt(protocol, key); err != nil {
+			t.Fatalf("%v: %v", protocol, err)
+		}
+
+		if decoded.ContextName != "ctx" || !bytes.Equal(decoded.ContextEngineID, testEngineID) {
+			t.Errorf("%v: unexpected context %x %q", protocol, decoded.ContextEngineID, decoded.ContextName)
+		}
+
+		req, ok := decoded.PDU.(GetRequest)
+		if !ok || req.RequestID() != 9 || len(req.Varbinds()) != 2 || !req.Varbinds()[1].OID.Equal(sysName) {
+			t.Errorf("%v: unexpected PDU %#v", protocol, decoded.PDU)
+		}
+	}
+}
+
+func TestPrivacySalt(t *testing.T) {
+	key := make([]byte, 16)
+
+	_, salt1, err := AES.encrypt(key, 1, 1, []byte("scopedPDU"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, salt2, err := AES.encrypt(key, 1, 1, []byte("scopedPDU"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(salt1, salt2) {
+		t.Error("expected a new salt for every message")
+	}
+
+	_, salt, err := DES.encrypt(key, 7, 1, []byte("scopedPDU"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(salt[:4], []byte{0, 0, 0, 7}) {
+		t.Errorf("expected the DES salt to start with engine boots, got %x", salt)
+	}
+}
+
+func TestClientV3Privacy(t *testing.T) {
+	for _, protocol := range []PrivProtocol{DES, AES} {
+		agent := v3Agent(t, SHA, "authpassphrase")
+
+		key, err := LocalizePrivKey(SHA, protocol, "privpassphrase", testEngineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		agent.privacy(protocol, key)
+
+		c := &Client{
+			Addr:    agent.addr(),
+			Version: Version3,
+			User: &USMUser{
+				Name:           "operator",
+				AuthProtocol:   SHA,
+				AuthPassphrase: "authpassphrase",
+				PrivProtocol:   protocol,
+				PrivPassphrase: "privpassphrase",
+			},
+		}
+
+		varbinds, err := c.Get(sysDescr)
+		if err != nil {
+			t.Fatalf("%v: %v", protocol, err)
+		}
+
+		if len(varbinds) != 1 || varbinds[0].Value() != String("v3 agent") {
+			t.Errorf("%v: unexpected varbinds %v", protocol, varbinds)
+		}
+	}
+}
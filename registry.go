@@ -0,0 +1,137 @@
+package snmp
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry maps names to OIDs registered in code, as a lightweight
+// alternative to loading MIB files with MIB. Names of objects resolve
+// their instances too, as in "sysDescr.0" or "ifDescr.2". The zero
+// value is an empty Registry, which may be used concurrently.
+type Registry struct {
+	mu     sync.RWMutex
+	byName map[string]ObjectIdentifier
+	byOID  registryNode
+}
+
+// registryNode is a node of the trie of registered OIDs, keyed by arc.
+type registryNode struct {
+	name     string
+	children map[uint32]*registryNode
+}
+
+// Register maps name to oid, replacing any earlier mapping of name or
+// of oid. Names can't contain dots, which separate instance arcs.
+func (r *Registry) Register(name string, oid ObjectIdentifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.byName[name]; ok {
+		if node := r.byOID.find(old); node != nil && node.name == name {
+			node.name = ""
+		}
+	}
+
+	if r.byName == nil {
+		r.byName = map[string]ObjectIdentifier{}
+	}
+
+	r.byName[name] = oid.Clone()
+
+	node := &r.byOID
+	for _, arc := range oid {
+		child, ok := node.children[arc]
+		if !ok {
+			child = &registryNode{}
+			if node.children == nil {
+				node.children = map[uint32]*registryNode{}
+			}
+
+			node.children[arc] = child
+		}
+
+		node = child
+	}
+
+	if node.name != "" && node.name != name {
+		delete(r.byName, node.name)
+	}
+
+	node.name = name
+}
+
+// Resolve returns the OID of name, which may be followed by the arcs of
+// an instance, such as "ifDescr.2". It returns false if the name isn't
+// registered or the arcs aren't numbers.
+func (r *Registry) Resolve(name string) (ObjectIdentifier, bool) {
+	name, rest, _ := strings.Cut(name, ".")
+
+	r.mu.RLock()
+	oid, ok := r.byName[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	oid = oid.Clone()
+
+	if rest == "" {
+		return oid, true
+	}
+
+	for _, part := range strings.Split(rest, ".") {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, false
+		}
+
+		oid = append(oid, uint32(arc))
+	}
+
+	return oid, true
+}
+
+// Name returns the name registered for the longest prefix of oid,
+// followed by the arcs after the prefix, such as "ifDescr.2". It
+// returns false if no prefix of oid is registered.
+func (r *Registry) Name(oid ObjectIdentifier) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, n := "", 0
+
+	node := &r.byOID
+	for i, arc := range oid {
+		if node = node.children[arc]; node == nil {
+			break
+		}
+
+		if node.name != "" {
+			name, n = node.name, i+1
+		}
+	}
+
+	if name == "" {
+		return "", false
+	}
+
+	if n < len(oid) {
+		name += "." + oid[n:].Dotted()
+	}
+
+	return name, true
+}
+
+// find returns the node of oid, or nil if there is none.
+func (n *registryNode) find(oid ObjectIdentifier) *registryNode {
+	for _, arc := range oid {
+		if n = n.children[arc]; n == nil {
+			return nil
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,90 @@
+package snmp
+
+import "testing"
+
+func testRegistry() *Registry {
+	r := &Registry{}
+	r.Register("system", MustParseOID(".1.3.6.1.2.1.1"))
+	r.Register("sysDescr", MustParseOID(".1.3.6.1.2.1.1.1"))
+	r.Register("ifDescr", ifDescr)
+
+	return r
+}
+
+func TestRegistryResolve(t *testing.T) {
+	r := testRegistry()
+
+	for name, expected := range map[string]string{
+		"sysDescr":   ".1.3.6.1.2.1.1.1",
+		"sysDescr.0": ".1.3.6.1.2.1.1.1.0",
+		"ifDescr.2":  ".1.3.6.1.2.1.2.2.1.2.2",
+		"system.5.0": ".1.3.6.1.2.1.1.5.0",
+	} {
+		oid, ok := r.Resolve(name)
+		if !ok || oid.String() != expected {
+			t.Errorf("expected %s to resolve to %s, got %v (%v)", name, expected, oid, ok)
+		}
+	}
+
+	for _, name := range []string{"sysName", "sysName.0", "ifDescr.x", "ifDescr..2", ""} {
+		if oid, ok := r.Resolve(name); ok {
+			t.Errorf("expected %q not to resolve, got %v", name, oid)
+		}
+	}
+
+	// The registered OID isn't shared with the results
+	oid, _ := r.Resolve("sysDescr")
+	oid[0] = 2
+
+	if oid, _ := r.Resolve("sysDescr"); oid[0] != 1 {
+		t.Errorf("expected the registered OID to be unchanged, got %v", oid)
+	}
+}
+
+func TestRegistryName(t *testing.T) {
+	r := testRegistry()
+
+	for oid, expected := range map[string]string{
+		".1.3.6.1.2.1.1.1":          "sysDescr",
+		".1.3.6.1.2.1.1.1.0":        "sysDescr.0",
+		".1.3.6.1.2.1.1.5.0":        "system.5.0",
+		".1.3.6.1.2.1.2.2.1.2.1000": "ifDescr.1000",
+	} {
+		name, ok := r.Name(MustParseOID(oid))
+		if !ok || name != expected {
+			t.Errorf("expected %s to be named %s, got %q (%v)", oid, expected, name, ok)
+		}
+	}
+
+	for _, oid := range []string{".1.3.6.1.2.1.2", ".1.3.6.1.4.1.9"} {
+		if name, ok := r.Name(MustParseOID(oid)); ok {
+			t.Errorf("expected %s to have no name, got %q", oid, name)
+		}
+	}
+
+	if name, ok := (&Registry{}).Name(sysDescr); ok {
+		t.Errorf("expected an empty Registry to have no names, got %q", name)
+	}
+}
+
+func TestRegistryReplace(t *testing.T) {
+	r := testRegistry()
+
+	// Moving a name frees its old OID
+	r.Register("sysDescr", MustParseOID(".1.3.6.1.2.1.1.99"))
+
+	if name, _ := r.Name(MustParseOID(".1.3.6.1.2.1.1.1.0")); name != "system.1.0" {
+		t.Errorf("expected the old OID to fall back to system, got %q", name)
+	}
+
+	// Renaming an OID frees its old name
+	r.Register("interfaceDescr", ifDescr)
+
+	if _, ok := r.Resolve("ifDescr"); ok {
+		t.Error("expected the old name not to resolve")
+	}
+
+	if name, _ := r.Name(ifDescr.Child(1)); name != "interfaceDescr.1" {
+		t.Errorf("expected interfaceDescr.1, got %q", name)
+	}
+}
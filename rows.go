@@ -0,0 +1,80 @@
+package snmp
+
+// Row is a row of a conceptual table assembled by AssembleRows.
+type Row struct {
+	// Index is the index of the row, i.e. the arcs after the
+	// column OID in dotted form, as with GetTable.
+	Index string
+
+	// Values holds the value of each column of the row, keyed by
+	// the number of the column, the arc after the entry OID.
+	Values map[uint32]DataType
+}
+
+// AssembleRows groups the variable bindings of the conceptual table
+// entryOID, such as those from WalkAll of ifEntry, into rows by index.
+// It returns the rows that have every one of columns and the indexes
+// of those that don't, such as rows added or removed while the table
+// was walked, both in the order of their indexes. If columns is empty,
+// every column present in any row is expected. Bindings outside the
+// table or of other columns, and exceptions, are ignored. If a binding
+// is repeated, the last one is used.
+func AssembleRows(varbinds []Varbind, entryOID ObjectIdentifier, columns []ObjectIdentifier) ([]Row, []string) {
+	wanted := map[uint32]bool{}
+	for _, column := range columns {
+		if len(column) == len(entryOID)+1 && column.HasPrefix(entryOID) {
+			wanted[column[len(entryOID)]] = true
+		}
+	}
+
+	var indexes []ObjectIdentifier
+	rows := map[string]*Row{}
+	seen := map[uint32]bool{}
+
+	for _, v := range varbinds {
+		if len(v.OID) <= len(entryOID)+1 || !v.OID.HasPrefix(entryOID) || v.IsException() {
+			continue
+		}
+
+		column := v.OID[len(entryOID)]
+		if len(columns) > 0 && !wanted[column] {
+			continue
+		}
+
+		seen[column] = true
+
+		index := v.OID[len(entryOID)+1:]
+		key := index.Dotted()
+
+		row, ok := rows[key]
+		if !ok {
+			row = &Row{Index: key, Values: map[uint32]DataType{}}
+			rows[key] = row
+			indexes = append(indexes, index)
+		}
+
+		row.Values[column] = v.value
+	}
+
+	if len(columns) == 0 {
+		wanted = seen
+	}
+
+	SortOIDs(indexes)
+
+	var complete []Row
+	var torn []string
+
+	for _, index := range indexes {
+		row := rows[index.Dotted()]
+
+		if len(row.Values) < len(wanted) {
+			torn = append(torn, row.Index)
+			continue
+		}
+
+		complete = append(complete, *row)
+	}
+
+	return complete, torn
+}
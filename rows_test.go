@@ -0,0 +1,67 @@
+package snmp
+
+import "testing"
+
+func TestAssembleRows(t *testing.T) {
+	ifEntry := MustParseOID(".1.3.6.1.2.1.2.2.1")
+	ifIndex, ifDescr, ifType := ifEntry.Child(1), ifEntry.Child(2), ifEntry.Child(3)
+
+	varbinds := []Varbind{
+		NewVarbind(ifIndex.Child(1), Int(1)),
+		NewVarbind(ifIndex.Child(2), Int(2)),
+		NewVarbind(ifIndex.Child(10), Int(10)),
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(ifDescr.Child(10), String("eth1")),
+		NewVarbind(ifType.Child(1), Int(24)),
+		NewVarbind(ifType.Child(2), Int(6)),
+		NewVarbind(ifType.Child(10), Int(6)),
+		NewVarbind(sysDescr, String("outside the table")),
+	}
+
+	rows, torn := AssembleRows(varbinds, ifEntry, []ObjectIdentifier{ifDescr, ifType})
+	if len(torn) != 0 {
+		t.Errorf("expected no torn rows, got %v", torn)
+	}
+
+	// In index order, not the string order of "10" and "2"
+	if len(rows) != 3 || rows[0].Index != "1" || rows[1].Index != "2" || rows[2].Index != "10" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	if len(rows[1].Values) != 2 || rows[1].Values[2] != String("eth0") || rows[1].Values[3] != Int(6) {
+		t.Errorf("unexpected values of row 2 %v", rows[1].Values)
+	}
+
+	if rows, _ := AssembleRows(varbinds, ifEntry, nil); len(rows) != 3 || len(rows[0].Values) != 3 {
+		t.Errorf("expected 3 rows of every column, got %v", rows)
+	}
+}
+
+func TestAssembleRowsTorn(t *testing.T) {
+	ifEntry := MustParseOID(".1.3.6.1.2.1.2.2.1")
+	ifDescr, ifType := ifEntry.Child(2), ifEntry.Child(3)
+
+	// Row 3 was added after ifDescr was walked, and row 2 removed
+	// before ifType was, leaving an exception
+	varbinds := []Varbind{
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(ifDescr.Child(1), String("lo0")),
+		NewVarbind(ifType.Child(1), Int(24)),
+		NewVarbind(ifType.Child(2), NoSuchInstance),
+		NewVarbind(ifType.Child(3), Int(6)),
+	}
+
+	for _, columns := range [][]ObjectIdentifier{{ifDescr, ifType}, nil} {
+		rows, torn := AssembleRows(varbinds, ifEntry, columns)
+
+		if len(rows) != 1 || rows[0].Index != "1" || rows[0].Values[2] != String("lo0") {
+			t.Errorf("expected only row 1 with the repeated value, got %v", rows)
+		}
+
+		if len(torn) != 2 || torn[0] != "2" || torn[1] != "3" {
+			t.Errorf("expected rows 2 and 3 to be torn, got %v", torn)
+		}
+	}
+}
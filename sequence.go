@@ -49,5 +49,9 @@ func decodeSequence(length int, r io.Reader) (Sequence, int, error) {
 		}
 	}
 
+	if seqBytes > length {
+		return nil, bytesRead, errOverrun(length, seqBytes)
+	}
+
 	return seq, bytesRead, nil
 }
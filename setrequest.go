@@ -0,0 +1,30 @@
+package snmp
+
+import (
+	"io"
+)
+
+// SetRequest represents an SNMP SetRequest-PDU.
+type SetRequest struct {
+	PDU
+}
+
+// NewSetRequest returns a new SetRequest which sets the
+// value of each variable binding.
+func NewSetRequest(requestID int32, varbinds ...Varbind) SetRequest {
+	return SetRequest{
+		PDU: newPDU(int(requestID), 0, 0, varbinds),
+	}
+}
+
+// Encode encodes a SetRequest with the proper header.
+func (s SetRequest) Encode() ([]byte, error) {
+	return s.PDU.encode(TypeSetRequest)
+}
+
+// decodeSetRequest decodes a SetRequest up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeSetRequest(length int, r io.Reader) (SetRequest, int, error) {
+	pdu, bytesRead, err := decodePDU(length, r)
+	return SetRequest{PDU: pdu}, bytesRead, err
+}
@@ -0,0 +1,165 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// smartWalkRepetitions is the max-repetitions of the GETBULK
+// requests sent by SmartWalk.
+const smartWalkRepetitions = 20
+
+// WalkResult holds the variable bindings collected by SmartWalk.
+type WalkResult struct {
+	// Version is the SNMP version the walk was made with.
+	Version int
+
+	// Varbinds holds the bindings of the subtree, sorted by OID.
+	Varbinds []Varbind
+}
+
+// SmartWalk walks the subtree rooted at root with GETBULK requests
+// like BulkWalk, unless c.Version is Version1. If the agent doesn't
+// answer the first GETBULK, or answers it with an error, as agents
+// that only speak SNMPv1 do, the subtree is walked again with SNMPv1
+// GETNEXT requests. The result reports the version that was used.
+// Bindings are limited to c.MaxWalkBindings as for WalkAll.
+func (c *Client) SmartWalk(root ObjectIdentifier) (WalkResult, error) {
+	return c.SmartWalkContext(context.Background(), root)
+}
+
+// SmartWalkContext is like SmartWalk but aborts when ctx is done.
+func (c *Client) SmartWalkContext(ctx context.Context, root ObjectIdentifier) (WalkResult, error) {
+	if c.Version == Version1 {
+		varbinds, err := c.WalkAllContext(ctx, root)
+		return WalkResult{Version: Version1, Varbinds: varbinds}, err
+	}
+
+	limit := c.MaxWalkBindings
+	if limit <= 0 {
+		limit = defaultMaxWalkBindings
+	}
+
+	var varbinds []Varbind
+
+	err := c.BulkWalkContext(ctx, root, smartWalkRepetitions, func(v Varbind) error {
+		if len(varbinds) == limit {
+			return fmt.Errorf("%w: more than %d under %v", ErrTooManyBindings, limit, root)
+		}
+
+		varbinds = append(varbinds, v)
+		return nil
+	})
+
+	// Only a walk that failed before returning anything falls back,
+	// so that the bindings never mix versions
+	var snmpErr SNMPError
+	if c.Version == Version2c && len(varbinds) == 0 && ctx.Err() == nil &&
+		(errors.Is(err, ErrTimeout) || errors.As(err, &snmpErr)) {
+		varbinds, err := c.withVersion(Version1).WalkAllContext(ctx, root)
+		return WalkResult{Version: Version1, Varbinds: varbinds}, err
+	}
+
+	sort.Slice(varbinds, func(i, j int) bool {
+		return varbinds[i].OID.Compare(varbinds[j].OID) < 0
+	})
+
+	return WalkResult{Version: c.Version, Varbinds: varbinds}, err
+}
+
+// withVersion returns a Client configured like c that uses version.
+// It doesn't share the SNMPv3 engine of c.
+func (c *Client) withVersion(version int) *Client {
+	other := c.clone()
+	other.Version = version
+
+	return other
+}
+
+// clone returns a Client configured like c. It doesn't share the
+// SNMPv3 engine or the RateLimit of c.
+func (c *Client) clone() *Client {
+	return &Client{
+		Addr:                   c.Addr,
+		Port:                   c.Port,
+		Network:                c.Network,
+		LocalAddr:              c.LocalAddr,
+		Community:              c.Community,
+		Version:                c.Version,
+		User:                   c.User,
+		Timeout:                c.Timeout,
+		Retries:                c.Retries,
+		MaxPDUSize:             c.MaxPDUSize,
+		MaxMessageSize:         c.MaxMessageSize,
+		EncodeOptions:          c.EncodeOptions,
+		Concurrency:            c.Concurrency,
+		RateLimit:              c.RateLimit,
+		Interner:               c.Interner,
+		MIB:                    c.MIB,
+		StrictOIDs:             c.StrictOIDs,
+		RejectDuplicateOIDs:    c.RejectDuplicateOIDs,
+		MaxWalkBindings:        c.MaxWalkBindings,
+		MaxBindingsPerResponse: c.MaxBindingsPerResponse,
+		Trace:                  c.Trace,
+		Transport:              c.Transport,
+	}
+}
+
+// DumpAll calls fn for every variable binding the agent exposes under
+// roots, or under .1, its whole MIB, if no roots are given, such as to
+// record a device when it's onboarded. Each root is walked with GETBULK
+// requests like BulkWalk, unless c.Version is Version1, where GETNEXT
+// requests are used like Walk. Walks end as those do, including when
+// the agent echoes the OID requested after its last one. If more than
+// c.MaxWalkBindings bindings are found in total, the dump stops with
+// ErrTooManyBindings. If fn returns ErrStopWalk, DumpAll returns nil.
+func (c *Client) DumpAll(fn func(Varbind) error, roots ...ObjectIdentifier) error {
+	return c.DumpAllContext(context.Background(), fn, roots...)
+}
+
+// DumpAllContext is like DumpAll but aborts when ctx is done.
+func (c *Client) DumpAllContext(ctx context.Context, fn func(Varbind) error, roots ...ObjectIdentifier) error {
+	if len(roots) == 0 {
+		roots = []ObjectIdentifier{{1}}
+	}
+
+	limit := c.MaxWalkBindings
+	if limit <= 0 {
+		limit = defaultMaxWalkBindings
+	}
+
+	count := 0
+	stopped := false
+
+	dump := func(v Varbind) error {
+		if count == limit {
+			return fmt.Errorf("%w: more than %d in the dump", ErrTooManyBindings, limit)
+		}
+
+		count++
+
+		err := fn(v)
+		if err == ErrStopWalk {
+			stopped = true
+		}
+
+		return err
+	}
+
+	for _, root := range roots {
+		var err error
+		if c.Version == Version1 {
+			err = c.WalkContext(ctx, root, dump)
+		} else {
+			err = c.BulkWalkContext(ctx, root, smartWalkRepetitions, dump)
+		}
+
+		if err != nil || stopped {
+			return err
+		}
+	}
+
+	return nil
+}
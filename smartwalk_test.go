@@ -0,0 +1,234 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// v1OnlyAgent returns a stub agent serving ifDescr to SNMPv1 GETNEXT
+// requests that answers other versions with answer, or drops them if
+// it returns nil.
+func v1OnlyAgent(t *testing.T, answer func(a *stubAgent, m *Message) *Message) *stubAgent {
+	agent := newStubAgent(t,
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(ifDescr.Child(3), String("eth1")),
+		NewVarbind(sysDescr, String("test agent")),
+	)
+
+	agent.handle(func(m *Message) []*Message {
+		if m.Version != Version1 {
+			return []*Message{answer(agent, m)}
+		}
+
+		// SNMPv1 signals the end of the MIB with noSuchName
+		res := agent.respond(m)
+		pdu := res.PDU.(GetResponse).PDU
+		for i, v := range pdu.varbinds {
+			if v.IsException() {
+				req := m.PDU.(GetNextRequest)
+				res.PDU = GetResponse{PDU: newPDU(req.requestID, int(NoSuchName), i+1, req.varbinds)}
+			}
+		}
+
+		return []*Message{res}
+	})
+
+	return agent
+}
+
+func TestClientSmartWalkFallback(t *testing.T) {
+	for name, answer := range map[string]func(a *stubAgent, m *Message) *Message{
+		"dropped": func(*stubAgent, *Message) *Message { return nil },
+		"genErr": func(a *stubAgent, m *Message) *Message {
+			res := a.respond(m)
+			req := m.PDU.(GetBulkRequest)
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, int(GenErr), 1, req.varbinds)}
+			return res
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			agent := v1OnlyAgent(t, answer)
+
+			c := &Client{Addr: agent.addr(), Community: "public", Version: Version1, Timeout: 100 * time.Millisecond}
+
+			expected, err := c.WalkAll(ifDescr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.Version = Version2c
+
+			res, err := c.SmartWalk(ifDescr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Version != Version1 {
+				t.Errorf("expected SNMPv1, got version %d", res.Version)
+			}
+
+			if len(res.Varbinds) != 3 || !reflect.DeepEqual(res.Varbinds, expected) {
+				t.Errorf("expected %v, got %v", expected, res.Varbinds)
+			}
+		})
+	}
+}
+
+func TestClientSmartWalkBulk(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	expected, err := c.WalkAll(ifDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.SmartWalk(ifDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Version != Version2c || !reflect.DeepEqual(res.Varbinds, expected) {
+		t.Errorf("expected %v with SNMPv2c, got %v with version %d", expected, res.Varbinds, res.Version)
+	}
+}
+
+// stubTransport is a comparable Transport for TestClientWithVersion.
+type stubTransport struct{ name string }
+
+func (stubTransport) RoundTrip(ctx context.Context, req []byte) ([]byte, error) {
+	return nil, errors.New("stub")
+}
+
+// setNonZero sets the exported fields of f, or f itself, to non-zero values.
+func setNonZero(f reflect.Value) {
+	switch f.Kind() {
+	case reflect.Bool:
+		f.SetBool(true)
+	case reflect.String:
+		f.SetString("set")
+	case reflect.Int, reflect.Int64:
+		f.SetInt(1)
+	case reflect.Float64:
+		f.SetFloat(1)
+	case reflect.Ptr:
+		f.Set(reflect.New(f.Type().Elem()))
+	case reflect.Interface:
+		if stub := reflect.ValueOf(stubTransport{"set"}); stub.Type().Implements(f.Type()) {
+			f.Set(stub)
+		}
+	case reflect.Struct:
+		for i := 0; i < f.NumField(); i++ {
+			if f.Type().Field(i).IsExported() {
+				setNonZero(f.Field(i))
+			}
+		}
+	}
+}
+
+func TestClientWithVersion(t *testing.T) {
+	c := &Client{}
+
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Type().Field(i); field.IsExported() {
+			setNonZero(v.Field(i))
+
+			// A field of a new kind must be handled by setNonZero
+			if v.Field(i).IsZero() {
+				t.Fatalf("%s of kind %s wasn't set", field.Name, field.Type.Kind())
+			}
+		}
+	}
+
+	other := c.withVersion(Version1)
+	o := reflect.ValueOf(other).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Type().Field(i); field.IsExported() && field.Name != "Version" &&
+			!reflect.DeepEqual(v.Field(i).Interface(), o.Field(i).Interface()) {
+			t.Errorf("expected withVersion to copy %s", field.Name)
+		}
+	}
+}
+
+func TestClientDumpAll(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// SNMPv1 agents signal the end of the MIB with noSuchName
+	agent.handle(func(m *Message) []*Message {
+		res := agent.respond(m)
+		if req, ok := m.PDU.(GetNextRequest); ok && m.Version == Version1 && res.PDU.(GetResponse).varbinds[0].IsException() {
+			res.PDU = GetResponse{PDU: newPDU(req.requestID, int(NoSuchName), 1, req.varbinds)}
+		}
+
+		return []*Message{res}
+	})
+
+	var expected []ObjectIdentifier
+	for _, v := range agent.varbinds {
+		expected = append(expected, v.OID)
+	}
+
+	for _, version := range []int{Version1, Version2c} {
+		c := &Client{Addr: agent.addr(), Community: "public", Version: version, Timeout: time.Second}
+
+		var dumped []ObjectIdentifier
+		err := c.DumpAll(func(v Varbind) error {
+			dumped = append(dumped, v.OID)
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(dumped, expected) {
+			t.Errorf("v%d: expected %v, got %v", version, expected, dumped)
+		}
+	}
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	system := MustParseOID(".1.3.6.1.2.1.1")
+
+	// Only the given roots are walked
+	var dumped []ObjectIdentifier
+	err := c.DumpAll(func(v Varbind) error {
+		dumped = append(dumped, v.OID)
+		return nil
+	}, system, ifDescr)
+
+	if err != nil || len(dumped) != 4 || !dumped[0].Equal(sysDescr) || !dumped[3].Equal(ifDescr.Child(3)) {
+		t.Errorf("expected sysDescr and ifDescr, got %v and %v", dumped, err)
+	}
+
+	// The cap applies across roots
+	c.MaxWalkBindings = 3
+
+	count := 0
+	err = c.DumpAll(func(v Varbind) error {
+		count++
+		return nil
+	}, system, ifDescr)
+
+	if !errors.Is(err, ErrTooManyBindings) || count != 3 {
+		t.Errorf("expected ErrTooManyBindings after 3 bindings, got %v after %d", err, count)
+	}
+
+	// ErrStopWalk stops the whole dump
+	count = 0
+	err = c.DumpAll(func(v Varbind) error {
+		count++
+		return ErrStopWalk
+	}, system, ifDescr)
+
+	if err != nil || count != 1 {
+		t.Errorf("expected the dump to stop after 1 binding, got %v after %d", err, count)
+	}
+}
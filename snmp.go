@@ -2,6 +2,7 @@ package snmp
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"errors"
 	"log"
 	"math/rand"
@@ -272,8 +273,8 @@ func (s *Session) Discover() error {
 	s.engineBoots = int32(engineStuff.(Sequence)[1].(Int))
 	s.engineTime = int32(engineStuff.(Sequence)[2].(Int))
 
-	s.privKey = passphraseToKey(s.privPassphrase, s.engineID)[:16]
-	s.authKey = passphraseToKey(s.authPassphrase, s.engineID)
+	s.privKey = passphraseToKey(sha1.New, s.privPassphrase, s.engineID)[:16]
+	s.authKey = passphraseToKey(sha1.New, s.authPassphrase, s.engineID)
 
 	return nil
 }
@@ -32,7 +32,7 @@ func TestSNMP(t *testing.T) {
 				vbinds[0].value, vbinds[0].value)
 		}
 		if desc != "SunOS zeus.snmplabs.com 4.1.3_U1 1 sun4m" {
-			t.Error("Expected desc %v, got %v", "SunOS zeus.snmplabs.com 4.1.3_U1 1 sun4m", desc)
+			t.Errorf("Expected desc %v, got %v", "SunOS zeus.snmplabs.com 4.1.3_U1 1 sun4m", desc)
 		}
 
 	} else {
@@ -0,0 +1,73 @@
+// Package snmptest provides an SNMP agent for testing clients.
+package snmptest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PreetamJinka/snmp"
+)
+
+// An Agent is an SNMPv1 and SNMPv2c agent listening on the loopback
+// interface, serving a fixed table of objects to any community.
+type Agent struct {
+	addr string
+}
+
+// NewAgent starts an Agent answering GET, GETNEXT, and GETBULK requests
+// from table, which maps dotted OIDs such as ".1.3.6.1.2.1.1.1.0" to
+// their values. The Agent is closed when the test and its subtests
+// complete. NewAgent fails the test if an OID is invalid or the Agent
+// can't listen.
+func NewAgent(t *testing.T, table map[string]snmp.DataType) *Agent {
+	t.Helper()
+
+	a := &snmp.Agent{Addr: "127.0.0.1:0"}
+
+	for s, value := range table {
+		oid, err := snmp.ParseOID(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a.Handle(oid, func() (snmp.DataType, error) { return value, nil })
+	}
+
+	var err error
+	done := make(chan struct{})
+
+	go func() {
+		err = a.ListenAndServe()
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		a.Close()
+		<-done
+
+		if err != nil && !t.Failed() {
+			t.Error(err)
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		if addr := a.LocalAddr(); addr != nil {
+			return &Agent{addr: addr.String()}
+		}
+
+		select {
+		case <-done:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("snmptest: the agent didn't start listening")
+	return nil
+}
+
+// Addr returns the address the Agent is listening on as host:port,
+// for use as the Addr of a snmp.Client.
+func (a *Agent) Addr() string {
+	return a.addr
+}
@@ -0,0 +1,65 @@
+package snmptest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PreetamJinka/snmp"
+)
+
+var (
+	sysDescr = snmp.MustParseOID(".1.3.6.1.2.1.1.1.0")
+	ifDescr  = snmp.MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+)
+
+func TestNewAgent(t *testing.T) {
+	agent := NewAgent(t, map[string]snmp.DataType{
+		".1.3.6.1.2.1.1.1.0":     snmp.String("test agent"),
+		".1.3.6.1.2.1.2.2.1.2.1": snmp.String("lo"),
+		".1.3.6.1.2.1.2.2.1.2.2": snmp.String("eth0"),
+		".1.3.6.1.2.1.2.2.1.2.3": snmp.String("eth1"),
+	})
+
+	for _, version := range []int{snmp.Version1, snmp.Version2c} {
+		c := &snmp.Client{Addr: agent.Addr(), Community: "public", Version: version, Timeout: time.Second}
+
+		varbinds, err := c.Get(sysDescr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(varbinds) != 1 || varbinds[0].Value() != snmp.String("test agent") {
+			t.Errorf("v%d: unexpected sysDescr %v", version, varbinds)
+		}
+
+		v, err := c.GetNext(sysDescr)
+		if err != nil || !v.OID.Equal(ifDescr.Child(1)) {
+			t.Errorf("v%d: expected ifDescr.1 after sysDescr, got %v and %v", version, v, err)
+		}
+
+		varbinds, err = c.WalkAll(ifDescr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(varbinds) != 3 || varbinds[2].Value() != snmp.String("eth1") {
+			t.Errorf("v%d: unexpected walk %v", version, varbinds)
+		}
+	}
+
+	c := &snmp.Client{Addr: agent.Addr(), Community: "public", Version: snmp.Version2c, Timeout: time.Second}
+
+	var walked []snmp.Varbind
+	err := c.BulkWalk(ifDescr, 2, func(v snmp.Varbind) error {
+		walked = append(walked, v)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(walked) != 3 || !walked[0].OID.Equal(ifDescr.Child(1)) || walked[1].Value() != snmp.String("eth0") {
+		t.Errorf("unexpected bulk walk %v", walked)
+	}
+}
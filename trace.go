@@ -0,0 +1,57 @@
+package snmp
+
+// Trace holds callbacks invoked as a Client exchanges messages, such
+// as for logging them. Any of the callbacks may be nil. They may be
+// called concurrently, and must not retain or modify the slices they
+// are passed.
+type Trace struct {
+	// OnSend is called with each packet before it is sent,
+	// including resent packets.
+	OnSend func(b []byte)
+
+	// OnReceive is called with each datagram received,
+	// whether or not it is a response to a request.
+	OnReceive func(b []byte)
+
+	// OnRetry is called before a request is resent after a timeout.
+	// attempt is 1 for the first retry.
+	OnRetry func(attempt int)
+
+	// OnError is called with the error of each failed request and
+	// with the decoding error of each datagram that is ignored.
+	OnError func(err error)
+
+	// OnBulkRepetitions is called with the max-repetitions of each
+	// GETBULK request sent by BulkWalk, which adapts it to the agent.
+	OnBulkRepetitions func(n int)
+}
+
+func (t *Trace) send(b []byte) {
+	if t != nil && t.OnSend != nil {
+		t.OnSend(b)
+	}
+}
+
+func (t *Trace) receive(b []byte) {
+	if t != nil && t.OnReceive != nil {
+		t.OnReceive(b)
+	}
+}
+
+func (t *Trace) retry(attempt int) {
+	if t != nil && t.OnRetry != nil {
+		t.OnRetry(attempt)
+	}
+}
+
+func (t *Trace) bulkRepetitions(n int) {
+	if t != nil && t.OnBulkRepetitions != nil {
+		t.OnBulkRepetitions(n)
+	}
+}
+
+func (t *Trace) error(err error) {
+	if t != nil && t.OnError != nil {
+		t.OnError(err)
+	}
+}
@@ -0,0 +1,13 @@
+package snmp
+
+import "context"
+
+// A Transport carries the messages of a Client in place of its UDP
+// socket or TCP connection, such as a mock in tests or SNMP over DTLS
+// as in RFC 6353. RoundTrip sends the encoded message req and returns
+// the encoded response. It is canceled through ctx when the request
+// is, or when its attempt times out, after which the Client may send
+// req again. RoundTrip is called concurrently by concurrent requests.
+type Transport interface {
+	RoundTrip(ctx context.Context, req []byte) (resp []byte, err error)
+}
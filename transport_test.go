@@ -0,0 +1,89 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// transportFunc is a Transport that calls itself for each round trip.
+type transportFunc func(ctx context.Context, req []byte) ([]byte, error)
+
+func (f transportFunc) RoundTrip(ctx context.Context, req []byte) ([]byte, error) {
+	return f(ctx, req)
+}
+
+func TestClientTransport(t *testing.T) {
+	agent := &stubAgent{varbinds: []Varbind{NewVarbind(sysDescr, String("in memory"))}}
+
+	transport := transportFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+		m, err := Unmarshal(req)
+		if err != nil {
+			return nil, err
+		}
+
+		return agent.respond(m).Encode()
+	})
+
+	c := &Client{Community: "public", Version: Version2c, Timeout: time.Second, Transport: transport}
+
+	varbinds, err := c.Get(sysDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(varbinds) != 1 || varbinds[0].Value() != String("in memory") {
+		t.Errorf("unexpected varbinds %v", varbinds)
+	}
+
+	count := 0
+	if err := c.Walk(ifDescr, func(Varbind) error { count++; return nil }); err != nil || count != 0 {
+		t.Errorf("expected an empty walk, got %d and %v", count, err)
+	}
+}
+
+func TestClientTransportError(t *testing.T) {
+	down := errors.New("link down")
+
+	c := &Client{Community: "public", Version: Version2c, Timeout: time.Second}
+	c.Transport = transportFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+		return nil, down
+	})
+
+	if _, err := c.Get(sysDescr); !errors.Is(err, down) {
+		t.Errorf("expected the error of the transport, got %v", err)
+	}
+}
+
+func TestClientTransportTimeout(t *testing.T) {
+	var attempts, canceled int32
+
+	c := &Client{Community: "public", Version: Version2c, Timeout: 50 * time.Millisecond, Retries: 2}
+	c.Transport = transportFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+		atomic.AddInt32(&attempts, 1)
+		<-ctx.Done()
+		atomic.AddInt32(&canceled, 1)
+
+		return nil, ctx.Err()
+	})
+
+	if _, err := c.Get(sysDescr); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+
+	// Each round trip is canceled once its attempt times out
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&canceled) != 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&canceled); n != 3 {
+		t.Errorf("expected 3 canceled round trips, got %d", n)
+	}
+}
@@ -0,0 +1,172 @@
+package snmp
+
+import (
+	"io"
+)
+
+// Generic trap types of an SNMPv1 Trap-PDU.
+const (
+	ColdStart             = 0
+	WarmStart             = 1
+	LinkDown              = 2
+	LinkUp                = 3
+	AuthenticationFailure = 4
+	EGPNeighborLoss       = 5
+	EnterpriseSpecific    = 6
+)
+
+var (
+	sysUpTimeOID   = ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	snmpTrapOIDOID = ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+)
+
+// TrapV1 represents an SNMPv1 Trap-PDU.
+type TrapV1 struct {
+	Enterprise   ObjectIdentifier
+	AgentAddr    IpAddress
+	GenericTrap  int
+	SpecificTrap int
+	Timestamp    TimeTicks
+	Varbinds     []Varbind
+}
+
+// Encode encodes a TrapV1 with the proper header.
+func (t TrapV1) Encode() ([]byte, error) {
+	varbindsSequence := Sequence{}
+	for _, v := range t.Varbinds {
+		varbindsSequence = append(varbindsSequence, v)
+	}
+
+	pdu := PDU{
+		rawSequence: []DataType{
+			t.Enterprise,
+			t.AgentAddr,
+			Int(t.GenericTrap),
+			Int(t.SpecificTrap),
+			t.Timestamp,
+			varbindsSequence,
+		},
+	}
+
+	return pdu.encode(TypeTrap)
+}
+
+// decodeTrapV1 decodes a TrapV1 up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeTrapV1(length int, r io.Reader) (TrapV1, int, error) {
+	seq, bytesRead, err := decodeSequence(length, r)
+	if err != nil {
+		return TrapV1{}, bytesRead, err
+	}
+
+	if len(seq) != 6 {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	enterprise, ok := seq[0].(ObjectIdentifier)
+	if !ok {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	agentAddr, ok := seq[1].(IpAddress)
+	if !ok {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	genericTrap, ok := seq[2].(Int)
+	if !ok {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	specificTrap, ok := seq[3].(Int)
+	if !ok {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	timestamp, ok := seq[4].(TimeTicks)
+	if !ok {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	varbindSeq, ok := seq[5].(Sequence)
+	if !ok {
+		return TrapV1{}, bytesRead, ErrDecodingType
+	}
+
+	t := TrapV1{
+		Enterprise:   enterprise,
+		AgentAddr:    agentAddr,
+		GenericTrap:  int(genericTrap),
+		SpecificTrap: int(specificTrap),
+		Timestamp:    timestamp,
+	}
+
+	for _, varbindElem := range varbindSeq {
+		v, err := varbindFromSequence(varbindElem)
+		if err != nil {
+			return TrapV1{}, bytesRead, err
+		}
+
+		t.Varbinds = append(t.Varbinds, v)
+	}
+
+	return t, bytesRead, nil
+}
+
+// TrapV2 represents an SNMPv2-Trap-PDU.
+// It is not valid in SNMPv1 messages.
+type TrapV2 struct {
+	PDU
+}
+
+// NewTrapV2 returns a new TrapV2 for the notification trapOID.
+// The sysUpTime.0 and snmpTrapOID.0 variable bindings are added
+// before extra.
+func NewTrapV2(requestID int32, uptime TimeTicks, trapOID ObjectIdentifier, extra ...Varbind) TrapV2 {
+	return TrapV2{
+		PDU: newPDU(int(requestID), 0, 0, notificationVarbinds(uptime, trapOID, extra)),
+	}
+}
+
+// notificationVarbinds returns the variable bindings of a notification.
+func notificationVarbinds(uptime TimeTicks, trapOID ObjectIdentifier, extra []Varbind) []Varbind {
+	varbinds := make([]Varbind, 0, len(extra)+2)
+	varbinds = append(varbinds,
+		NewVarbind(sysUpTimeOID, uptime),
+		NewVarbind(snmpTrapOIDOID, trapOID),
+	)
+
+	return append(varbinds, extra...)
+}
+
+// Uptime returns the sysUpTime.0 value of a notification.
+func (p PDU) Uptime() (TimeTicks, bool) {
+	if len(p.varbinds) < 1 || !p.varbinds[0].OID.Equal(sysUpTimeOID) {
+		return 0, false
+	}
+
+	uptime, ok := p.varbinds[0].value.(TimeTicks)
+	return uptime, ok
+}
+
+// TrapOID returns the snmpTrapOID.0 value of a notification.
+func (p PDU) TrapOID() (ObjectIdentifier, bool) {
+	if len(p.varbinds) < 2 || !p.varbinds[1].OID.Equal(snmpTrapOIDOID) {
+		return nil, false
+	}
+
+	oid, ok := p.varbinds[1].value.(ObjectIdentifier)
+	return oid, ok
+}
+
+// Encode encodes a TrapV2 with the proper header.
+func (t TrapV2) Encode() ([]byte, error) {
+	return t.PDU.encode(TypeTrapV2)
+}
+
+// decodeTrapV2 decodes a TrapV2 up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeTrapV2(length int, r io.Reader) (TrapV2, int, error) {
+	pdu, bytesRead, err := decodePDU(length, r)
+	return TrapV2{PDU: pdu}, bytesRead, err
+}
@@ -0,0 +1,119 @@
+package snmp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTrapV1Encoding(t *testing.T) {
+	m := Message{
+		Version:   Version1,
+		Community: "public",
+		PDU: TrapV1{
+			Enterprise:  MustParseOID(".1.3.6.1.4.1.8072.3.2.10"),
+			AgentAddr:   IpAddress{192, 0, 2, 1},
+			GenericTrap: ColdStart,
+			Timestamp:   100,
+		},
+	}
+
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SNMPv1 coldStart trap from 192.0.2.1
+	expected := []byte{
+		0x30, 0x2a,
+		0x02, 0x01, 0x00,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa4, 0x1d,
+		0x06, 0x0a, 0x2b, 0x06, 0x01, 0x04, 0x01, 0xbf, 0x08, 0x03, 0x02, 0x0a,
+		0x40, 0x04, 0xc0, 0x00, 0x02, 0x01,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x43, 0x01, 0x64,
+		0x30, 0x00,
+	}
+
+	if !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+
+	decoded, _, err := decodeMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trap, ok := decoded.PDU.(TrapV1)
+	if !ok {
+		t.Fatalf("expected a TrapV1, got %T", decoded.PDU)
+	}
+
+	if !trap.Enterprise.Equal(MustParseOID(".1.3.6.1.4.1.8072.3.2.10")) ||
+		trap.AgentAddr.String() != "192.0.2.1" ||
+		trap.GenericTrap != ColdStart ||
+		trap.Timestamp != 100 {
+		t.Errorf("unexpected decoded trap %+v", trap)
+	}
+
+	m.Version = Version2c
+	if _, err := m.Encode(); err == nil {
+		t.Error("expected an error encoding a TrapV1 in an SNMPv2c message")
+	}
+}
+
+func TestTrapV2(t *testing.T) {
+	linkDown := MustParseOID(".1.3.6.1.6.3.1.1.5.3")
+	ifIndex := NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.1.2"), Int(2))
+
+	trap := NewTrapV2(5, 4200, linkDown, ifIndex)
+
+	varbinds := trap.Varbinds()
+	if len(varbinds) != 3 {
+		t.Fatalf("expected 3 varbinds, got %d", len(varbinds))
+	}
+
+	if !varbinds[0].OID.Equal(MustParseOID(".1.3.6.1.2.1.1.3.0")) || varbinds[0].Value() != TimeTicks(4200) {
+		t.Errorf("expected sysUpTime.0 = 4200 first, got %v = %v", varbinds[0].OID, varbinds[0].Value())
+	}
+
+	if !varbinds[1].OID.Equal(MustParseOID(".1.3.6.1.6.3.1.1.4.1.0")) {
+		t.Errorf("expected snmpTrapOID.0 second, got %v", varbinds[1].OID)
+	}
+
+	if oid, ok := varbinds[1].Value().(ObjectIdentifier); !ok || !oid.Equal(linkDown) {
+		t.Errorf("expected %v, got %v", linkDown, varbinds[1].Value())
+	}
+
+	b, err := Message{Version: Version2c, Community: "public", PDU: trap}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b[13] != TypeTrapV2 {
+		t.Errorf("expected PDU type 0x%x, got 0x%x", TypeTrapV2, b[13])
+	}
+
+	decoded, _, err := decodeMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedTrap, ok := decoded.PDU.(TrapV2)
+	if !ok {
+		t.Fatalf("expected a TrapV2, got %T", decoded.PDU)
+	}
+
+	if uptime, ok := decodedTrap.Uptime(); !ok || uptime != 4200 {
+		t.Errorf("expected uptime 4200, got %v", uptime)
+	}
+
+	if oid, ok := decodedTrap.TrapOID(); !ok || !oid.Equal(linkDown) {
+		t.Errorf("expected trap OID %v, got %v", linkDown, oid)
+	}
+
+	if _, err := (Message{Version: Version1, Community: "public", PDU: trap}).Encode(); err == nil {
+		t.Error("expected an error encoding a TrapV2 in an SNMPv1 message")
+	}
+}
@@ -0,0 +1,255 @@
+package snmp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultTrapPort is the port used when a TrapListener
+// address doesn't include one.
+const defaultTrapPort = "162"
+
+// defaultDedupCacheSize is the number of traps remembered when
+// TrapListener.DedupCacheSize is zero.
+const defaultDedupCacheSize = 1024
+
+// A TrapListener receives SNMP traps and notifications over UDP.
+type TrapListener struct {
+	// Addr is the address to listen on as host or host:port.
+	// Port 162 is used if no port is given.
+	Addr string
+
+	// DedupWindow, if positive, suppresses a trap identical to one
+	// received within the window before it, since UDP may deliver a
+	// datagram more than once. Traps are identical if they have the
+	// same source, trap identity and variable bindings, in any order;
+	// the request ID is ignored.
+	DedupWindow time.Duration
+
+	// DedupCacheSize is the most traps remembered for DedupWindow,
+	// after which the oldest are forgotten early. If it's zero,
+	// 1024 are remembered.
+	DedupCacheSize int
+
+	conn   net.PacketConn
+	closed bool
+	lock   sync.Mutex
+}
+
+// Listen listens for traps and calls fn with each decoded Message.
+// Datagrams that can't be decoded are logged and skipped. Datagrams are
+// received one at a time into a single buffer, but the Message doesn't
+// refer to it, so fn may keep the Message. Duplicates are skipped as
+// DedupWindow describes. Listen blocks until Close is called, after
+// which it returns nil.
+func (l *TrapListener) Listen(fn func(src net.Addr, msg *Message)) error {
+	conn, err := net.ListenPacket("udp", withDefaultPort(l.Addr, defaultTrapPort))
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		conn.Close()
+		return nil
+	}
+
+	if l.conn != nil {
+		l.lock.Unlock()
+		conn.Close()
+		return errors.New("snmp: TrapListener is already listening")
+	}
+
+	l.conn = conn
+	l.lock.Unlock()
+
+	buf := make([]byte, 65535)
+
+	var dedup *trapDedup
+	if l.DedupWindow > 0 {
+		dedup = newTrapDedup(l.DedupWindow, l.DedupCacheSize)
+	}
+
+	for {
+		n, src, err := conn.ReadFrom(buf)
+		if err != nil {
+			l.lock.Lock()
+			closed := l.closed
+			l.lock.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		m, _, err := decodeMessage(bytes.NewReader(buf[:n]))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if dedup != nil {
+			if key, ok := trapDigest(src, m); ok && dedup.duplicate(key, time.Now()) {
+				continue
+			}
+		}
+
+		fn(src, m)
+	}
+}
+
+// LocalAddr returns the address the TrapListener is listening on,
+// or nil if it isn't listening.
+func (l *TrapListener) LocalAddr() net.Addr {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	return l.conn.LocalAddr()
+}
+
+// Close stops the TrapListener.
+func (l *TrapListener) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.closed = true
+
+	if l.conn == nil {
+		return nil
+	}
+
+	return l.conn.Close()
+}
+
+// trapDedup remembers the digests of recent traps for a TrapListener.
+type trapDedup struct {
+	window time.Duration
+	size   int
+
+	seen map[[sha256.Size]byte]bool
+
+	// order holds the remembered digests from oldest to newest.
+	order []dedupEntry
+}
+
+type dedupEntry struct {
+	key [sha256.Size]byte
+	at  time.Time
+}
+
+func newTrapDedup(window time.Duration, size int) *trapDedup {
+	if size <= 0 {
+		size = defaultDedupCacheSize
+	}
+
+	return &trapDedup{
+		window: window,
+		size:   size,
+		seen:   map[[sha256.Size]byte]bool{},
+	}
+}
+
+// duplicate reports whether key was seen within the window before now,
+// remembering it if not.
+func (d *trapDedup) duplicate(key [sha256.Size]byte, now time.Time) bool {
+	for len(d.order) > 0 && now.Sub(d.order[0].at) >= d.window {
+		d.forgetOldest()
+	}
+
+	if d.seen[key] {
+		return true
+	}
+
+	if len(d.order) >= d.size {
+		d.forgetOldest()
+	}
+
+	d.seen[key] = true
+	d.order = append(d.order, dedupEntry{key: key, at: now})
+
+	return false
+}
+
+func (d *trapDedup) forgetOldest() {
+	delete(d.seen, d.order[0].key)
+	d.order = d.order[1:]
+}
+
+// trapDigest returns a hash of the source, trap identity and sorted
+// variable bindings of the notification m. It returns false if m isn't
+// a notification or can't be encoded.
+func trapDigest(src net.Addr, m *Message) ([sha256.Size]byte, bool) {
+	var identity []DataType
+	var varbinds []Varbind
+
+	switch pdu := m.PDU.(type) {
+	case TrapV1:
+		identity = []DataType{pdu.Enterprise, pdu.AgentAddr, Int(pdu.GenericTrap), Int(pdu.SpecificTrap)}
+		varbinds = pdu.Varbinds
+
+	case TrapV2:
+		// The snmpTrapOID.0 binding is the identity
+		varbinds = pdu.Varbinds()
+
+	case InformRequest:
+		varbinds = pdu.Varbinds()
+
+	default:
+		return [sha256.Size]byte{}, false
+	}
+
+	parts := [][]byte{[]byte(src.String())}
+
+	for _, t := range identity {
+		b, err := t.Encode()
+		if err != nil {
+			return [sha256.Size]byte{}, false
+		}
+
+		parts = append(parts, b)
+	}
+
+	sorted := make([][]byte, 0, len(varbinds))
+
+	for _, v := range varbinds {
+		b, err := v.Encode()
+		if err != nil {
+			return [sha256.Size]byte{}, false
+		}
+
+		sorted = append(sorted, b)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	parts = append(parts, sorted...)
+
+	h := sha256.New()
+
+	// Each part is prefixed with its length so that parts can't run
+	// into each other
+	var length [4]byte
+	for _, part := range parts {
+		binary.BigEndian.PutUint32(length[:], uint32(len(part)))
+		h.Write(length[:])
+		h.Write(part)
+	}
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	return key, true
+}
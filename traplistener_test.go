@@ -0,0 +1,259 @@
+package snmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// startTrapListener starts l and waits until it is listening.
+func startTrapListener(t *testing.T, l *TrapListener, fn func(src net.Addr, msg *Message)) net.Addr {
+	errs := make(chan error, 1)
+	go func() { errs <- l.Listen(fn) }()
+
+	t.Cleanup(func() {
+		l.Close()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		if addr := l.LocalAddr(); addr != nil {
+			return addr
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("TrapListener didn't start listening")
+	return nil
+}
+
+func TestTrapListener(t *testing.T) {
+	l := &TrapListener{Addr: "127.0.0.1:0"}
+
+	messages := make(chan *Message, 1)
+	addr := startTrapListener(t, l, func(src net.Addr, msg *Message) {
+		messages <- msg
+	})
+
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// Malformed datagrams are skipped
+	conn.Write([]byte{0x30, 0x03, 0x02})
+
+	linkUp := MustParseOID(".1.3.6.1.6.3.1.1.5.4")
+
+	b, err := Message{Version: Version2c, Community: "public", PDU: NewTrapV2(1, 100, linkUp)}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Write(b)
+
+	select {
+	case msg := <-messages:
+		trap, ok := msg.PDU.(TrapV2)
+		if !ok {
+			t.Fatalf("expected a TrapV2, got %T", msg.PDU)
+		}
+
+		if oid, ok := trap.TrapOID(); !ok || !oid.Equal(linkUp) {
+			t.Errorf("expected trap OID %v, got %v", linkUp, oid)
+		}
+
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the trap")
+	}
+}
+
+func TestTrapListenerRetainedMessages(t *testing.T) {
+	l := &TrapListener{Addr: "127.0.0.1:0"}
+
+	messages := make(chan *Message, 2)
+	addr := startTrapListener(t, l, func(src net.Addr, msg *Message) {
+		messages <- msg
+	})
+
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	linkDown := MustParseOID(".1.3.6.1.6.3.1.1.5.3")
+
+	var retained []*Message
+
+	// Traps of the same size are received into the same bytes
+	for _, name := range []string{"eth0", "eth1"} {
+		b, err := Message{
+			Version:   Version2c,
+			Community: "public",
+			PDU:       NewTrapV2(1, 100, linkDown, NewVarbind(ifDescr.Child(1), String(name))),
+		}.Encode()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Write(b)
+
+		select {
+		case msg := <-messages:
+			retained = append(retained, msg)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the trap")
+		}
+	}
+
+	for i, expected := range []String{"eth0", "eth1"} {
+		varbinds := retained[i].PDU.(TrapV2).Varbinds()
+		if value := varbinds[len(varbinds)-1].Value(); value != expected {
+			t.Errorf("expected trap %d to keep %q, got %v", i, expected, value)
+		}
+	}
+}
+
+func TestTrapListenerDedup(t *testing.T) {
+	l := &TrapListener{Addr: "127.0.0.1:0", DedupWindow: time.Minute}
+
+	messages := make(chan *Message, 3)
+	addr := startTrapListener(t, l, func(src net.Addr, msg *Message) {
+		messages <- msg
+	})
+
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	linkDown := MustParseOID(".1.3.6.1.6.3.1.1.5.3")
+	name := NewVarbind(ifDescr.Child(1), String("eth0"))
+	status := NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.8.1"), Int(2))
+
+	// The resent trap has another request ID and order of bindings
+	for _, pdu := range []TrapV2{
+		NewTrapV2(1, 100, linkDown, name, status),
+		NewTrapV2(2, 100, linkDown, status, name),
+		NewTrapV2(3, 100, linkDown, NewVarbind(ifDescr.Child(1), String("eth1")), status),
+	} {
+		b, err := Message{Version: Version2c, Community: "public", PDU: pdu}.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Write(b)
+	}
+
+	for _, expected := range []int{1, 3} {
+		select {
+		case msg := <-messages:
+			if id := msg.PDU.(TrapV2).requestID; id != expected {
+				t.Errorf("expected trap %d, got %d", expected, id)
+			}
+
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for trap %d", expected)
+		}
+	}
+
+	select {
+	case msg := <-messages:
+		t.Errorf("expected the duplicate to be skipped, got %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTrapDedupEviction(t *testing.T) {
+	d := newTrapDedup(time.Minute, 2)
+	now := time.Now()
+
+	a, b, c := [32]byte{1}, [32]byte{2}, [32]byte{3}
+
+	if d.duplicate(a, now) || !d.duplicate(a, now.Add(time.Second)) {
+		t.Error("expected a to be a duplicate only the second time")
+	}
+
+	// The window is from the first delivery
+	if d.duplicate(a, now.Add(time.Minute)) {
+		t.Error("expected a to be forgotten after the window")
+	}
+
+	// c evicts a, the oldest, from the cache of 2
+	d.duplicate(b, now.Add(time.Minute))
+	d.duplicate(c, now.Add(time.Minute))
+
+	if len(d.order) != 2 || len(d.seen) != 2 {
+		t.Errorf("expected 2 traps remembered, got %d and %d", len(d.order), len(d.seen))
+	}
+
+	if d.duplicate(a, now.Add(time.Minute)) {
+		t.Error("expected a to be evicted")
+	}
+
+	if !d.duplicate(c, now.Add(time.Minute)) {
+		t.Error("expected c to be remembered")
+	}
+}
+
+func BenchmarkTrapListener(b *testing.B) {
+	l := &TrapListener{Addr: "127.0.0.1:0"}
+
+	received := make(chan struct{}, 1)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- l.Listen(func(net.Addr, *Message) { received <- struct{}{} })
+	}()
+
+	defer func() {
+		l.Close()
+		<-errs
+	}()
+
+	for l.LocalAddr() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	conn, err := net.Dial("udp", l.LocalAddr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	packet, err := Message{
+		Version:   Version2c,
+		Community: "public",
+		PDU: NewTrapV2(1, 100, MustParseOID(".1.3.6.1.6.3.1.1.5.3"),
+			NewVarbind(ifDescr.Child(1), String("eth0")),
+			NewVarbind(MustParseOID(".1.3.6.1.2.1.2.2.1.8.1"), Int(2))),
+	}.Encode()
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	// Each trap is sent once the last was handled, so none are dropped
+	for i := 0; i < b.N; i++ {
+		conn.Write(packet)
+
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			b.Fatal("timed out waiting for the trap")
+		}
+	}
+}
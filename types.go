@@ -2,6 +2,16 @@ package snmp
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -14,6 +24,7 @@ const (
 	TypeCounter   = 0x41
 	TypeGauge     = 0x42
 	TypeTimeTicks = 0x43
+	TypeOpaque    = 0x44
 	TypeCounter64 = 0x46
 
 	TypeNoSuchObject   = 0x80
@@ -23,6 +34,11 @@ const (
 	TypeGetRequest     = 0xa0
 	TypeGetNextRequest = 0xa1
 	TypeGetResponse    = 0xa2
+	TypeSetRequest     = 0xa3
+	TypeTrap           = 0xa4
+	TypeGetBulkRequest = 0xa5
+	TypeInformRequest  = 0xa6
+	TypeTrapV2         = 0xa7
 	TypeReport         = 0xa8
 )
 
@@ -39,14 +55,233 @@ func (s String) Encode() ([]byte, error) {
 	return append(encodeHeaderSequence(0x4, len(s)), []byte(s)...), nil
 }
 
-// String represents an SNMP IpAddress.
+// String returns the text of a String if it is printable UTF-8, where
+// tabs and line breaks count as printable. Otherwise it returns the
+// bytes in hex as Net-SNMP prints them, such as "Hex-STRING: 00 1A 2B".
+func (s String) String() string {
+	if s.printable() {
+		return string(s)
+	}
+
+	return "Hex-STRING: " + s.Hex()
+}
+
+// Hex returns the bytes of a String in hex separated
+// by spaces, such as "00 1A 2B".
+func (s String) Hex() string {
+	buf := &strings.Builder{}
+
+	for i := 0; i < len(s); i++ {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+
+		fmt.Fprintf(buf, "%02X", s[i])
+	}
+
+	return buf.String()
+}
+
+// printable reports whether s is valid UTF-8 of printable
+// characters, tabs, and line breaks.
+func (s String) printable() bool {
+	if !utf8.ValidString(string(s)) {
+		return false
+	}
+
+	for _, r := range s {
+		if !unicode.IsPrint(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeString decodes a String up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeString(length int, r io.Reader) (String, int, error) {
+	str, n, err := readContent(length, r)
+	if err != nil {
+		return "", n, err
+	}
+
+	return String(str), n, nil
+}
+
+// IpAddress represents an SNMP IpAddress.
+// It is always four bytes long.
 type IpAddress []byte
 
-// Encode encodes a String with the proper header.
+// NewIpAddress returns ip as an IpAddress.
+// An error is returned if ip is not an IPv4 address.
+func NewIpAddress(ip net.IP) (IpAddress, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("snmp: %v is not an IPv4 address", ip)
+	}
+
+	return IpAddress(append([]byte(nil), ip4...)), nil
+}
+
+// Encode encodes an IpAddress with the proper header.
 func (ip IpAddress) Encode() ([]byte, error) {
+	if len(ip) != 4 {
+		return nil, errors.New("snmp: IpAddress must be 4 bytes")
+	}
+
 	return append(encodeHeaderSequence(TypeIpAddress, len(ip)), []byte(ip)...), nil
 }
 
+// String returns an IpAddress in dotted-quad form.
+func (ip IpAddress) String() string {
+	if len(ip) != 4 {
+		return fmt.Sprintf("IpAddress(%x)", []byte(ip))
+	}
+
+	return net.IP(ip).String()
+}
+
+// decodeIpAddress decodes an IpAddress up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeIpAddress(length int, r io.Reader) (IpAddress, int, error) {
+	if length != 4 {
+		return nil, 0, errors.New("snmp: IpAddress must be 4 bytes")
+	}
+
+	ip := make(IpAddress, length)
+
+	n, err := io.ReadFull(r, ip)
+	if err != nil {
+		return nil, n, err
+	}
+
+	return ip, n, nil
+}
+
+// InetAddress represents an RFC 4001 InetAddress, an OCTET STRING
+// holding an IPv4 or IPv6 address in network byte order. Unlike
+// IpAddress, it is used for IPv6 addresses. Agents return it as a
+// String, which can be converted with InetAddress(s).
+type InetAddress []byte
+
+// NewInetAddress returns ip as an InetAddress. IPv4 addresses are
+// 4 bytes long, and IPv6 addresses are 16 bytes long.
+func NewInetAddress(ip net.IP) (InetAddress, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return InetAddress(append([]byte(nil), ip4...)), nil
+	}
+
+	if len(ip) != net.IPv6len {
+		return nil, fmt.Errorf("snmp: invalid IP address %v", ip)
+	}
+
+	return InetAddress(append([]byte(nil), ip...)), nil
+}
+
+// Encode encodes an InetAddress as an OCTET STRING.
+func (a InetAddress) Encode() ([]byte, error) {
+	return String(a).Encode()
+}
+
+// IP returns the address of an InetAddress. ok is false if it isn't
+// an ipv4, ipv6, ipv4z, or ipv6z address; the zone index of the
+// latter two is ignored.
+func (a InetAddress) IP() (ip net.IP, ok bool) {
+	switch len(a) {
+	case net.IPv4len, net.IPv4len + 4:
+		return net.IP(append([]byte(nil), a[:net.IPv4len]...)), true
+	case net.IPv6len, net.IPv6len + 4:
+		return net.IP(append([]byte(nil), a[:net.IPv6len]...)), true
+	}
+
+	return nil, false
+}
+
+// String returns an InetAddress in the textual form of its address.
+func (a InetAddress) String() string {
+	ip, ok := a.IP()
+	if !ok {
+		return fmt.Sprintf("InetAddress(%x)", []byte(a))
+	}
+
+	return ip.String()
+}
+
+// Opaque represents an SNMP Opaque, which wraps the BER encoding of
+// another value. Net-SNMP uses it to carry floats and doubles, which
+// AsFloat decodes; the raw contents are kept for other encodings.
+type Opaque []byte
+
+// Encode encodes an Opaque with the proper header.
+func (o Opaque) Encode() ([]byte, error) {
+	return append(encodeHeaderSequence(TypeOpaque, len(o)), []byte(o)...), nil
+}
+
+// OpaqueFloat returns an Opaque holding f as a Net-SNMP float.
+func OpaqueFloat(f float32) Opaque {
+	o := Opaque{0x9f, 0x78, 4, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(o[3:], math.Float32bits(f))
+
+	return o
+}
+
+// OpaqueDouble returns an Opaque holding f as a Net-SNMP double.
+func OpaqueDouble(f float64) Opaque {
+	o := Opaque{0x9f, 0x79, 8, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(o[3:], math.Float64bits(f))
+
+	return o
+}
+
+// AsFloat returns the value of an Opaque holding a Net-SNMP float
+// or double. ok is false for any other contents.
+func (o Opaque) AsFloat() (f float64, ok bool) {
+	// Net-SNMP uses the extension tags 0x9f78 for a 4-byte
+	// float and 0x9f79 for an 8-byte double
+	if len(o) < 3 || o[0] != 0x9f || int(o[2]) != len(o)-3 {
+		return 0, false
+	}
+
+	switch {
+	case o[1] == 0x78 && o[2] == 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(o[3:]))), true
+	case o[1] == 0x79 && o[2] == 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(o[3:])), true
+	}
+
+	return 0, false
+}
+
+// decodeOpaque decodes an Opaque up to length bytes from r.
+// It returns the SNMP data type, the number of bytes read, and an error.
+func decodeOpaque(length int, r io.Reader) (Opaque, int, error) {
+	o, n, err := readContent(length, r)
+	if err != nil {
+		return nil, n, err
+	}
+
+	return Opaque(o), n, nil
+}
+
+// UnknownValue is a value whose tag isn't that of any type of this
+// package, such as one of a vendor-specific application type, as it
+// was decoded. It encodes back to the same bytes.
+type UnknownValue struct {
+	Tag   byte
+	Bytes []byte
+}
+
+// Encode encodes an UnknownValue with its tag.
+func (u UnknownValue) Encode() ([]byte, error) {
+	return append(encodeHeaderSequence(u.Tag, len(u.Bytes)), u.Bytes...), nil
+}
+
+// String returns the bytes of an UnknownValue in hex, like String.Hex.
+func (u UnknownValue) String() string {
+	return String(u.Bytes).Hex()
+}
+
 // Report represents an SNMP Report-PDU.
 type Report []DataType
 
@@ -71,6 +306,19 @@ func (s Report) Encode() ([]byte, error) {
 	return append(encodeHeaderSequence(0xa8, seqLength), buf.Bytes()...), nil
 }
 
+// Err returns the error a Report describes by the counter in its first
+// variable binding, such as ErrWrongDigest for usmStatsWrongDigests or
+// ErrNotInTimeWindow for usmStatsNotInTimeWindows. Unknown counters
+// are reported by OID.
+func (s Report) Err() error {
+	return reportError(s)
+}
+
+// pdu returns the PDU carried by a Report.
+func (s Report) pdu() (PDU, error) {
+	return pduFromSequence(s)
+}
+
 type tag byte
 
 func (t tag) Encode() ([]byte, error) {
@@ -78,44 +326,147 @@ func (t tag) Encode() ([]byte, error) {
 }
 
 // Null represents an SNMP NULL.
+// It is used as the value of variable bindings in requests.
 const Null tag = 0x05
 
+// decodeNull decodes a NULL of the given length.
+// It returns an error if length is not zero.
+func decodeNull(length int) (tag, error) {
+	if length != 0 {
+		return 0, errors.New("snmp: NULL with non-zero length")
+	}
+
+	return Null, nil
+}
+
+// NoSuchObject, NoSuchInstance, and EndOfMIBView are the SNMPv2
+// exception values an agent returns in place of a variable's value.
 const NoSuchObject tag = TypeNoSuchObject
 const NoSuchInstance tag = TypeNoSuchInstance
 const EndOfMIBView tag = TypeEndOfMIBView
 
-// Gauge represents an SNMP Gauge data type.
-type Gauge int
+// String returns the name of a tag.
+func (t tag) String() string {
+	switch t {
+	case Null:
+		return "NULL"
+	case NoSuchObject:
+		return "noSuchObject"
+	case NoSuchInstance:
+		return "noSuchInstance"
+	case EndOfMIBView:
+		return "endOfMibView"
+	}
+
+	return fmt.Sprintf("tag(0x%x)", byte(t))
+}
+
+// decodeException decodes an exception value of the given type and length.
+// It returns an error if length is not zero.
+func decodeException(t byte, length int) (tag, error) {
+	if length != 0 {
+		return 0, fmt.Errorf("snmp: %v with non-zero length", tag(t))
+	}
+
+	return tag(t), nil
+}
+
+// Gauge represents an SNMP Gauge32 data type.
+// SNMPv2 also refers to this type as Unsigned32.
+type Gauge uint32
 
 // Encode encodes a Gauge with the proper header.
 func (g Gauge) Encode() ([]byte, error) {
-	result := encodeInteger(int(g))
+	result := encodeUnsigned(uint64(g))
 	return append(encodeHeaderSequence(TypeGauge, len(result)), result...), nil
 }
 
-// Counter represents an SNMP Counter data type.
-type Counter int
+// Counter represents an SNMP Counter32 data type.
+type Counter uint32
 
 // Encode encodes a Counter with the proper header.
 func (c Counter) Encode() ([]byte, error) {
-	result := encodeInteger(int(c))
+	result := encodeUnsigned(uint64(c))
 	return append(encodeHeaderSequence(TypeCounter, len(result)), result...), nil
 }
 
-// Counter64 represents an SNMP Counter64 data type.
-type Counter64 int
+// Counter64 represents an SNMPv2 Counter64 data type.
+// It is not valid in SNMPv1 messages.
+type Counter64 uint64
 
-// Encode encodes a Counter with the proper header.
+// Encode encodes a Counter64 with the proper header.
 func (c Counter64) Encode() ([]byte, error) {
-	result := encodeInteger(int(c))
+	result := encodeUnsigned(uint64(c))
 	return append(encodeHeaderSequence(TypeCounter64, len(result)), result...), nil
 }
 
 // TimeTicks represents an SNMP TimeTicks data type.
-type TimeTicks int
+// It counts hundredths of a second.
+type TimeTicks uint32
 
-// Encode encodes a Gauge with the proper header.
+// TimeTicksFromDuration returns d as TimeTicks. Durations are
+// truncated to a whole number of hundredths of a second, and
+// wrap around like sysUpTime if they don't fit in 32 bits.
+func TimeTicksFromDuration(d time.Duration) TimeTicks {
+	return TimeTicks(d / (10 * time.Millisecond))
+}
+
+// Duration returns t as a time.Duration.
+func (t TimeTicks) Duration() time.Duration {
+	return time.Duration(t) * 10 * time.Millisecond
+}
+
+// Format returns t as Net-SNMP prints uptimes, such as
+// "12 days, 3:04:05.67" or "1 day, 0:00:00.00". The days are left out
+// if there are none, as in "0:00:12.34".
+func (t TimeTicks) Format() string {
+	hundredths := t % 100
+	seconds := t / 100 % 60
+	minutes := t / 6000 % 60
+	hours := t / 360000 % 24
+	days := t / 8640000
+
+	clock := fmt.Sprintf("%d:%02d:%02d.%02d", hours, minutes, seconds, hundredths)
+
+	switch days {
+	case 0:
+		return clock
+	case 1:
+		return "1 day, " + clock
+	}
+
+	return fmt.Sprintf("%d days, %s", days, clock)
+}
+
+// Encode encodes a TimeTicks with the proper header.
 func (t TimeTicks) Encode() ([]byte, error) {
-	result := encodeInteger(int(t))
+	result := encodeUnsigned(uint64(t))
 	return append(encodeHeaderSequence(TypeTimeTicks, len(result)), result...), nil
 }
+
+// ValuesEqual reports whether a and b are values of the same type with
+// the same contents. Values of different types are never equal, even
+// if they have the same number, such as Int(5) and Gauge(5).
+func ValuesEqual(a, b DataType) bool {
+	switch a := a.(type) {
+	case IpAddress:
+		b, ok := b.(IpAddress)
+		return ok && bytes.Equal(a, b)
+	case InetAddress:
+		b, ok := b.(InetAddress)
+		return ok && bytes.Equal(a, b)
+	case Opaque:
+		b, ok := b.(Opaque)
+		return ok && bytes.Equal(a, b)
+	case UnknownValue:
+		b, ok := b.(UnknownValue)
+		return ok && a.Tag == b.Tag && bytes.Equal(a.Bytes, b.Bytes)
+	case ObjectIdentifier:
+		b, ok := b.(ObjectIdentifier)
+		return ok && a.Equal(b)
+	case String, Int, Gauge, Counter, Counter64, TimeTicks, tag:
+		return a == b
+	}
+
+	return false
+}
@@ -0,0 +1,393 @@
+package snmp
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStringEncoding(t *testing.T) {
+	long := String(strings.Repeat("x", 200))
+
+	cases := []struct {
+		str    String
+		header []byte
+	}{
+		{String(""), []byte{0x04, 0x00}},
+		{String("public"), []byte{0x04, 0x06}},
+		{String("\x00\xff\xfe"), []byte{0x04, 0x03}},
+		{long, []byte{0x04, 0x81, 0xc8}},
+	}
+
+	for _, c := range cases {
+		b, err := c.str.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(b[:len(c.header)], c.header) {
+			t.Errorf("expected header %v for %q, got %v", c.header, c.str, b[:len(c.header)])
+		}
+
+		decoded, n, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("decoding %q: %v", c.str, err)
+		}
+
+		if n != len(b) {
+			t.Errorf("expected %d bytes read, got %d", len(b), n)
+		}
+
+		if decoded != c.str {
+			t.Errorf("expected %q, got %q", c.str, decoded)
+		}
+	}
+
+	if _, _, err := decode(bytes.NewReader([]byte{0x04, 0x05, 'a', 'b'})); err == nil {
+		t.Error("expected an error decoding a truncated String")
+	}
+}
+
+func TestStringString(t *testing.T) {
+	for s, expected := range map[String]string{
+		"":                     "",
+		"Linux router 5.10":    "Linux router 5.10",
+		"Zürich – 2F\tA\r\n":   "Zürich – 2F\tA\r\n",
+		`back\slash`:           `back\slash`,
+		"\x00\x1a\x2b\xfe\xff": "Hex-STRING: 00 1A 2B FE FF",
+		"a\x00b":               "Hex-STRING: 61 00 62",
+		"\xc3\x28":             "Hex-STRING: C3 28",
+	} {
+		if str := s.String(); str != expected {
+			t.Errorf("expected %q, got %q", expected, str)
+		}
+	}
+
+	if hex := String("public").Hex(); hex != "70 75 62 6C 69 63" {
+		t.Errorf("expected the hex of public, got %q", hex)
+	}
+
+	if hex := String("").Hex(); hex != "" {
+		t.Errorf("expected no hex for an empty String, got %q", hex)
+	}
+}
+
+func TestNull(t *testing.T) {
+	b, err := Null.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b, []byte{0x05, 0x00}) {
+		t.Errorf("expected NULL to encode as [5 0], got %v", b)
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != Null {
+		t.Errorf("expected Null, got %v", decoded)
+	}
+
+	if _, _, err := decode(bytes.NewReader([]byte{0x05, 0x01, 0x00})); err == nil {
+		t.Error("expected an error decoding a NULL with non-zero length")
+	}
+}
+
+func TestTimeTicks(t *testing.T) {
+	uptime := TimeTicks(8640000)
+
+	if uptime.Duration() != 24*time.Hour {
+		t.Errorf("expected %v, got %v", 24*time.Hour, uptime.Duration())
+	}
+
+	if ticks := TimeTicksFromDuration(24 * time.Hour); ticks != uptime {
+		t.Errorf("expected %d ticks, got %d", uptime, ticks)
+	}
+
+	for _, d := range []time.Duration{0, 9 * time.Millisecond, 1234567 * time.Microsecond, 90 * time.Minute} {
+		diff := d - TimeTicksFromDuration(d).Duration()
+		if diff < 0 || diff >= 10*time.Millisecond {
+			t.Errorf("expected %v to round-trip within one tick, got a difference of %v", d, diff)
+		}
+	}
+
+	b, err := uptime.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []byte{0x43, 0x04, 0x00, 0x83, 0xd6, 0x00}; !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != uptime {
+		t.Errorf("expected %v (%T), got %v (%T)", uptime, uptime, decoded, decoded)
+	}
+}
+
+func TestTimeTicksFormat(t *testing.T) {
+	for ticks, expected := range map[TimeTicks]string{
+		0:                "0:00:00.00",
+		8640000:          "1 day, 0:00:00.00",
+		8640000*2 - 1:    "1 day, 23:59:59.99",
+		105144567:        "12 days, 4:04:05.67",
+		math.MaxUint32:   "497 days, 2:27:52.95",
+		6000*61 + 100*59: "1:01:59.00",
+	} {
+		if s := ticks.Format(); s != expected {
+			t.Errorf("expected %d ticks to format as %q, got %q", ticks, expected, s)
+		}
+	}
+}
+func TestOpaque(t *testing.T) {
+	for _, test := range []struct {
+		encoded []byte
+		float   float64
+		ok      bool
+	}{
+		// A plain Opaque wrapping an INTEGER
+		{[]byte{0x44, 0x03, 0x02, 0x01, 0x2a}, 0, false},
+		// Net-SNMP float 1.5
+		{[]byte{0x44, 0x07, 0x9f, 0x78, 0x04, 0x3f, 0xc0, 0x00, 0x00}, 1.5, true},
+		// Net-SNMP double -0.25
+		{[]byte{0x44, 0x0b, 0x9f, 0x79, 0x08, 0xbf, 0xd0, 0, 0, 0, 0, 0, 0}, -0.25, true},
+		// A float with the wrong length
+		{[]byte{0x44, 0x05, 0x9f, 0x78, 0x02, 0x3f, 0xc0}, 0, false},
+	} {
+		decoded, n, err := decode(bytes.NewReader(test.encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		o, ok := decoded.(Opaque)
+		if !ok || n != len(test.encoded) {
+			t.Fatalf("%x: expected an Opaque of %d bytes, got %T of %d", test.encoded, len(test.encoded), decoded, n)
+		}
+
+		if !bytes.Equal(o, test.encoded[2:]) {
+			t.Errorf("%x: expected contents %x, got %x", test.encoded, test.encoded[2:], []byte(o))
+		}
+
+		if f, ok := o.AsFloat(); ok != test.ok || f != test.float {
+			t.Errorf("%x: expected %v (%v), got %v (%v)", test.encoded, test.float, test.ok, f, ok)
+		}
+
+		b, err := o.Encode()
+		if err != nil || !bytes.Equal(b, test.encoded) {
+			t.Errorf("%x: re-encoded as %x (%v)", test.encoded, b, err)
+		}
+	}
+}
+
+func TestOpaqueFloat(t *testing.T) {
+	for _, test := range []struct {
+		o       Opaque
+		encoded []byte
+		float   float64
+	}{
+		{OpaqueFloat(1.5), []byte{0x44, 0x07, 0x9f, 0x78, 0x04, 0x3f, 0xc0, 0x00, 0x00}, 1.5},
+		{OpaqueDouble(-0.25), []byte{0x44, 0x0b, 0x9f, 0x79, 0x08, 0xbf, 0xd0, 0, 0, 0, 0, 0, 0}, -0.25},
+	} {
+		// The value must survive a SetRequest
+		packet, err := Message{
+			Version:   Version2c,
+			Community: "private",
+			PDU:       NewSetRequest(1, NewVarbind(sysName, test.o)),
+		}.Encode()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.HasSuffix(packet, test.encoded) {
+			t.Errorf("expected %x to end with %x", packet, test.encoded)
+		}
+
+		m, err := Unmarshal(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		varbinds := m.PDU.(SetRequest).Varbinds()
+		if len(varbinds) != 1 {
+			t.Fatalf("expected 1 binding, got %v", varbinds)
+		}
+
+		o, ok := varbinds[0].Value().(Opaque)
+		if !ok {
+			t.Fatalf("expected an Opaque, got %T", varbinds[0].Value())
+		}
+
+		if f, ok := o.AsFloat(); !ok || f != test.float {
+			t.Errorf("expected %v, got %v (%v)", test.float, f, ok)
+		}
+	}
+}
+
+func TestInetAddress(t *testing.T) {
+	for _, str := range []string{"192.0.2.1", "2001:db8::1", "::1"} {
+		a, err := NewInetAddress(net.ParseIP(str))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a.String() != str {
+			t.Errorf("expected %s, got %s", str, a.String())
+		}
+
+		b, err := a.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, ok := decoded.(String)
+		if !ok {
+			t.Fatalf("expected a String, got %T", decoded)
+		}
+
+		if ip, ok := InetAddress(s).IP(); !ok || !ip.Equal(net.ParseIP(str)) {
+			t.Errorf("expected decoded %s, got %v", str, ip)
+		}
+	}
+
+	// An ipv6z address with zone index 2
+	zoned := InetAddress(append(net.ParseIP("fe80::1"), 0, 0, 0, 2))
+	if zoned.String() != "fe80::1" {
+		t.Errorf("expected fe80::1, got %s", zoned.String())
+	}
+
+	if _, ok := (InetAddress{1, 2, 3}).IP(); ok {
+		t.Error("expected a 3-byte InetAddress to be invalid")
+	}
+}
+
+func TestIpAddress(t *testing.T) {
+	for _, str := range []string{"0.0.0.0", "255.255.255.255", "192.0.2.1"} {
+		ip, err := NewIpAddress(net.ParseIP(str))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ip.String() != str {
+			t.Errorf("expected %s, got %s", str, ip.String())
+		}
+
+		b, err := ip.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(b) != 6 || b[0] != TypeIpAddress || b[1] != 4 {
+			t.Errorf("unexpected encoding %v for %s", b, str)
+		}
+
+		decoded, _, err := decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.(IpAddress).String() != str {
+			t.Errorf("expected decoded %s, got %v", str, decoded)
+		}
+	}
+
+	if _, err := NewIpAddress(net.ParseIP("2001:db8::1")); err == nil {
+		t.Error("expected an error for an IPv6 address")
+	}
+
+	if _, _, err := decode(bytes.NewReader([]byte{0x40, 0x03, 10, 0, 0})); err == nil {
+		t.Error("expected an error decoding a 3-byte IpAddress")
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	for _, c := range []struct {
+		a, b  DataType
+		equal bool
+	}{
+		{String("eth0"), String("eth0"), true},
+		{Int(-5), Int(-5), true},
+		{Counter64(1 << 40), Counter64(1 << 40), true},
+		{IpAddress{10, 0, 0, 1}, IpAddress{10, 0, 0, 1}, true},
+		{Opaque{0x9f, 0x78}, Opaque{0x9f, 0x78}, true},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6}, true},
+		{NoSuchInstance, NoSuchInstance, true},
+		{String("eth0"), String("eth1"), false},
+		{Gauge(5), Gauge(6), false},
+		{IpAddress{10, 0, 0, 1}, IpAddress{10, 0, 0, 2}, false},
+		{ObjectIdentifier{1, 3, 6}, ObjectIdentifier{1, 3, 6, 1}, false},
+		{Int(5), Gauge(5), false},
+		{Counter(5), Counter64(5), false},
+		{IpAddress{1, 2, 3, 4}, Opaque{1, 2, 3, 4}, false},
+		{String("x"), nil, false},
+		{NoSuchInstance, NoSuchObject, false},
+		{UnknownValue{0x4f, []byte{1, 2}}, UnknownValue{0x4f, []byte{1, 2}}, true},
+		{UnknownValue{0x4f, []byte{1, 2}}, UnknownValue{0x4e, []byte{1, 2}}, false},
+		{UnknownValue{0x4f, []byte{1, 2}}, UnknownValue{0x4f, []byte{1, 3}}, false},
+		{UnknownValue{TypeOpaque, []byte{1, 2}}, Opaque{1, 2}, false},
+	} {
+		if equal := ValuesEqual(c.a, c.b); equal != c.equal {
+			t.Errorf("expected ValuesEqual(%#v, %#v) to be %t", c.a, c.b, c.equal)
+		}
+
+		if equal := ValuesEqual(c.b, c.a); equal != c.equal {
+			t.Errorf("expected ValuesEqual(%#v, %#v) to be %t", c.b, c.a, c.equal)
+		}
+	}
+}
+
+func TestUnknownValue(t *testing.T) {
+	// GetResponse binding sysDescr.0 to the application tag 0x4f,
+	// which no SNMP type uses
+	b := []byte{
+		0xa2, 0x1a,
+		0x02, 0x01, 0x01,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x0f,
+		0x30, 0x0d,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+		0x4f, 0x01, 0x2a,
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := decoded.(GetResponse).Varbinds()[0]
+
+	value, ok := v.Value().(UnknownValue)
+	if !ok || value.Tag != 0x4f || !bytes.Equal(value.Bytes, []byte{0x2a}) {
+		t.Fatalf("expected the raw value, got %#v", v.Value())
+	}
+
+	if encoded, err := decoded.Encode(); err != nil || !bytes.Equal(encoded, b) {
+		t.Errorf("expected the GetResponse to encode as decoded, got % x and %v", encoded, err)
+	}
+
+	if s := DumpVarbind(v, nil); s != ".1.3.6.1.2.1.1.1.0 = Unknown(0x4F): 2A" {
+		t.Errorf("unexpected dump %q", s)
+	}
+
+	// Unknown constructed tags aren't values
+	if _, _, err := decode(bytes.NewReader([]byte{0xaf, 0x00})); err != ErrUnknownType {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package snmp
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// AuthProtocol is an SNMPv3 authentication protocol.
+type AuthProtocol int
+
+const (
+	NoAuth AuthProtocol = iota
+	MD5
+	SHA
+)
+
+// authParamsLength is the length of HMAC-MD5-96 and
+// HMAC-SHA-96 authentication parameters.
+const authParamsLength = 12
+
+var (
+	ErrAuthenticationFailed = errors.New("snmp: message authentication failed")
+
+	// Errors reported by agents through the usmStats counters.
+	ErrUnsupportedSecurityLevel = errors.New("snmp: unsupported security level")
+	ErrNotInTimeWindow          = errors.New("snmp: not in time window")
+	ErrUnknownUserName          = errors.New("snmp: unknown user name")
+	ErrUnknownEngineID          = errors.New("snmp: unknown engine ID")
+	ErrWrongDigest              = errors.New("snmp: wrong digest")
+	ErrDecryption               = errors.New("snmp: decryption error")
+
+	// Errors reported by agents through the counters of the message
+	// processing and target MIBs of RFC 3412 and RFC 3413.
+	ErrUnknownSecurityModel = errors.New("snmp: unknown security model")
+	ErrInvalidMessage       = errors.New("snmp: invalid message")
+	ErrUnknownPDUHandler    = errors.New("snmp: unknown PDU handler")
+	ErrUnavailableContext   = errors.New("snmp: unavailable context")
+	ErrUnknownContext       = errors.New("snmp: unknown context")
+)
+
+// usmStats is the OID of the usmStats counters of RFC 3414.
+var usmStats = ObjectIdentifier{1, 3, 6, 1, 6, 3, 15, 1, 1}
+
+// usmStatsErrors maps usmStats counters, by their
+// sub-identifier in usmStats, to the errors they report.
+var usmStatsErrors = map[uint32]error{
+	1: ErrUnsupportedSecurityLevel,
+	2: ErrNotInTimeWindow,
+	3: ErrUnknownUserName,
+	4: ErrUnknownEngineID,
+	5: ErrWrongDigest,
+	6: ErrDecryption,
+}
+
+// reportCounters maps the other counters Reports carry to the
+// errors they report.
+var reportCounters = []struct {
+	oid ObjectIdentifier
+	err error
+}{
+	{ObjectIdentifier{1, 3, 6, 1, 6, 3, 11, 2, 1, 1}, ErrUnknownSecurityModel},
+	{ObjectIdentifier{1, 3, 6, 1, 6, 3, 11, 2, 1, 2}, ErrInvalidMessage},
+	{ObjectIdentifier{1, 3, 6, 1, 6, 3, 11, 2, 1, 3}, ErrUnknownPDUHandler},
+	{ObjectIdentifier{1, 3, 6, 1, 6, 3, 12, 1, 4}, ErrUnavailableContext},
+	{ObjectIdentifier{1, 3, 6, 1, 6, 3, 12, 1, 5}, ErrUnknownContext},
+}
+
+// hash returns the hash function of an AuthProtocol.
+func (a AuthProtocol) hash() func() hash.Hash {
+	switch a {
+	case MD5:
+		return md5.New
+	case SHA:
+		return sha1.New
+	}
+
+	return nil
+}
+
+// String returns the name of an AuthProtocol.
+func (a AuthProtocol) String() string {
+	switch a {
+	case NoAuth:
+		return "none"
+	case MD5:
+		return "MD5"
+	case SHA:
+		return "SHA"
+	}
+
+	return "unknown"
+}
+
+// LocalizeKey returns the key for passphrase localized to engineID
+// using the RFC 3414 password-to-key algorithm for protocol.
+func LocalizeKey(protocol AuthProtocol, passphrase string, engineID []byte) ([]byte, error) {
+	newHash := protocol.hash()
+	if newHash == nil {
+		return nil, errors.New("snmp: unsupported authentication protocol")
+	}
+
+	if len(passphrase) == 0 {
+		return nil, errors.New("snmp: empty passphrase")
+	}
+
+	return passphraseToKey(newHash, []byte(passphrase), engineID), nil
+}
+
+// digest returns the HMAC-96 digest of packet using key.
+func (a AuthProtocol) digest(key, packet []byte) []byte {
+	mac := hmac.New(a.hash(), key)
+	mac.Write(packet)
+	return mac.Sum(nil)[:authParamsLength]
+}
+
+// authenticate computes the digest of packet and writes it into the
+// authentication parameters at offset, which must hold zeros.
+func (a AuthProtocol) authenticate(key, packet []byte, offset int) {
+	copy(packet[offset:offset+authParamsLength], a.digest(key, packet))
+}
+
+// verify checks the digest at offset of packet using key.
+func (a AuthProtocol) verify(key, packet []byte, offset int) error {
+	if offset < 0 || offset+authParamsLength > len(packet) {
+		return ErrAuthenticationFailed
+	}
+
+	received := append([]byte(nil), packet[offset:offset+authParamsLength]...)
+
+	zeroed := append([]byte(nil), packet...)
+	copy(zeroed[offset:offset+authParamsLength], make([]byte, authParamsLength))
+
+	if !hmac.Equal(received, a.digest(key, zeroed)) {
+		return ErrAuthenticationFailed
+	}
+
+	return nil
+}
+
+// encodeAuthenticated encodes m and, if m has FlagAuth set,
+// authenticates it with key.
+func (m Message) encodeAuthenticated(protocol AuthProtocol, key []byte) ([]byte, error) {
+	packet, err := m.Encode()
+	if err != nil || m.Flags&FlagAuth == 0 {
+		return packet, err
+	}
+
+	offset, err := authParamsOffset(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	protocol.authenticate(key, packet, offset)
+
+	return packet, nil
+}
+
+// verifyPacket checks the digest of an encoded SNMPv3 message using key.
+func (a AuthProtocol) verifyPacket(key, packet []byte) error {
+	offset, err := authParamsOffset(packet)
+	if err != nil {
+		return err
+	}
+
+	return a.verify(key, packet, offset)
+}
+
+// reportError returns the error reported by an SNMPv3 Report.
+func reportError(r Report) error {
+	pdu, err := r.pdu()
+	if err != nil {
+		return err
+	}
+
+	if len(pdu.varbinds) == 0 {
+		return errors.New("snmp: agent returned an empty Report")
+	}
+
+	oid := pdu.varbinds[0].OID
+	if len(oid) == len(usmStats)+2 && oid.HasPrefix(usmStats) {
+		if err, ok := usmStatsErrors[oid[len(usmStats)]]; ok {
+			return err
+		}
+	}
+
+	for _, counter := range reportCounters {
+		if len(oid) == len(counter.oid)+1 && oid.HasPrefix(counter.oid) {
+			return counter.err
+		}
+	}
+
+	return fmt.Errorf("snmp: agent reported %v", oid)
+}
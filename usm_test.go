@@ -0,0 +1,131 @@
+package snmp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestLocalizeKey(t *testing.T) {
+	// RFC 3414 A.3
+	engineID := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}
+
+	cases := []struct {
+		protocol AuthProtocol
+		expected string
+	}{
+		{MD5, "526f5eed9fcce26f8964c2930787d82b"},
+		{SHA, "6695febc9288e36282235fc7151f128497b38f3f"},
+	}
+
+	for _, c := range cases {
+		key, err := LocalizeKey(c.protocol, "maplesyrup", engineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := hex.EncodeToString(key); got != c.expected {
+			t.Errorf("%v: expected %s, got %s", c.protocol, c.expected, got)
+		}
+	}
+
+	if _, err := LocalizeKey(NoAuth, "maplesyrup", engineID); err == nil {
+		t.Error("expected an error for NoAuth")
+	}
+}
+
+func TestMessageV3Authentication(t *testing.T) {
+	engineID := []byte{0x80, 0, 0x1f, 0x88, 0x80, 1, 2, 3, 4}
+
+	m := Message{
+		Version:   Version3,
+		MessageID: 42,
+		Flags:     FlagAuth | FlagReportable,
+		Security: USMSecurityParameters{
+			AuthoritativeEngineID:    engineID,
+			AuthoritativeEngineBoots: 3,
+			AuthoritativeEngineTime:  1200,
+			UserName:                 "operator",
+		},
+		ContextEngineID: engineID,
+		PDU:             NewGetRequest(7, MustParseOID(".1.3.6.1.2.1.1.1.0")),
+	}
+
+	for _, protocol := range []AuthProtocol{MD5, SHA} {
+		key, err := LocalizeKey(protocol, "authpassphrase", engineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := m.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		offset, err := authParamsOffset(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(b[offset:offset+authParamsLength], make([]byte, authParamsLength)) {
+			t.Fatalf("%v: expected zeroed authentication parameters", protocol)
+		}
+
+		protocol.authenticate(key, b, offset)
+
+		if err := protocol.verify(key, b, offset); err != nil {
+			t.Errorf("%v: %v", protocol, err)
+		}
+
+		decoded, _, err := decodeMessage(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.MessageID != 42 || decoded.Flags != FlagAuth|FlagReportable ||
+			decoded.Security.UserName != "operator" ||
+			decoded.Security.AuthoritativeEngineBoots != 3 ||
+			decoded.Security.AuthoritativeEngineTime != 1200 ||
+			!bytes.Equal(decoded.ContextEngineID, engineID) {
+			t.Errorf("%v: unexpected decoded message %+v", protocol, decoded)
+		}
+
+		if _, ok := decoded.PDU.(GetRequest); !ok {
+			t.Errorf("%v: expected a GetRequest, got %T", protocol, decoded.PDU)
+		}
+
+		b[len(b)-1] ^= 0xff
+		if err := protocol.verify(key, b, offset); err != ErrAuthenticationFailed {
+			t.Errorf("%v: expected ErrAuthenticationFailed for a modified message, got %v", protocol, err)
+		}
+	}
+}
+
+func TestMessageV3Encrypted(t *testing.T) {
+	m := Message{
+		Version:      Version3,
+		MessageID:    1,
+		Flags:        FlagAuth | FlagPriv,
+		Security:     USMSecurityParameters{UserName: "operator"},
+		EncryptedPDU: []byte{1, 2, 3, 4},
+	}
+
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, _, err := decodeMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.PDU != nil || !bytes.Equal(decoded.EncryptedPDU, m.EncryptedPDU) {
+		t.Errorf("unexpected decoded message %+v", decoded)
+	}
+
+	m.Flags = FlagPriv
+	if _, err := m.Encode(); err == nil {
+		t.Error("expected an error for privacy without authentication")
+	}
+}
@@ -2,6 +2,10 @@ package snmp
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
 )
 
 var (
@@ -22,6 +26,70 @@ func NewVarbind(OID ObjectIdentifier, value DataType) Varbind {
 	}
 }
 
+// nullVarbinds returns a Varbind with a NULL value for each OID.
+func nullVarbinds(oids []ObjectIdentifier) []Varbind {
+	varbinds := make([]Varbind, 0, len(oids))
+	for _, oid := range oids {
+		varbinds = append(varbinds, NewVarbind(oid, Null))
+	}
+
+	return varbinds
+}
+
+// Value returns the value of a variable binding.
+func (v Varbind) Value() DataType {
+	return v.value
+}
+
+// Encode encodes a Varbind as a SEQUENCE of its OID and value.
+// A Varbind without a value is encoded with a NULL value.
+func (v Varbind) Encode() ([]byte, error) {
+	value := v.value
+	if value == nil {
+		value = Null
+	}
+
+	return Sequence{v.OID, value}.Encode()
+}
+
+// decodeVarbind decodes a Varbind from r.
+// It returns the Varbind, the number of bytes read, and an error.
+func decodeVarbind(r io.Reader) (Varbind, int, error) {
+	item, bytesRead, err := decode(r)
+	if err != nil {
+		return Varbind{}, bytesRead, err
+	}
+
+	v, err := varbindFromSequence(item)
+	return v, bytesRead, err
+}
+
+// varbindFromSequence converts a decoded SEQUENCE into a Varbind.
+func varbindFromSequence(item DataType) (Varbind, error) {
+	pair, ok := item.(Sequence)
+	if !ok || len(pair) != 2 {
+		return Varbind{}, ErrDecodingType
+	}
+
+	oid, ok := pair[0].(ObjectIdentifier)
+	if !ok {
+		return Varbind{}, ErrDecodingType
+	}
+
+	return NewVarbind(oid, pair[1]), nil
+}
+
+// IsException returns true if the value of a variable binding is
+// one of the NoSuchObject, NoSuchInstance, or EndOfMIBView exceptions.
+func (v Varbind) IsException() bool {
+	switch v.value {
+	case NoSuchObject, NoSuchInstance, EndOfMIBView:
+		return true
+	}
+
+	return false
+}
+
 // GetStringValue returns the string value of a variable binding.
 // An error is returned if the value is not a string.
 func (v Varbind) GetStringValue() (string, error) {
@@ -45,3 +113,145 @@ func (v Varbind) GetIntegerValue() (int, error) {
 
 	return int(i), nil
 }
+
+// Int returns the value of a variable binding if it is an INTEGER.
+func (v Varbind) Int() (int64, bool) {
+	i, ok := v.value.(Int)
+	return int64(i), ok
+}
+
+// Uint returns the value of a variable binding if it is a
+// Counter, Gauge, TimeTicks, or Counter64.
+func (v Varbind) Uint() (uint64, bool) {
+	switch u := v.value.(type) {
+	case Counter:
+		return uint64(u), true
+	case Gauge:
+		return uint64(u), true
+	case TimeTicks:
+		return uint64(u), true
+	case Counter64:
+		return uint64(u), true
+	}
+
+	return 0, false
+}
+
+// Bytes returns the value of a variable binding
+// if it is an OCTET STRING or an Opaque.
+func (v Varbind) Bytes() ([]byte, bool) {
+	switch b := v.value.(type) {
+	case String:
+		return []byte(b), true
+	case Opaque:
+		return []byte(b), true
+	}
+
+	return nil, false
+}
+
+// String returns the value of a variable binding if it is an OCTET STRING.
+func (v Varbind) String() (string, bool) {
+	str, ok := v.value.(String)
+	return string(str), ok
+}
+
+// ObjectIdentifier returns the value of a variable binding if it is an
+// OBJECT IDENTIFIER, such as a sysORID.
+func (v Varbind) ObjectIdentifier() (ObjectIdentifier, bool) {
+	oid, ok := v.value.(ObjectIdentifier)
+	return oid, ok
+}
+
+// Bool returns the value of a variable binding if it is a TruthValue,
+// an INTEGER where 1 is true and 2 is false, such as ifPromiscuousMode.
+func (v Varbind) Bool() (bool, bool) {
+	i, ok := v.value.(Int)
+	if !ok || (i != 1 && i != 2) {
+		return false, false
+	}
+
+	return i == 1, true
+}
+
+// VarbindError is an error decoding one variable binding of a PDU.
+type VarbindError struct {
+	// Index is the 1-based index of the variable binding.
+	Index int
+	Err   error
+}
+
+// Error implements the error interface.
+func (e VarbindError) Error() string {
+	return fmt.Sprintf("snmp: variable binding %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e VarbindError) Unwrap() error {
+	return e.Err
+}
+
+// VarbindErrors lists the variable bindings of a PDU that failed
+// to decode with DecodeOptions.ContinueOnVarbindError.
+type VarbindErrors []VarbindError
+
+// Error implements the error interface.
+func (e VarbindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the error of each variable binding.
+func (e VarbindErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// SearchBindings returns the index of the binding for target in sorted,
+// and whether it's there. Otherwise the index is where a binding for
+// target would be inserted. The bindings must be sorted by OID, as
+// WalkAll returns them; the result is undefined if they aren't.
+func SearchBindings(sorted []Varbind, target ObjectIdentifier) (int, bool) {
+	i := sort.Search(len(sorted), func(i int) bool {
+		return sorted[i].OID.Compare(target) >= 0
+	})
+
+	return i, i < len(sorted) && sorted[i].OID.Equal(target)
+}
+
+// MergeBindings returns the bindings of sets in one slice sorted by
+// OID, such as the results of walks of overlapping subtrees. Each set
+// may be sorted or not. If an OID is bound more than once, the binding
+// in the last set wins, or the last within a set. The sets aren't
+// modified.
+func MergeBindings(sets ...[]Varbind) []Varbind {
+	var merged []Varbind
+	for _, set := range sets {
+		merged = append(merged, set...)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].OID.Compare(merged[j].OID) < 0
+	})
+
+	// Keep the last of each run of equal OIDs
+	n := 0
+	for i, v := range merged {
+		if i+1 < len(merged) && merged[i+1].OID.Equal(v.OID) {
+			continue
+		}
+
+		merged[n] = v
+		n++
+	}
+
+	return merged[:n]
+}
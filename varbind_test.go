@@ -0,0 +1,281 @@
+package snmp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVarbindEncoding(t *testing.T) {
+	v := NewVarbind(MustParseOID(".1.3.6.1.2.1.1.1.0"), Null)
+
+	b, err := v.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []byte{
+		0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+		0x05, 0x00,
+	}; !bytes.Equal(expected, b) {
+		t.Errorf("expected %v, got %v", expected, b)
+	}
+
+	decoded, n, err := decodeVarbind(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(b) {
+		t.Errorf("expected %d bytes read, got %d", len(b), n)
+	}
+
+	if !decoded.OID.Equal(v.OID) || decoded.Value() != Null {
+		t.Errorf("expected %v = %v, got %v = %v", v.OID, Null, decoded.OID, decoded.Value())
+	}
+
+	// A Varbind whose first element isn't an OID is invalid
+	b, _ = Sequence{Int(1), Null}.Encode()
+	if _, _, err := decodeVarbind(bytes.NewReader(b)); err != ErrDecodingType {
+		t.Errorf("expected %v, got %v", ErrDecodingType, err)
+	}
+}
+
+func TestVarbindExceptions(t *testing.T) {
+	for _, exception := range []tag{NoSuchObject, NoSuchInstance, EndOfMIBView} {
+		b, err := NewVarbind(MustParseOID(".1.3.6.1.2.1.1.1.0"), exception).Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, _, err := decodeVarbind(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("decoding %v: %v", exception, err)
+		}
+
+		if decoded.Value() != exception || !decoded.IsException() {
+			t.Errorf("expected exception %v, got %v", exception, decoded.Value())
+		}
+	}
+
+	if NewVarbind(MustParseOID(".1.3.6.1.2.1.1.1.0"), Null).IsException() {
+		t.Error("expected NULL not to be an exception")
+	}
+
+	if _, _, err := decode(bytes.NewReader([]byte{0x82, 0x01, 0x00})); err == nil {
+		t.Error("expected an error decoding endOfMibView with non-zero length")
+	}
+}
+
+func TestVarbindWalkTermination(t *testing.T) {
+	root := MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+	res := GetResponse{PDU: newPDU(1, 0, 0, []Varbind{
+		NewVarbind(root.Child(1), String("lo")),
+		NewVarbind(root.Child(2), String("eth0")),
+		NewVarbind(root.Child(2), EndOfMIBView),
+		NewVarbind(root.Child(3), String("unreachable")),
+	})}
+
+	b, err := res.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, _, err := decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []string
+	for _, v := range decoded.(GetResponse).Varbinds() {
+		if !v.OID.HasPrefix(root) || v.Value() == EndOfMIBView {
+			break
+		}
+
+		str, _ := v.GetStringValue()
+		seen = append(seen, str)
+	}
+
+	if len(seen) != 2 || seen[0] != "lo" || seen[1] != "eth0" {
+		t.Errorf("expected walk to stop at endOfMibView after [lo eth0], got %v", seen)
+	}
+}
+
+func TestVarbindAccessors(t *testing.T) {
+	oid := MustParseOID(".1.3.6.1.2.1.1.1.0")
+
+	for _, test := range []struct {
+		value DataType
+		i     int64
+		u     uint64
+		b     []byte
+		s     string
+		o     ObjectIdentifier
+		kinds string
+	}{
+		{Int(-3), -3, 0, nil, "", nil, "i"},
+		{Counter(7), 0, 7, nil, "", nil, "u"},
+		{Gauge(8), 0, 8, nil, "", nil, "u"},
+		{TimeTicks(9), 0, 9, nil, "", nil, "u"},
+		{Counter64(1 << 40), 0, 1 << 40, nil, "", nil, "u"},
+		{String("eth0"), 0, 0, []byte("eth0"), "eth0", nil, "bs"},
+		{Opaque{0x9f, 0x78}, 0, 0, []byte{0x9f, 0x78}, "", nil, "b"},
+		{IpAddress{192, 0, 2, 1}, 0, 0, nil, "", nil, ""},
+		{ObjectIdentifier{1, 3, 6}, 0, 0, nil, "", ObjectIdentifier{1, 3, 6}, "o"},
+		{Null, 0, 0, nil, "", nil, ""},
+		{NoSuchInstance, 0, 0, nil, "", nil, ""},
+	} {
+		v := NewVarbind(oid, test.value)
+
+		if i, ok := v.Int(); ok != strings.Contains(test.kinds, "i") || i != test.i {
+			t.Errorf("%v: Int returned %d, %v", test.value, i, ok)
+		}
+
+		if u, ok := v.Uint(); ok != strings.Contains(test.kinds, "u") || u != test.u {
+			t.Errorf("%v: Uint returned %d, %v", test.value, u, ok)
+		}
+
+		if b, ok := v.Bytes(); ok != strings.Contains(test.kinds, "b") || !bytes.Equal(b, test.b) {
+			t.Errorf("%v: Bytes returned %v, %v", test.value, b, ok)
+		}
+
+		if s, ok := v.String(); ok != strings.Contains(test.kinds, "s") || s != test.s {
+			t.Errorf("%v: String returned %q, %v", test.value, s, ok)
+		}
+
+		if o, ok := v.ObjectIdentifier(); ok != strings.Contains(test.kinds, "o") || !o.Equal(test.o) {
+			t.Errorf("%v: ObjectIdentifier returned %v, %v", test.value, o, ok)
+		}
+	}
+}
+
+func TestVarbindBool(t *testing.T) {
+	for _, test := range []struct {
+		value    DataType
+		expected bool
+		ok       bool
+	}{
+		{Int(1), true, true},
+		{Int(2), false, true},
+		{Int(0), false, false},
+		{Int(3), false, false},
+		{Gauge(1), false, false},
+		{String("true"), false, false},
+		{NoSuchObject, false, false},
+	} {
+		if b, ok := NewVarbind(sysDescr, test.value).Bool(); b != test.expected || ok != test.ok {
+			t.Errorf("%v: expected %v, %v, got %v, %v", test.value, test.expected, test.ok, b, ok)
+		}
+	}
+}
+
+func TestVarbindOIDValue(t *testing.T) {
+	// sysORID.1 = .1.3.6.1.6.3.1, the OID of SNMPv2-MIB
+	b := []byte{
+		0x30, 0x13,
+		0x06, 0x09, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x09, 0x01, 0x02,
+		0x06, 0x06, 0x2b, 0x06, 0x01, 0x06, 0x03, 0x01,
+	}
+
+	v, n, err := decodeVarbind(bytes.NewReader(b))
+	if err != nil || n != len(b) {
+		t.Fatalf("decoded %d of %d bytes: %v", n, len(b), err)
+	}
+
+	oid, ok := v.ObjectIdentifier()
+	if !ok || !oid.Equal(MustParseOID(".1.3.6.1.6.3.1")) {
+		t.Fatalf("expected the value .1.3.6.1.6.3.1, got %#v", v.Value())
+	}
+
+	if !ValuesEqual(v.Value(), MustParseOID(".1.3.6.1.6.3.1")) {
+		t.Error("expected the value to equal the OID")
+	}
+}
+
+func TestSearchBindings(t *testing.T) {
+	sorted := []Varbind{
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+		NewVarbind(ifDescr.Child(10), String("eth1")),
+		NewVarbind(ifDescr.Append(10, 1), String("eth1.1")),
+	}
+
+	for _, test := range []struct {
+		target ObjectIdentifier
+		index  int
+		found  bool
+	}{
+		{ifDescr.Child(1), 0, true},
+		{ifDescr.Child(2), 1, true},
+		{ifDescr.Append(10, 1), 3, true},
+		{ifDescr, 0, false},
+		{ifDescr.Child(0), 0, false},
+		{ifDescr.Child(3), 2, false},
+		{ifDescr.Append(10, 0), 3, false},
+		{ifDescr.Child(11), 4, false},
+	} {
+		if i, found := SearchBindings(sorted, test.target); i != test.index || found != test.found {
+			t.Errorf("%v: expected %d, %v, got %d, %v", test.target, test.index, test.found, i, found)
+		}
+	}
+
+	if i, found := SearchBindings(nil, ifDescr); i != 0 || found {
+		t.Errorf("expected 0, false for no bindings, got %d, %v", i, found)
+	}
+}
+
+func TestMergeBindings(t *testing.T) {
+	ifDescr := MustParseOID(".1.3.6.1.2.1.2.2.1.2")
+
+	system := []Varbind{
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(sysUpTime, TimeTicks(100)),
+	}
+
+	interfaces := []Varbind{
+		NewVarbind(ifDescr.Child(10), String("eth1")),
+		NewVarbind(ifDescr.Child(2), String("eth0")),
+	}
+
+	// Overlaps both, and rebinds sysUpTime and ifDescr.2
+	overlap := []Varbind{
+		NewVarbind(sysUpTime, TimeTicks(200)),
+		NewVarbind(ifDescr.Child(2), String("eth0.new")),
+		NewVarbind(ifDescr.Child(1), String("lo")),
+	}
+
+	merged := MergeBindings(system, interfaces, overlap)
+
+	expected := []Varbind{
+		NewVarbind(sysDescr, String("test agent")),
+		NewVarbind(sysUpTime, TimeTicks(200)),
+		NewVarbind(ifDescr.Child(1), String("lo")),
+		NewVarbind(ifDescr.Child(2), String("eth0.new")),
+		NewVarbind(ifDescr.Child(10), String("eth1")),
+	}
+
+	if len(merged) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, merged)
+	}
+
+	for i, v := range merged {
+		if !v.OID.Equal(expected[i].OID) || v.Value() != expected[i].Value() {
+			t.Errorf("%d: expected %v, got %v", i, expected[i], v)
+		}
+	}
+
+	if interfaces[0].Value() != String("eth1") || system[1].Value() != TimeTicks(100) {
+		t.Error("expected the sets to be unchanged")
+	}
+
+	// Disjoint sets are only sorted
+	if merged := MergeBindings(interfaces, system); len(merged) != 4 || !merged[0].OID.Equal(sysDescr) || !merged[3].OID.Equal(ifDescr.Child(10)) {
+		t.Errorf("unexpected merge of disjoint sets %v", merged)
+	}
+
+	if merged := MergeBindings(); len(merged) != 0 {
+		t.Errorf("expected no bindings, got %v", merged)
+	}
+}
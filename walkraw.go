@@ -0,0 +1,223 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WalkRaw is like Walk but calls fn with the encoded bindings rather
+// than decoded Varbinds: oid is the content of the OID, its BER-encoded
+// sub-identifiers without a tag and length, which ParseOIDBytes decodes,
+// and value is the whole TLV of the value. Responses aren't decoded
+// beyond what the walk needs, which saves the allocations of Walk. Both
+// slices point into a buffer that is only valid until fn returns, so fn
+// must copy any bytes it keeps. WalkRaw doesn't support SNMPv3.
+func (c *Client) WalkRaw(root ObjectIdentifier, fn func(oid, value []byte) error) error {
+	return c.WalkRawContext(context.Background(), root, fn)
+}
+
+// WalkRawContext is like WalkRaw but aborts when ctx is done.
+func (c *Client) WalkRawContext(ctx context.Context, root ObjectIdentifier, fn func(oid, value []byte) error) error {
+	conn, err := c.Dial()
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	return conn.WalkRawContext(ctx, root, fn)
+}
+
+// WalkRaw is like Client.WalkRaw.
+func (conn *Conn) WalkRaw(root ObjectIdentifier, fn func(oid, value []byte) error) error {
+	return conn.WalkRawContext(context.Background(), root, fn)
+}
+
+// WalkRawContext is like WalkRaw but aborts when ctx is done.
+func (conn *Conn) WalkRawContext(ctx context.Context, root ObjectIdentifier, fn func(oid, value []byte) error) error {
+	return conn.client.walkRaw(ctx, conn.mx, root, fn)
+}
+
+func (c *Client) walkRaw(ctx context.Context, mx *mux, root ObjectIdentifier, fn func(oid, value []byte) error) error {
+	if c.Version == Version3 {
+		return errors.New("snmp: WalkRaw doesn't support SNMPv3")
+	}
+
+	oid := walkStart(root)
+
+	var e Encoder
+
+	for {
+		packet, err := c.rawGetNext(ctx, mx, &e, oid)
+		if err != nil {
+			return err
+		}
+
+		status, index, oidBytes, value, err := rawResponse(packet)
+		if err != nil {
+			return err
+		}
+
+		if status != NoError {
+			// SNMPv1 agents signal the end of the MIB with noSuchName
+			if status == NoSuchName && c.Version == Version1 {
+				return nil
+			}
+
+			return SNMPError{Status: status, Index: index, OID: oid}
+		}
+
+		if isExceptionTag(value[0]) {
+			return nil
+		}
+
+		next, err := ParseOIDBytes(oidBytes)
+		if err != nil {
+			return err
+		}
+
+		// The value isn't decoded, as endOfWalk only needs the OID
+		if done, err := endOfWalk(root, oid, oid, Varbind{OID: next}); done {
+			return err
+		}
+
+		if err := fn(oidBytes, value); err != nil {
+			if err == ErrStopWalk {
+				return nil
+			}
+
+			return err
+		}
+
+		oid = next
+	}
+}
+
+// rawGetNext sends a GetNextRequest for oid on mx, encoded with e, and
+// returns the response packet without decoding it.
+func (c *Client) rawGetNext(ctx context.Context, mx *mux, e *Encoder, oid ObjectIdentifier) ([]byte, error) {
+	id := mx.reserveRaw()
+	defer mx.release(id)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e.Reset()
+
+	packet, err := e.EncodeMessage(&Message{
+		Version:   c.Version,
+		Community: c.Community,
+		PDU:       newGetNextRequest(int(id), nullVarbinds([]ObjectIdentifier{oid})),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The mux only routes GetResponses with the request ID to it
+	res := c.send(ctx, mx, id, packet, func(*Message, []byte) bool { return true })
+	if res.err != nil {
+		c.Trace.error(res.err)
+	}
+
+	return res.packet, res.err
+}
+
+// rawResponse returns the error-status, the error-index, and the
+// content of the OID and the TLV of the value of the first variable
+// binding of the SNMPv1 or SNMPv2c GetResponse message packet.
+func rawResponse(packet []byte) (status ErrorStatus, index int, oid, value []byte, err error) {
+	message, _, err := expectTLV(packet, TypeSequence)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	// The version and community
+	for _, tag := range []byte{TypeInteger, TypeString} {
+		if _, message, err = expectTLV(message, tag); err != nil {
+			return 0, 0, nil, nil, err
+		}
+	}
+
+	pdu, _, err := expectTLV(message, TypeGetResponse)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	var fields [3][]byte
+
+	// The request-id, error-status, and error-index
+	for i := range fields {
+		if fields[i], pdu, err = expectTLV(pdu, TypeInteger); err != nil {
+			return 0, 0, nil, nil, err
+		}
+	}
+
+	varbinds, _, err := expectTLV(pdu, TypeSequence)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	status, index = ErrorStatus(rawInt(fields[1])), rawInt(fields[2])
+
+	if len(varbinds) == 0 {
+		if status != NoError {
+			return status, index, nil, nil, nil
+		}
+
+		return 0, 0, nil, nil, ErrDecodingType
+	}
+
+	varbind, _, err := expectTLV(varbinds, TypeSequence)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	oid, value, err = expectTLV(varbind, TypeOID)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	if _, _, n, err := nextTLV(value); err != nil || n != len(value) {
+		return 0, 0, nil, nil, ErrDecodingType
+	}
+
+	return status, index, oid, value, nil
+}
+
+// expectTLV returns the content of the TLV with the given tag that data
+// begins with, and the data after it.
+func expectTLV(data []byte, tag byte) ([]byte, []byte, error) {
+	t, content, n, err := nextTLV(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t != tag {
+		return nil, nil, fmt.Errorf("%w: expected tag %#x, got %#x", ErrDecodingType, tag, t)
+	}
+
+	return content, data[n:], nil
+}
+
+// rawInt returns the value of the content of an INTEGER, which
+// must fit in an int.
+func rawInt(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+
+	i := int(int8(content[0]))
+	for _, b := range content[1:] {
+		i = i<<8 | int(b)
+	}
+
+	return i
+}
+
+// isExceptionTag reports whether tag is that of an SNMPv2 exception.
+func isExceptionTag(tag byte) bool {
+	return tag == TypeNoSuchObject || tag == TypeNoSuchInstance || tag == TypeEndOfMIBView
+}
@@ -0,0 +1,259 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClientWalkRaw(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	expected, err := c.WalkAll(ifDescr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var walked []Varbind
+
+	err = c.WalkRaw(ifDescr, func(oid, value []byte) error {
+		decodedOID, _, err := decodeOID(len(oid), bytes.NewReader(oid))
+		if err != nil {
+			return err
+		}
+
+		decoded, _, err := decode(bytes.NewReader(value))
+		if err != nil {
+			return err
+		}
+
+		walked = append(walked, NewVarbind(decodedOID, decoded))
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(walked) != len(expected) || len(walked) != 3 {
+		t.Fatalf("expected %v, got %v", expected, walked)
+	}
+
+	for i, v := range walked {
+		if !v.OID.Equal(expected[i].OID) || v.Value() != expected[i].Value() {
+			t.Errorf("%d: expected %v = %v, got %v = %v", i, expected[i].OID, expected[i].Value(), v.OID, v.Value())
+		}
+	}
+
+	// The walk of the last column ends at endOfMibView
+	n := 0
+	if err := c.WalkRaw(MustParseOID(".1.3.6.1.2.1.2.2.1.3"), func(oid, value []byte) error { n++; return nil }); err != nil || n != 2 {
+		t.Errorf("expected 2 bindings, got %d, %v", n, err)
+	}
+
+	n = 0
+	if err := c.WalkRaw(ifDescr, func(oid, value []byte) error { n++; return ErrStopWalk }); err != nil || n != 1 {
+		t.Errorf("expected ErrStopWalk to stop the walk after 1 binding, got %d, %v", n, err)
+	}
+
+	stop := errors.New("stop")
+	if err := c.WalkRaw(ifDescr, func(oid, value []byte) error { return stop }); err != stop {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+
+	v3 := &Client{Addr: agent.addr(), Version: Version3, User: &USMUser{Name: "operator"}}
+	if err := v3.WalkRaw(ifDescr, func(oid, value []byte) error { return nil }); err == nil {
+		t.Error("expected an error walking over SNMPv3")
+	}
+}
+
+func TestClientWalkRawNotIncreasing(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	// The agent returns the first row after the second one
+	agent.handle(func(m *Message) []*Message {
+		req := m.PDU.(GetNextRequest)
+		if !req.varbinds[0].OID.Equal(ifDescr.Child(2)) {
+			return []*Message{agent.respond(m)}
+		}
+
+		return []*Message{{
+			Version:   m.Version,
+			Community: m.Community,
+			PDU:       GetResponse{PDU: newPDU(req.requestID, 0, 0, []Varbind{NewVarbind(ifDescr.Child(1), String("lo"))})},
+		}}
+	})
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	n := 0
+	err := c.WalkRaw(ifDescr, func(oid, value []byte) error { n++; return nil })
+	if !errors.Is(err, ErrOIDNotIncreasing) || n != 2 {
+		t.Errorf("expected ErrOIDNotIncreasing after 2 bindings, got %d, %v", n, err)
+	}
+}
+
+func TestClientWalkRawEncodedLengths(t *testing.T) {
+	// Arc 2000 is encoded in 2 bytes, 8f 50, and arc 20000 in 3, 81 9c 20,
+	// which sort before them
+	agent := newStubAgent(t,
+		NewVarbind(ifDescr.Child(2000), String("eth0")),
+		NewVarbind(ifDescr.Child(20000), String("eth1")),
+	)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	var walked []ObjectIdentifier
+
+	err := c.WalkRaw(ifDescr, func(oid, value []byte) error {
+		decoded, err := ParseOIDBytes(oid)
+		walked = append(walked, decoded)
+		return err
+	})
+
+	if err != nil || len(walked) != 2 || !walked[0].Equal(ifDescr.Child(2000)) || !walked[1].Equal(ifDescr.Child(20000)) {
+		t.Errorf("expected both rows, got %v, %v", walked, err)
+	}
+}
+
+func TestClientWalkRawSingleArcRoot(t *testing.T) {
+	agent := ifTableAgent(t)
+
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	expected, err := c.WalkAll(ObjectIdentifier{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	if err := c.WalkRaw(ObjectIdentifier{1}, func(oid, value []byte) error { n++; return nil }); err != nil || n != len(expected) || n == 0 {
+		t.Errorf("expected %d bindings, got %d, %v", len(expected), n, err)
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	b, err := Message{
+		Version:   Version1,
+		Community: "public",
+		PDU:       GetResponse{PDU: newPDU(7, int(NoSuchName), 1, []Varbind{NewVarbind(ifDescr, Null)})},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, index, _, _, err := rawResponse(b)
+	if err != nil || status != NoSuchName || index != 1 {
+		t.Errorf("expected noSuchName at 1, got %v at %d, %v", status, index, err)
+	}
+
+	for i := 1; i < len(b); i++ {
+		if _, _, _, _, err := rawResponse(b[:i]); err == nil {
+			t.Errorf("expected an error for %d of %d bytes", i, len(b))
+		}
+	}
+
+	if i := rawInt([]byte{0xff, 0x38}); i != -200 {
+		t.Errorf("expected -200, got %d", i)
+	}
+}
+
+// rawAgent answers the GETNEXT requests of a walk of varbinds from
+// precomputed responses, so that benchmarks mostly measure the client.
+func rawAgent(b *testing.B, varbinds []Varbind) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(func() { conn.Close() })
+
+	// The encoded variable bindings of the response to a request for
+	// each OID, keyed by the content of the OID
+	responses := map[string][]byte{}
+	prev := ifDescr
+
+	for _, v := range append(varbinds, NewVarbind(varbinds[len(varbinds)-1].OID, EndOfMIBView)) {
+		encoded, err := Sequence{v}.Encode()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		oid, _ := prev.Encode()
+		responses[string(oid[2:])] = encoded
+		prev = v.OID
+	}
+
+	go func() {
+		buf := make([]byte, 65535)
+		var res []byte
+
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			// The request-id and the OID of the only binding
+			message, _, _ := expectTLV(buf[:n], TypeSequence)
+			_, message, _ = expectTLV(message, TypeInteger)
+			_, message, _ = expectTLV(message, TypeString)
+			pdu, _, _ := expectTLV(message, TypeGetNextRequest)
+			requestID, pdu, _ := expectTLV(pdu, TypeInteger)
+			_, pdu, _ = expectTLV(pdu, TypeInteger)
+			_, pdu, _ = expectTLV(pdu, TypeInteger)
+			list, _, _ := expectTLV(pdu, TypeSequence)
+			varbind, _, _ := expectTLV(list, TypeSequence)
+			oid, _, _ := expectTLV(varbind, TypeOID)
+
+			encoded := responses[string(oid)]
+			pduLength := 2 + len(requestID) + 6 + len(encoded)
+
+			res = appendTLVHeader(res[:0], TypeSequence, 3+8+2+pduLength)
+			res = append(res, 0x02, 0x01, byte(Version2c), 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c')
+			res = appendTLVHeader(res, TypeGetResponse, pduLength)
+			res = appendTLVHeader(res, TypeInteger, len(requestID))
+			res = append(res, requestID...)
+			res = append(res, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00)
+			res = append(res, encoded...)
+
+			conn.WriteTo(res, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// benchmarkWalk walks an ifDescr column of 100 rows with walk.
+func benchmarkWalk(b *testing.B, walk func(c *Client) error) {
+	varbinds := make([]Varbind, 100)
+	for i := range varbinds {
+		varbinds[i] = NewVarbind(ifDescr.Child(uint32(i+1)), String("GigabitEthernet0/1"))
+	}
+
+	c := &Client{Addr: rawAgent(b, varbinds), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := walk(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+func BenchmarkClientWalk(b *testing.B) {
+	benchmarkWalk(b, func(c *Client) error {
+		return c.Walk(ifDescr, func(Varbind) error { return nil })
+	})
+}
+
+func BenchmarkClientWalkRaw(b *testing.B) {
+	benchmarkWalk(b, func(c *Client) error {
+		return c.WalkRaw(ifDescr, func(oid, value []byte) error { return nil })
+	})
+}
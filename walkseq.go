@@ -0,0 +1,40 @@
+package snmp
+
+import (
+	"context"
+	"iter"
+)
+
+// WalkSeq returns an iterator over the variable bindings of the subtree
+// rooted at root, got with GETNEXT requests as Walk does. If the walk
+// fails, the error is yielded with a zero Varbind and the iteration
+// ends. Each iteration sends its own requests, and breaking out of the
+// loop stops the walk.
+func (c *Client) WalkSeq(root ObjectIdentifier) iter.Seq2[Varbind, error] {
+	return c.WalkSeqContext(context.Background(), root)
+}
+
+// WalkSeqContext is like WalkSeq but aborts when ctx is done.
+func (c *Client) WalkSeqContext(ctx context.Context, root ObjectIdentifier) iter.Seq2[Varbind, error] {
+	return func(yield func(Varbind, error) bool) {
+		conn, err := c.Dial()
+		if err != nil {
+			yield(Varbind{}, err)
+			return
+		}
+
+		defer conn.Close()
+
+		err = c.walk(ctx, conn.mx, root, func(v Varbind) error {
+			if !yield(v, nil) {
+				return ErrStopWalk
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			yield(Varbind{}, err)
+		}
+	}
+}
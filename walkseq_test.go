@@ -0,0 +1,69 @@
+package snmp
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestClientWalkSeq(t *testing.T) {
+	agent := ifTableAgent(t)
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	var values []DataType
+	for v, err := range c.WalkSeq(ifDescr) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values = append(values, v.Value())
+	}
+
+	if len(values) != 3 || values[0] != String("lo") || values[1] != String("eth0") || values[2] != String("eth1") {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var errs []error
+	for _, err := range c.WalkSeqContext(ctx, ifDescr) {
+		errs = append(errs, err)
+	}
+
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("expected only context.Canceled, got %v", errs)
+	}
+}
+
+func TestClientWalkSeqBreak(t *testing.T) {
+	agent := ifTableAgent(t)
+	c := &Client{Addr: agent.addr(), Community: "public", Version: Version2c, Timeout: time.Second}
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		for v, err := range c.WalkSeq(ifDescr) {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v.Value() != String("lo") {
+				t.Fatalf("expected lo first, got %v", v)
+			}
+
+			break
+		}
+	}
+
+	// The Conn of each walk is closed when the loop breaks
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("expected at most %d goroutines, got %d", before, n)
+	}
+}